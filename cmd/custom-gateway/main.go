@@ -33,14 +33,20 @@ Options:`)
 	os.Exit(2)
 }
 
-func startHTTPGateway(mux *runtime.ServeMux, hostPort string) {
-	if err := http.ListenAndServe(hostPort, integrate.HttpMux(mux)); err != nil {
+func startHTTPGateway(handler http.Handler, hostPort string) {
+	if err := http.ListenAndServe(hostPort, handler); err != nil {
 		glog.Errorf("Start http gateway error: %v", err)
 		shutdown()
 		panic(err)
 	}
 }
 
+func startAdminServer(mux *runtime.ServeMux) {
+	if err := integrate.ServeAdmin(mux); err != nil {
+		glog.Errorf("Start admin server error: %v", err)
+	}
+}
+
 func main() {
 	letsgo.Init(letsgo.FlagUsage(usage))
 	// checkFlags()
@@ -55,15 +61,31 @@ func main() {
 	glog.Info("***** Janus gateway init. *****")
 
 	hostPort := fmt.Sprintf("%s:%d", *host, *port)
-	mux := runtime.NewServeMux()
-	runtime.SetGatewayServiceHook(integrate.NewGatewayHook(mux, hostPort))
+	if err := integrate.AddDynamicServices(); err != nil {
+		glog.Errorf("Load dynamic services error: %v", err)
+		shutdown()
+		panic(err)
+	}
+	mux := runtime.NewServeMux(runtime.WithIncomingHeaderMatcher(integrate.IncomingHeaderMatcher()))
+	if err := runtime.SetGatewayServiceHook(integrate.NewGatewayHook(mux, hostPort)); err != nil {
+		glog.Errorf("Bootstrap gateway error: %v", err)
+		shutdown()
+		panic(err)
+	}
+	handler, err := integrate.NewHttpMux(mux)
+	if err != nil {
+		glog.Errorf("Set up http gateway error: %v", err)
+		shutdown()
+		panic(err)
+	}
 
 	glog.Infof("***** Starting custom janus-gateway at %s. *****", hostPort)
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, os.Kill)
 
-	go startHTTPGateway(mux, hostPort)
+	go startAdminServer(mux)
+	go startHTTPGateway(handler, hostPort)
 
 	select {
 	case <-signals:
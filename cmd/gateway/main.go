@@ -50,22 +50,28 @@ func checkFlags() {
 	}
 }
 
-func startHTTPGateway(mux *runtime.ServeMux, hostPort string) {
-	if err := http.ListenAndServe(hostPort, integrate.HttpMux(mux)); err != nil {
+func startHTTPGateway(handler http.Handler, hostPort string) {
+	if err := http.ListenAndServe(hostPort, handler); err != nil {
 		glog.Errorf("Start http gateway error: %v", err)
 		shutdown()
 		panic(err)
 	}
 }
 
-func startHTTPSGateway(mux *runtime.ServeMux, hostPort string) {
-	if err := http.ListenAndServeTLS(hostPort, *certFile, *keyFile, integrate.HttpMux(mux)); err != nil {
+func startHTTPSGateway(handler http.Handler, hostPort string) {
+	if err := http.ListenAndServeTLS(hostPort, *certFile, *keyFile, handler); err != nil {
 		glog.Errorf("Start https gateway error: %v", err)
 		shutdown()
 		panic(err)
 	}
 }
 
+func startAdminServer(mux *runtime.ServeMux) {
+	if err := integrate.ServeAdmin(mux); err != nil {
+		glog.Errorf("Start admin server error: %v", err)
+	}
+}
+
 func main() {
 	defer letsgo.Cleanup()
 	letsgo.Init(letsgo.FlagUsage(usage))
@@ -84,18 +90,34 @@ func main() {
 	if *port > 0 {
 		hostPort = fmt.Sprintf("%s:%d", *host, *port)
 	}
-	mux := runtime.NewServeMux()
-	runtime.SetGatewayServiceHook(integrate.NewGatewayHook(mux, hostPort))
+	if err := integrate.AddDynamicServices(); err != nil {
+		glog.Errorf("Load dynamic services error: %v", err)
+		shutdown()
+		panic(err)
+	}
+	mux := runtime.NewServeMux(runtime.WithIncomingHeaderMatcher(integrate.IncomingHeaderMatcher()))
+	if err := runtime.SetGatewayServiceHook(integrate.NewGatewayHook(mux, hostPort)); err != nil {
+		glog.Errorf("Bootstrap gateway error: %v", err)
+		shutdown()
+		panic(err)
+	}
+	handler, err := integrate.NewHttpMux(mux)
+	if err != nil {
+		glog.Errorf("Set up http gateway error: %v", err)
+		shutdown()
+		panic(err)
+	}
 
 	util.Logf(util.DefaultLogger, "*****Starting %s at %s.*****", serviceName, hostPort)
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, os.Kill)
 
+	go startAdminServer(mux)
 	if *enableHTTPS {
-		go startHTTPSGateway(mux, hostPort)
+		go startHTTPSGateway(handler, hostPort)
 	} else {
-		go startHTTPGateway(mux, hostPort)
+		go startHTTPGateway(handler, hostPort)
 	}
 
 	select {
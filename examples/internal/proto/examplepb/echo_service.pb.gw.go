@@ -105,10 +105,9 @@ var (
 	filter_EchoService_Echo_0 = &utilities.DoubleArray{Encoding: map[string]int{"id": 0}, Base: []int{1, 1, 0}, Check: []int{0, 1, 2}}
 )
 
-func request_EchoService_Echo_0(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+func request_EchoService_Echo_0(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string, meth *runtime.Method) (proto.Message, runtime.ServerMetadata, error) {
 	var protoReq SimpleMessage
 	var metadata runtime.ServerMetadata
-	spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 
 	var (
 		val string
@@ -119,7 +118,7 @@ func request_EchoService_Echo_0(ctx context.Context, marshaler runtime.Marshaler
 
 	val, ok = pathParams["id"]
 	if !ok {
-		runtime.RequestHandled(ctx, spec, "EchoService", "Echo", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", "id")
 	}
 
@@ -137,13 +136,14 @@ func request_EchoService_Echo_0(ctx context.Context, marshaler runtime.Marshaler
 
 	// Only hook up for non-stream call for now.
 
-	runtime.RequestParsed(ctx, spec, "EchoService", "Echo", &protoReq, &metadata)
+	runtime.RequestParsedFor(ctx, meth, &protoReq, &metadata)
 	ctx = runtime.PreLoadBalance(ctx, "ROUND_ROBIN", "", &protoReq)
+	ctx = runtime.WithServiceMethod(ctx, meth)
 	msg, err := client.Echo(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
 	// if err != nil {
 	// 	grpclog.Errorf("client.%s returns error: %v", "Echo", err)
 	// }
-	runtime.RequestHandled(ctx, spec, "EchoService", "Echo", msg, &metadata, err)
+	runtime.RequestHandledFor(ctx, meth, msg, &metadata, err)
 	return msg, metadata, err
 
 }
@@ -185,10 +185,9 @@ var (
 	filter_EchoService_Echo_1 = &utilities.DoubleArray{Encoding: map[string]int{"id": 0, "num": 1}, Base: []int{1, 1, 2, 0, 0}, Check: []int{0, 1, 1, 2, 3}}
 )
 
-func request_EchoService_Echo_1(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+func request_EchoService_Echo_1(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string, meth *runtime.Method) (proto.Message, runtime.ServerMetadata, error) {
 	var protoReq SimpleMessage
 	var metadata runtime.ServerMetadata
-	spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 
 	var (
 		val string
@@ -199,7 +198,7 @@ func request_EchoService_Echo_1(ctx context.Context, marshaler runtime.Marshaler
 
 	val, ok = pathParams["id"]
 	if !ok {
-		runtime.RequestHandled(ctx, spec, "EchoService", "Echo", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", "id")
 	}
 
@@ -210,7 +209,7 @@ func request_EchoService_Echo_1(ctx context.Context, marshaler runtime.Marshaler
 
 	val, ok = pathParams["num"]
 	if !ok {
-		runtime.RequestHandled(ctx, spec, "EchoService", "Echo", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", "num")
 	}
 
@@ -228,13 +227,14 @@ func request_EchoService_Echo_1(ctx context.Context, marshaler runtime.Marshaler
 
 	// Only hook up for non-stream call for now.
 
-	runtime.RequestParsed(ctx, spec, "EchoService", "Echo", &protoReq, &metadata)
+	runtime.RequestParsedFor(ctx, meth, &protoReq, &metadata)
 	ctx = runtime.PreLoadBalance(ctx, "ROUND_ROBIN", "", &protoReq)
+	ctx = runtime.WithServiceMethod(ctx, meth)
 	msg, err := client.Echo(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
 	// if err != nil {
 	// 	grpclog.Errorf("client.%s returns error: %v", "Echo", err)
 	// }
-	runtime.RequestHandled(ctx, spec, "EchoService", "Echo", msg, &metadata, err)
+	runtime.RequestHandledFor(ctx, meth, msg, &metadata, err)
 	return msg, metadata, err
 
 }
@@ -286,10 +286,9 @@ var (
 	filter_EchoService_Echo_2 = &utilities.DoubleArray{Encoding: map[string]int{"id": 0, "num": 1, "lang": 2}, Base: []int{1, 1, 2, 3, 0, 0, 0}, Check: []int{0, 1, 1, 1, 2, 3, 4}}
 )
 
-func request_EchoService_Echo_2(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+func request_EchoService_Echo_2(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string, meth *runtime.Method) (proto.Message, runtime.ServerMetadata, error) {
 	var protoReq SimpleMessage
 	var metadata runtime.ServerMetadata
-	spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 
 	var (
 		val string
@@ -300,7 +299,7 @@ func request_EchoService_Echo_2(ctx context.Context, marshaler runtime.Marshaler
 
 	val, ok = pathParams["id"]
 	if !ok {
-		runtime.RequestHandled(ctx, spec, "EchoService", "Echo", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", "id")
 	}
 
@@ -311,7 +310,7 @@ func request_EchoService_Echo_2(ctx context.Context, marshaler runtime.Marshaler
 
 	val, ok = pathParams["num"]
 	if !ok {
-		runtime.RequestHandled(ctx, spec, "EchoService", "Echo", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", "num")
 	}
 
@@ -322,7 +321,7 @@ func request_EchoService_Echo_2(ctx context.Context, marshaler runtime.Marshaler
 
 	val, ok = pathParams["lang"]
 	if !ok {
-		runtime.RequestHandled(ctx, spec, "EchoService", "Echo", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", "lang")
 	}
 
@@ -345,13 +344,14 @@ func request_EchoService_Echo_2(ctx context.Context, marshaler runtime.Marshaler
 
 	// Only hook up for non-stream call for now.
 
-	runtime.RequestParsed(ctx, spec, "EchoService", "Echo", &protoReq, &metadata)
+	runtime.RequestParsedFor(ctx, meth, &protoReq, &metadata)
 	ctx = runtime.PreLoadBalance(ctx, "ROUND_ROBIN", "", &protoReq)
+	ctx = runtime.WithServiceMethod(ctx, meth)
 	msg, err := client.Echo(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
 	// if err != nil {
 	// 	grpclog.Errorf("client.%s returns error: %v", "Echo", err)
 	// }
-	runtime.RequestHandled(ctx, spec, "EchoService", "Echo", msg, &metadata, err)
+	runtime.RequestHandledFor(ctx, meth, msg, &metadata, err)
 	return msg, metadata, err
 
 }
@@ -418,10 +418,9 @@ var (
 	filter_EchoService_Echo_3 = &utilities.DoubleArray{Encoding: map[string]int{"id": 0, "line_num": 1, "status": 2, "note": 3}, Base: []int{1, 1, 2, 1, 3, 0, 0, 0}, Check: []int{0, 1, 1, 1, 4, 2, 3, 5}}
 )
 
-func request_EchoService_Echo_3(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+func request_EchoService_Echo_3(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string, meth *runtime.Method) (proto.Message, runtime.ServerMetadata, error) {
 	var protoReq SimpleMessage
 	var metadata runtime.ServerMetadata
-	spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 
 	var (
 		val string
@@ -432,7 +431,7 @@ func request_EchoService_Echo_3(ctx context.Context, marshaler runtime.Marshaler
 
 	val, ok = pathParams["id"]
 	if !ok {
-		runtime.RequestHandled(ctx, spec, "EchoService", "Echo", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", "id")
 	}
 
@@ -443,7 +442,7 @@ func request_EchoService_Echo_3(ctx context.Context, marshaler runtime.Marshaler
 
 	val, ok = pathParams["line_num"]
 	if !ok {
-		runtime.RequestHandled(ctx, spec, "EchoService", "Echo", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", "line_num")
 	}
 
@@ -459,7 +458,7 @@ func request_EchoService_Echo_3(ctx context.Context, marshaler runtime.Marshaler
 
 	val, ok = pathParams["status.note"]
 	if !ok {
-		runtime.RequestHandled(ctx, spec, "EchoService", "Echo", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", "status.note")
 	}
 
@@ -477,13 +476,14 @@ func request_EchoService_Echo_3(ctx context.Context, marshaler runtime.Marshaler
 
 	// Only hook up for non-stream call for now.
 
-	runtime.RequestParsed(ctx, spec, "EchoService", "Echo", &protoReq, &metadata)
+	runtime.RequestParsedFor(ctx, meth, &protoReq, &metadata)
 	ctx = runtime.PreLoadBalance(ctx, "ROUND_ROBIN", "", &protoReq)
+	ctx = runtime.WithServiceMethod(ctx, meth)
 	msg, err := client.Echo(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
 	// if err != nil {
 	// 	grpclog.Errorf("client.%s returns error: %v", "Echo", err)
 	// }
-	runtime.RequestHandled(ctx, spec, "EchoService", "Echo", msg, &metadata, err)
+	runtime.RequestHandledFor(ctx, meth, msg, &metadata, err)
 	return msg, metadata, err
 
 }
@@ -550,10 +550,9 @@ var (
 	filter_EchoService_Echo_4 = &utilities.DoubleArray{Encoding: map[string]int{"no": 0, "note": 1}, Base: []int{1, 1, 1, 0}, Check: []int{0, 1, 2, 3}}
 )
 
-func request_EchoService_Echo_4(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+func request_EchoService_Echo_4(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string, meth *runtime.Method) (proto.Message, runtime.ServerMetadata, error) {
 	var protoReq SimpleMessage
 	var metadata runtime.ServerMetadata
-	spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 
 	var (
 		val string
@@ -564,7 +563,7 @@ func request_EchoService_Echo_4(ctx context.Context, marshaler runtime.Marshaler
 
 	val, ok = pathParams["no.note"]
 	if !ok {
-		runtime.RequestHandled(ctx, spec, "EchoService", "Echo", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", "no.note")
 	}
 
@@ -582,13 +581,14 @@ func request_EchoService_Echo_4(ctx context.Context, marshaler runtime.Marshaler
 
 	// Only hook up for non-stream call for now.
 
-	runtime.RequestParsed(ctx, spec, "EchoService", "Echo", &protoReq, &metadata)
+	runtime.RequestParsedFor(ctx, meth, &protoReq, &metadata)
 	ctx = runtime.PreLoadBalance(ctx, "ROUND_ROBIN", "", &protoReq)
+	ctx = runtime.WithServiceMethod(ctx, meth)
 	msg, err := client.Echo(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
 	// if err != nil {
 	// 	grpclog.Errorf("client.%s returns error: %v", "Echo", err)
 	// }
-	runtime.RequestHandled(ctx, spec, "EchoService", "Echo", msg, &metadata, err)
+	runtime.RequestHandledFor(ctx, meth, msg, &metadata, err)
 	return msg, metadata, err
 
 }
@@ -626,29 +626,29 @@ func local_request_EchoService_Echo_4(ctx context.Context, marshaler runtime.Mar
 
 }
 
-func request_EchoService_EchoBody_0(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+func request_EchoService_EchoBody_0(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string, meth *runtime.Method) (proto.Message, runtime.ServerMetadata, error) {
 	var protoReq SimpleMessage
 	var metadata runtime.ServerMetadata
-	spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 
 	newReader, berr := utilities.IOReaderFactory(req.Body)
 	if berr != nil {
-		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", berr)
+		return nil, metadata, runtime.RequestBodyError(berr)
 	}
 	if err := marshaler.NewDecoder(newReader()).Decode(&protoReq); err != nil && err != io.EOF {
-		runtime.RequestHandled(ctx, spec, "EchoService", "EchoBody", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
 
 	// Only hook up for non-stream call for now.
 
-	runtime.RequestParsed(ctx, spec, "EchoService", "EchoBody", &protoReq, &metadata)
+	runtime.RequestParsedFor(ctx, meth, &protoReq, &metadata)
 	ctx = runtime.PreLoadBalance(ctx, "ROUND_ROBIN", "", &protoReq)
+	ctx = runtime.WithServiceMethod(ctx, meth)
 	msg, err := client.EchoBody(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
 	// if err != nil {
 	// 	grpclog.Errorf("client.%s returns error: %v", "EchoBody", err)
 	// }
-	runtime.RequestHandled(ctx, spec, "EchoService", "EchoBody", msg, &metadata, err)
+	runtime.RequestHandledFor(ctx, meth, msg, &metadata, err)
 	return msg, metadata, err
 
 }
@@ -659,7 +659,7 @@ func local_request_EchoService_EchoBody_0(ctx context.Context, marshaler runtime
 
 	newReader, berr := utilities.IOReaderFactory(req.Body)
 	if berr != nil {
-		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", berr)
+		return nil, metadata, runtime.RequestBodyError(berr)
 	}
 	if err := marshaler.NewDecoder(newReader()).Decode(&protoReq); err != nil && err != io.EOF {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
@@ -674,10 +674,9 @@ var (
 	filter_EchoService_EchoDelete_0 = &utilities.DoubleArray{Encoding: map[string]int{}, Base: []int(nil), Check: []int(nil)}
 )
 
-func request_EchoService_EchoDelete_0(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+func request_EchoService_EchoDelete_0(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string, meth *runtime.Method) (proto.Message, runtime.ServerMetadata, error) {
 	var protoReq SimpleMessage
 	var metadata runtime.ServerMetadata
-	spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 
 	if err := req.ParseForm(); err != nil {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
@@ -688,13 +687,14 @@ func request_EchoService_EchoDelete_0(ctx context.Context, marshaler runtime.Mar
 
 	// Only hook up for non-stream call for now.
 
-	runtime.RequestParsed(ctx, spec, "EchoService", "EchoDelete", &protoReq, &metadata)
+	runtime.RequestParsedFor(ctx, meth, &protoReq, &metadata)
 	ctx = runtime.PreLoadBalance(ctx, "ROUND_ROBIN", "", &protoReq)
+	ctx = runtime.WithServiceMethod(ctx, meth)
 	msg, err := client.EchoDelete(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
 	// if err != nil {
 	// 	grpclog.Errorf("client.%s returns error: %v", "EchoDelete", err)
 	// }
-	runtime.RequestHandled(ctx, spec, "EchoService", "EchoDelete", msg, &metadata, err)
+	runtime.RequestHandledFor(ctx, meth, msg, &metadata, err)
 	return msg, metadata, err
 
 }
@@ -719,17 +719,16 @@ var (
 	filter_EchoService_EchoPatch_0 = &utilities.DoubleArray{Encoding: map[string]int{"body": 0}, Base: []int{1, 1, 0}, Check: []int{0, 1, 2}}
 )
 
-func request_EchoService_EchoPatch_0(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+func request_EchoService_EchoPatch_0(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string, meth *runtime.Method) (proto.Message, runtime.ServerMetadata, error) {
 	var protoReq DynamicMessageUpdate
 	var metadata runtime.ServerMetadata
-	spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 
 	newReader, berr := utilities.IOReaderFactory(req.Body)
 	if berr != nil {
-		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", berr)
+		return nil, metadata, runtime.RequestBodyError(berr)
 	}
 	if err := marshaler.NewDecoder(newReader()).Decode(&protoReq.Body); err != nil && err != io.EOF {
-		runtime.RequestHandled(ctx, spec, "EchoService", "EchoPatch", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
 	if protoReq.UpdateMask == nil || len(protoReq.UpdateMask.GetPaths()) == 0 {
@@ -749,13 +748,14 @@ func request_EchoService_EchoPatch_0(ctx context.Context, marshaler runtime.Mars
 
 	// Only hook up for non-stream call for now.
 
-	runtime.RequestParsed(ctx, spec, "EchoService", "EchoPatch", &protoReq, &metadata)
+	runtime.RequestParsedFor(ctx, meth, &protoReq, &metadata)
 	ctx = runtime.PreLoadBalance(ctx, "ROUND_ROBIN", "", &protoReq)
+	ctx = runtime.WithServiceMethod(ctx, meth)
 	msg, err := client.EchoPatch(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
 	// if err != nil {
 	// 	grpclog.Errorf("client.%s returns error: %v", "EchoPatch", err)
 	// }
-	runtime.RequestHandled(ctx, spec, "EchoService", "EchoPatch", msg, &metadata, err)
+	runtime.RequestHandledFor(ctx, meth, msg, &metadata, err)
 	return msg, metadata, err
 
 }
@@ -766,7 +766,7 @@ func local_request_EchoService_EchoPatch_0(ctx context.Context, marshaler runtim
 
 	newReader, berr := utilities.IOReaderFactory(req.Body)
 	if berr != nil {
-		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", berr)
+		return nil, metadata, runtime.RequestBodyError(berr)
 	}
 	if err := marshaler.NewDecoder(newReader()).Decode(&protoReq.Body); err != nil && err != io.EOF {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
@@ -791,17 +791,16 @@ func local_request_EchoService_EchoPatch_0(ctx context.Context, marshaler runtim
 
 }
 
-func request_EchoService_EchoValidationRule_0(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+func request_EchoService_EchoValidationRule_0(ctx context.Context, marshaler runtime.Marshaler, client EchoServiceClient, req *http.Request, pathParams map[string]string, meth *runtime.Method) (proto.Message, runtime.ServerMetadata, error) {
 	var protoReq ValidationRuleTestRequest
 	var metadata runtime.ServerMetadata
-	spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 
 	newReader, berr := utilities.IOReaderFactory(req.Body)
 	if berr != nil {
-		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", berr)
+		return nil, metadata, runtime.RequestBodyError(berr)
 	}
 	if err := marshaler.NewDecoder(newReader()).Decode(&protoReq); err != nil && err != io.EOF {
-		runtime.RequestHandled(ctx, spec, "EchoService", "EchoValidationRule", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
 
@@ -810,17 +809,18 @@ func request_EchoService_EchoValidationRule_0(ctx context.Context, marshaler run
 	// Validate
 	// ValidationRuleTestRequest
 	if err := Validate__grpc_gateway_examples_internal_proto_examplepb_ValidationRuleTestRequest(&protoReq); err != nil {
-		runtime.RequestHandled(ctx, spec, "EchoService", "EchoValidationRule", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, err
 	}
 
-	runtime.RequestParsed(ctx, spec, "EchoService", "EchoValidationRule", &protoReq, &metadata)
+	runtime.RequestParsedFor(ctx, meth, &protoReq, &metadata)
 	ctx = runtime.PreLoadBalance(ctx, "ROUND_ROBIN", "", &protoReq)
+	ctx = runtime.WithServiceMethod(ctx, meth)
 	msg, err := client.EchoValidationRule(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
 	// if err != nil {
 	// 	grpclog.Errorf("client.%s returns error: %v", "EchoValidationRule", err)
 	// }
-	runtime.RequestHandled(ctx, spec, "EchoService", "EchoValidationRule", msg, &metadata, err)
+	runtime.RequestHandledFor(ctx, meth, msg, &metadata, err)
 	return msg, metadata, err
 
 }
@@ -831,7 +831,7 @@ func local_request_EchoService_EchoValidationRule_0(ctx context.Context, marshal
 
 	newReader, berr := utilities.IOReaderFactory(req.Body)
 	if berr != nil {
-		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", berr)
+		return nil, metadata, runtime.RequestBodyError(berr)
 	}
 	if err := marshaler.NewDecoder(newReader()).Decode(&protoReq); err != nil && err != io.EOF {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
@@ -1077,8 +1077,10 @@ func init() {
 
 	spec = internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 	s = &runtime.Service{
-		Spec:     *spec,
+		Spec:     spec,
 		Name:     "EchoService",
+		FullName: "grpc.gateway.examples.internal.proto.examplepb.EchoService",
+		Balancer: "ROUND_ROBIN",
 		Register: RegisterEchoServiceHandlerFromEndpoint,
 		Enable:   EnableEchoService_Service,
 		Disable:  DisableEchoService_Service,
@@ -1128,12 +1130,25 @@ func RegisterEchoServiceHandler(ctx context.Context, mux *runtime.ServeMux, conn
 func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux, client EchoServiceClient) error {
 	spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 
-	runtime.AddMethod(spec, "EchoService", "Echo", "/v1/example/echo/{id}", "POST", true, false, false, "UNSPECIFIED", "JANUS_GATEWAY", "JANUS_AUTH_TOKEN", "")
-	mux.Handle("POST", pattern_EchoService_Echo_0, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
-		// TODO(mojz): review all locking/unlocking logic.
-		// internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RLock()
-		// defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RUnlock()
-		client := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client
+	method_Echo_0 := runtime.AddMethod(spec, "EchoService", "Echo", "/v1/example/echo/{id}", "POST", &runtime.MethodOptions{
+		LoginRequired:      true,
+		ClientSignRequired: false,
+		IsThirdParty:       false,
+		SpecSourceType:     "UNSPECIFIED",
+		ApiSource:          "JANUS_GATEWAY",
+		TokenType:          "JANUS_AUTH_TOKEN",
+		Timeout:            "",
+		RateLimit:          0,
+		RateLimitBurst:     0,
+		MaxAttempts:        0,
+		Hedge:              false,
+		HedgeDelay:         "",
+		MaxBodyBytes:       0,
+		ClientStreaming:    false,
+		ServerStreaming:    false,
+	})
+	mux.HandleMethod("POST", pattern_EchoService_Echo_0, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, method_Echo_0, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		client, _ := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client.Load().(EchoServiceClient)
 		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
 		if client == nil {
 			err := status.Error(codes.Internal, "service disabled")
@@ -1141,7 +1156,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			return
 		}
 
-		ctx, err := runtime.RequestAccepted(inctx, internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec, "EchoService", "Echo", w, req)
+		ctx, err := runtime.RequestAcceptedFor(inctx, method_Echo_0, w, req)
 		if err != nil {
 			grpclog.Errorf("runtime.RequestAccepted returns error: %v", err)
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1156,7 +1171,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
 			return
 		}
-		resp, md, err := request_EchoService_Echo_0(ctx, inboundMarshaler, client, req, pathParams)
+		resp, md, err := request_EchoService_Echo_0(ctx, inboundMarshaler, client, req, pathParams, method_Echo_0)
 		ctx = runtime.NewServerMetadataContext(ctx, md)
 		if err != nil {
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1167,12 +1182,25 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 
 	})
 
-	runtime.AddMethod(spec, "EchoService", "Echo", "/v1/example/echo/{id}/{num}", "GET", true, false, false, "UNSPECIFIED", "JANUS_GATEWAY", "JANUS_AUTH_TOKEN", "")
-	mux.Handle("GET", pattern_EchoService_Echo_1, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
-		// TODO(mojz): review all locking/unlocking logic.
-		// internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RLock()
-		// defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RUnlock()
-		client := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client
+	method_Echo_1 := runtime.AddMethod(spec, "EchoService", "Echo", "/v1/example/echo/{id}/{num}", "GET", &runtime.MethodOptions{
+		LoginRequired:      true,
+		ClientSignRequired: false,
+		IsThirdParty:       false,
+		SpecSourceType:     "UNSPECIFIED",
+		ApiSource:          "JANUS_GATEWAY",
+		TokenType:          "JANUS_AUTH_TOKEN",
+		Timeout:            "",
+		RateLimit:          0,
+		RateLimitBurst:     0,
+		MaxAttempts:        0,
+		Hedge:              false,
+		HedgeDelay:         "",
+		MaxBodyBytes:       0,
+		ClientStreaming:    false,
+		ServerStreaming:    false,
+	})
+	mux.HandleMethod("GET", pattern_EchoService_Echo_1, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, method_Echo_1, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		client, _ := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client.Load().(EchoServiceClient)
 		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
 		if client == nil {
 			err := status.Error(codes.Internal, "service disabled")
@@ -1180,7 +1208,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			return
 		}
 
-		ctx, err := runtime.RequestAccepted(inctx, internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec, "EchoService", "Echo", w, req)
+		ctx, err := runtime.RequestAcceptedFor(inctx, method_Echo_1, w, req)
 		if err != nil {
 			grpclog.Errorf("runtime.RequestAccepted returns error: %v", err)
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1195,7 +1223,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
 			return
 		}
-		resp, md, err := request_EchoService_Echo_1(ctx, inboundMarshaler, client, req, pathParams)
+		resp, md, err := request_EchoService_Echo_1(ctx, inboundMarshaler, client, req, pathParams, method_Echo_1)
 		ctx = runtime.NewServerMetadataContext(ctx, md)
 		if err != nil {
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1206,12 +1234,25 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 
 	})
 
-	runtime.AddMethod(spec, "EchoService", "Echo", "/v1/example/echo/{id}/{num}/{lang}", "GET", true, false, false, "UNSPECIFIED", "JANUS_GATEWAY", "JANUS_AUTH_TOKEN", "")
-	mux.Handle("GET", pattern_EchoService_Echo_2, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
-		// TODO(mojz): review all locking/unlocking logic.
-		// internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RLock()
-		// defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RUnlock()
-		client := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client
+	method_Echo_2 := runtime.AddMethod(spec, "EchoService", "Echo", "/v1/example/echo/{id}/{num}/{lang}", "GET", &runtime.MethodOptions{
+		LoginRequired:      true,
+		ClientSignRequired: false,
+		IsThirdParty:       false,
+		SpecSourceType:     "UNSPECIFIED",
+		ApiSource:          "JANUS_GATEWAY",
+		TokenType:          "JANUS_AUTH_TOKEN",
+		Timeout:            "",
+		RateLimit:          0,
+		RateLimitBurst:     0,
+		MaxAttempts:        0,
+		Hedge:              false,
+		HedgeDelay:         "",
+		MaxBodyBytes:       0,
+		ClientStreaming:    false,
+		ServerStreaming:    false,
+	})
+	mux.HandleMethod("GET", pattern_EchoService_Echo_2, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, method_Echo_2, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		client, _ := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client.Load().(EchoServiceClient)
 		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
 		if client == nil {
 			err := status.Error(codes.Internal, "service disabled")
@@ -1219,7 +1260,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			return
 		}
 
-		ctx, err := runtime.RequestAccepted(inctx, internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec, "EchoService", "Echo", w, req)
+		ctx, err := runtime.RequestAcceptedFor(inctx, method_Echo_2, w, req)
 		if err != nil {
 			grpclog.Errorf("runtime.RequestAccepted returns error: %v", err)
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1234,7 +1275,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
 			return
 		}
-		resp, md, err := request_EchoService_Echo_2(ctx, inboundMarshaler, client, req, pathParams)
+		resp, md, err := request_EchoService_Echo_2(ctx, inboundMarshaler, client, req, pathParams, method_Echo_2)
 		ctx = runtime.NewServerMetadataContext(ctx, md)
 		if err != nil {
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1245,12 +1286,25 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 
 	})
 
-	runtime.AddMethod(spec, "EchoService", "Echo", "/v1/example/echo1/{id}/{line_num}/{status.note}", "GET", true, false, false, "UNSPECIFIED", "JANUS_GATEWAY", "JANUS_AUTH_TOKEN", "")
-	mux.Handle("GET", pattern_EchoService_Echo_3, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
-		// TODO(mojz): review all locking/unlocking logic.
-		// internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RLock()
-		// defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RUnlock()
-		client := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client
+	method_Echo_3 := runtime.AddMethod(spec, "EchoService", "Echo", "/v1/example/echo1/{id}/{line_num}/{status.note}", "GET", &runtime.MethodOptions{
+		LoginRequired:      true,
+		ClientSignRequired: false,
+		IsThirdParty:       false,
+		SpecSourceType:     "UNSPECIFIED",
+		ApiSource:          "JANUS_GATEWAY",
+		TokenType:          "JANUS_AUTH_TOKEN",
+		Timeout:            "",
+		RateLimit:          0,
+		RateLimitBurst:     0,
+		MaxAttempts:        0,
+		Hedge:              false,
+		HedgeDelay:         "",
+		MaxBodyBytes:       0,
+		ClientStreaming:    false,
+		ServerStreaming:    false,
+	})
+	mux.HandleMethod("GET", pattern_EchoService_Echo_3, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, method_Echo_3, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		client, _ := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client.Load().(EchoServiceClient)
 		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
 		if client == nil {
 			err := status.Error(codes.Internal, "service disabled")
@@ -1258,7 +1312,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			return
 		}
 
-		ctx, err := runtime.RequestAccepted(inctx, internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec, "EchoService", "Echo", w, req)
+		ctx, err := runtime.RequestAcceptedFor(inctx, method_Echo_3, w, req)
 		if err != nil {
 			grpclog.Errorf("runtime.RequestAccepted returns error: %v", err)
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1273,7 +1327,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
 			return
 		}
-		resp, md, err := request_EchoService_Echo_3(ctx, inboundMarshaler, client, req, pathParams)
+		resp, md, err := request_EchoService_Echo_3(ctx, inboundMarshaler, client, req, pathParams, method_Echo_3)
 		ctx = runtime.NewServerMetadataContext(ctx, md)
 		if err != nil {
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1284,12 +1338,25 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 
 	})
 
-	runtime.AddMethod(spec, "EchoService", "Echo", "/v1/example/echo2/{no.note}", "GET", true, false, false, "UNSPECIFIED", "JANUS_GATEWAY", "JANUS_AUTH_TOKEN", "")
-	mux.Handle("GET", pattern_EchoService_Echo_4, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
-		// TODO(mojz): review all locking/unlocking logic.
-		// internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RLock()
-		// defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RUnlock()
-		client := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client
+	method_Echo_4 := runtime.AddMethod(spec, "EchoService", "Echo", "/v1/example/echo2/{no.note}", "GET", &runtime.MethodOptions{
+		LoginRequired:      true,
+		ClientSignRequired: false,
+		IsThirdParty:       false,
+		SpecSourceType:     "UNSPECIFIED",
+		ApiSource:          "JANUS_GATEWAY",
+		TokenType:          "JANUS_AUTH_TOKEN",
+		Timeout:            "",
+		RateLimit:          0,
+		RateLimitBurst:     0,
+		MaxAttempts:        0,
+		Hedge:              false,
+		HedgeDelay:         "",
+		MaxBodyBytes:       0,
+		ClientStreaming:    false,
+		ServerStreaming:    false,
+	})
+	mux.HandleMethod("GET", pattern_EchoService_Echo_4, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, method_Echo_4, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		client, _ := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client.Load().(EchoServiceClient)
 		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
 		if client == nil {
 			err := status.Error(codes.Internal, "service disabled")
@@ -1297,7 +1364,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			return
 		}
 
-		ctx, err := runtime.RequestAccepted(inctx, internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec, "EchoService", "Echo", w, req)
+		ctx, err := runtime.RequestAcceptedFor(inctx, method_Echo_4, w, req)
 		if err != nil {
 			grpclog.Errorf("runtime.RequestAccepted returns error: %v", err)
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1312,7 +1379,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
 			return
 		}
-		resp, md, err := request_EchoService_Echo_4(ctx, inboundMarshaler, client, req, pathParams)
+		resp, md, err := request_EchoService_Echo_4(ctx, inboundMarshaler, client, req, pathParams, method_Echo_4)
 		ctx = runtime.NewServerMetadataContext(ctx, md)
 		if err != nil {
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1323,12 +1390,25 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 
 	})
 
-	runtime.AddMethod(spec, "EchoService", "EchoBody", "/v1/example/echo_body", "POST", true, false, false, "UNSPECIFIED", "JANUS_GATEWAY", "JANUS_AUTH_TOKEN", "")
-	mux.Handle("POST", pattern_EchoService_EchoBody_0, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
-		// TODO(mojz): review all locking/unlocking logic.
-		// internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RLock()
-		// defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RUnlock()
-		client := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client
+	method_EchoBody_0 := runtime.AddMethod(spec, "EchoService", "EchoBody", "/v1/example/echo_body", "POST", &runtime.MethodOptions{
+		LoginRequired:      true,
+		ClientSignRequired: false,
+		IsThirdParty:       false,
+		SpecSourceType:     "UNSPECIFIED",
+		ApiSource:          "JANUS_GATEWAY",
+		TokenType:          "JANUS_AUTH_TOKEN",
+		Timeout:            "",
+		RateLimit:          0,
+		RateLimitBurst:     0,
+		MaxAttempts:        0,
+		Hedge:              false,
+		HedgeDelay:         "",
+		MaxBodyBytes:       0,
+		ClientStreaming:    false,
+		ServerStreaming:    false,
+	})
+	mux.HandleMethod("POST", pattern_EchoService_EchoBody_0, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, method_EchoBody_0, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		client, _ := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client.Load().(EchoServiceClient)
 		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
 		if client == nil {
 			err := status.Error(codes.Internal, "service disabled")
@@ -1336,7 +1416,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			return
 		}
 
-		ctx, err := runtime.RequestAccepted(inctx, internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec, "EchoService", "EchoBody", w, req)
+		ctx, err := runtime.RequestAcceptedFor(inctx, method_EchoBody_0, w, req)
 		if err != nil {
 			grpclog.Errorf("runtime.RequestAccepted returns error: %v", err)
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1351,7 +1431,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
 			return
 		}
-		resp, md, err := request_EchoService_EchoBody_0(ctx, inboundMarshaler, client, req, pathParams)
+		resp, md, err := request_EchoService_EchoBody_0(ctx, inboundMarshaler, client, req, pathParams, method_EchoBody_0)
 		ctx = runtime.NewServerMetadataContext(ctx, md)
 		if err != nil {
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1362,12 +1442,25 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 
 	})
 
-	runtime.AddMethod(spec, "EchoService", "EchoDelete", "/v1/example/echo_delete", "DELETE", true, false, false, "UNSPECIFIED", "JANUS_GATEWAY", "JANUS_AUTH_TOKEN", "")
-	mux.Handle("DELETE", pattern_EchoService_EchoDelete_0, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
-		// TODO(mojz): review all locking/unlocking logic.
-		// internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RLock()
-		// defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RUnlock()
-		client := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client
+	method_EchoDelete_0 := runtime.AddMethod(spec, "EchoService", "EchoDelete", "/v1/example/echo_delete", "DELETE", &runtime.MethodOptions{
+		LoginRequired:      true,
+		ClientSignRequired: false,
+		IsThirdParty:       false,
+		SpecSourceType:     "UNSPECIFIED",
+		ApiSource:          "JANUS_GATEWAY",
+		TokenType:          "JANUS_AUTH_TOKEN",
+		Timeout:            "",
+		RateLimit:          0,
+		RateLimitBurst:     0,
+		MaxAttempts:        0,
+		Hedge:              false,
+		HedgeDelay:         "",
+		MaxBodyBytes:       0,
+		ClientStreaming:    false,
+		ServerStreaming:    false,
+	})
+	mux.HandleMethod("DELETE", pattern_EchoService_EchoDelete_0, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, method_EchoDelete_0, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		client, _ := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client.Load().(EchoServiceClient)
 		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
 		if client == nil {
 			err := status.Error(codes.Internal, "service disabled")
@@ -1375,7 +1468,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			return
 		}
 
-		ctx, err := runtime.RequestAccepted(inctx, internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec, "EchoService", "EchoDelete", w, req)
+		ctx, err := runtime.RequestAcceptedFor(inctx, method_EchoDelete_0, w, req)
 		if err != nil {
 			grpclog.Errorf("runtime.RequestAccepted returns error: %v", err)
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1390,7 +1483,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
 			return
 		}
-		resp, md, err := request_EchoService_EchoDelete_0(ctx, inboundMarshaler, client, req, pathParams)
+		resp, md, err := request_EchoService_EchoDelete_0(ctx, inboundMarshaler, client, req, pathParams, method_EchoDelete_0)
 		ctx = runtime.NewServerMetadataContext(ctx, md)
 		if err != nil {
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1401,12 +1494,25 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 
 	})
 
-	runtime.AddMethod(spec, "EchoService", "EchoPatch", "/v1/example/echo_patch", "PATCH", true, false, false, "UNSPECIFIED", "JANUS_GATEWAY", "JANUS_AUTH_TOKEN", "")
-	mux.Handle("PATCH", pattern_EchoService_EchoPatch_0, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
-		// TODO(mojz): review all locking/unlocking logic.
-		// internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RLock()
-		// defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RUnlock()
-		client := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client
+	method_EchoPatch_0 := runtime.AddMethod(spec, "EchoService", "EchoPatch", "/v1/example/echo_patch", "PATCH", &runtime.MethodOptions{
+		LoginRequired:      true,
+		ClientSignRequired: false,
+		IsThirdParty:       false,
+		SpecSourceType:     "UNSPECIFIED",
+		ApiSource:          "JANUS_GATEWAY",
+		TokenType:          "JANUS_AUTH_TOKEN",
+		Timeout:            "",
+		RateLimit:          0,
+		RateLimitBurst:     0,
+		MaxAttempts:        0,
+		Hedge:              false,
+		HedgeDelay:         "",
+		MaxBodyBytes:       0,
+		ClientStreaming:    false,
+		ServerStreaming:    false,
+	})
+	mux.HandleMethod("PATCH", pattern_EchoService_EchoPatch_0, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, method_EchoPatch_0, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		client, _ := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client.Load().(EchoServiceClient)
 		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
 		if client == nil {
 			err := status.Error(codes.Internal, "service disabled")
@@ -1414,7 +1520,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			return
 		}
 
-		ctx, err := runtime.RequestAccepted(inctx, internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec, "EchoService", "EchoPatch", w, req)
+		ctx, err := runtime.RequestAcceptedFor(inctx, method_EchoPatch_0, w, req)
 		if err != nil {
 			grpclog.Errorf("runtime.RequestAccepted returns error: %v", err)
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1429,7 +1535,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
 			return
 		}
-		resp, md, err := request_EchoService_EchoPatch_0(ctx, inboundMarshaler, client, req, pathParams)
+		resp, md, err := request_EchoService_EchoPatch_0(ctx, inboundMarshaler, client, req, pathParams, method_EchoPatch_0)
 		ctx = runtime.NewServerMetadataContext(ctx, md)
 		if err != nil {
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1440,12 +1546,25 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 
 	})
 
-	runtime.AddMethod(spec, "EchoService", "EchoValidationRule", "/v1/example/echo:validationRules", "POST", true, false, false, "UNSPECIFIED", "JANUS_GATEWAY", "JANUS_AUTH_TOKEN", "")
-	mux.Handle("POST", pattern_EchoService_EchoValidationRule_0, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
-		// TODO(mojz): review all locking/unlocking logic.
-		// internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RLock()
-		// defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.RUnlock()
-		client := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client
+	method_EchoValidationRule_0 := runtime.AddMethod(spec, "EchoService", "EchoValidationRule", "/v1/example/echo:validationRules", "POST", &runtime.MethodOptions{
+		LoginRequired:      true,
+		ClientSignRequired: false,
+		IsThirdParty:       false,
+		SpecSourceType:     "UNSPECIFIED",
+		ApiSource:          "JANUS_GATEWAY",
+		TokenType:          "JANUS_AUTH_TOKEN",
+		Timeout:            "",
+		RateLimit:          0,
+		RateLimitBurst:     0,
+		MaxAttempts:        0,
+		Hedge:              false,
+		HedgeDelay:         "",
+		MaxBodyBytes:       0,
+		ClientStreaming:    false,
+		ServerStreaming:    false,
+	})
+	mux.HandleMethod("POST", pattern_EchoService_EchoValidationRule_0, vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, method_EchoValidationRule_0, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		client, _ := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client.Load().(EchoServiceClient)
 		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
 		if client == nil {
 			err := status.Error(codes.Internal, "service disabled")
@@ -1453,7 +1572,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			return
 		}
 
-		ctx, err := runtime.RequestAccepted(inctx, internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec, "EchoService", "EchoValidationRule", w, req)
+		ctx, err := runtime.RequestAcceptedFor(inctx, method_EchoValidationRule_0, w, req)
 		if err != nil {
 			grpclog.Errorf("runtime.RequestAccepted returns error: %v", err)
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1468,7 +1587,7 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
 			return
 		}
-		resp, md, err := request_EchoService_EchoValidationRule_0(ctx, inboundMarshaler, client, req, pathParams)
+		resp, md, err := request_EchoService_EchoValidationRule_0(ctx, inboundMarshaler, client, req, pathParams, method_EchoValidationRule_0)
 		ctx = runtime.NewServerMetadataContext(ctx, md)
 		if err != nil {
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -1483,46 +1602,48 @@ func RegisterEchoServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux
 }
 
 func Disable_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_ServiceGroup() {
-	// internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.Lock()
-	// defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.Unlock()
+	internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.Lock()
+	defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.Unlock()
 	if internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_skycli != nil {
 		spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
 		sg := runtime.GetServiceGroup(spec)
 		for _, svc := range sg.Services {
 			svc.Disable()
+			svc.SetConn(nil)
 		}
 
-		internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client = nil
 		internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_skycli.Shutdown()
 		internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_skycli = nil
 	}
 }
 
 func Enable_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_ServiceGroup() {
-	// internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.Lock()
-	// defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.Unlock()
+	internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.Lock()
+	defer internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock.Unlock()
 
 	internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_skycli = client.NewServiceCli(runtime.CallerServiceId)
 
 	// Resolve service
 	spec := internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec
+	internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_skycli.AddUnaryInterceptor(runtime.ClientInterceptor(spec))
 
 	internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_skycli.Resolve(spec)
 
 	internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_skycli.Start(func(spec *skypb.ServiceSpec, conn *grpc.ClientConn) {
 		sg := runtime.GetServiceGroup(spec)
 		for _, svc := range sg.Services {
+			svc.SetConn(conn)
 			svc.Enable(spec, conn)
 		}
 	})
 }
 
 func EnableEchoService_Service(spec *skypb.ServiceSpec, conn *grpc.ClientConn) {
-	internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client = NewEchoServiceClient(conn)
+	internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client.Store(NewEchoServiceClient(conn))
 }
 
 func DisableEchoService_Service() {
-	internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client = nil
+	internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client.Store(nil)
 }
 
 var (
@@ -1567,9 +1688,9 @@ var (
 
 var (
 	internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_spec   = client.NewServiceSpec("default", vexpb.ServiceId_CUSTOM_JANUS_GATEWAY_TEST, "grpc")
-	internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client EchoServiceClient
+	internal_EchoService_CUSTOM_JANUS_GATEWAY_TEST_client runtime.ServiceClient
 
 	internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_skycli client.ServiceCli
 
-	internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock = sync.RWMutex{}
+	internal_CUSTOM_JANUS_GATEWAY_TEST__default__grpc_lock sync.RWMutex
 )
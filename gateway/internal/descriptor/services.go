@@ -117,6 +117,12 @@ func (r *Registry) newMethod(svc *Service, md *descriptorpb.MethodDescriptorProt
 		meth.ApiSource = mopts.ApiSource
 		meth.TokenType = mopts.TokenType
 		meth.Timeout = mopts.Timeout
+		meth.RateLimit = mopts.RateLimit
+		meth.RateLimitBurst = mopts.RateLimitBurst
+		meth.MaxAttempts = mopts.MaxAttempts
+		meth.Hedge = mopts.Hedge
+		meth.HedgeDelay = mopts.HedgeDelay
+		meth.MaxBodyBytes = mopts.MaxBodyBytes
 	}
 
 	newBinding := func(opts *options.HttpRule, idx int) (*Binding, error) {
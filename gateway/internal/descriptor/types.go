@@ -315,6 +315,12 @@ type Method struct {
 	SpecSourceType     options.SpecSourceType
 	HashKey            string
 	Timeout            string
+	RateLimit          float64
+	RateLimitBurst     int32
+	MaxAttempts        int32
+	Hedge              bool
+	HedgeDelay         string
+	MaxBodyBytes       int64
 }
 
 // FQMN returns a fully qualified rpc method name of this method.
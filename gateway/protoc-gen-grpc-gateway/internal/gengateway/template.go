@@ -448,13 +448,16 @@ var {{.GetUniqueName}}_error = lgr.ToGrpcError(codes.InvalidArgument, &fpb.Error
 {{else}}
 {{template "client-rpc-request-func" .}}
 {{end}}
+{{if or .Method.GetClientStreaming .Method.GetServerStreaming}}
+{{template "websocket-request-func" .}}
+{{end}}
 `))
 
 	_ = template.Must(handlerTemplate.New("request-func-signature").Parse(strings.Replace(`
 {{if .Method.GetServerStreaming}}
-func request_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}(ctx context.Context, marshaler runtime.Marshaler, client {{.Method.Service.InstanceName}}Client, req *http.Request, pathParams map[string]string) ({{.Method.Service.InstanceName}}_{{.Method.GetName}}Client, runtime.ServerMetadata, error)
+func request_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}(ctx context.Context, marshaler runtime.Marshaler, client {{.Method.Service.InstanceName}}Client, req *http.Request, pathParams map[string]string, meth *runtime.Method) ({{.Method.Service.InstanceName}}_{{.Method.GetName}}Client, runtime.ServerMetadata, error)
 {{else}}
-func request_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}(ctx context.Context, marshaler runtime.Marshaler, client {{.Method.Service.InstanceName}}Client, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error)
+func request_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}(ctx context.Context, marshaler runtime.Marshaler, client {{.Method.Service.InstanceName}}Client, req *http.Request, pathParams map[string]string, meth *runtime.Method) (proto.Message, runtime.ServerMetadata, error)
 {{end}}`, "\n", "", -1)))
 
 	_ = template.Must(handlerTemplate.New("client-streaming-request-func").Parse(`
@@ -515,14 +518,13 @@ var (
 {{template "request-func-signature" .}} {
 	var protoReq {{.Method.RequestType.GoType .Method.Service.File.GoPkg.Path}}
 	var metadata runtime.ServerMetadata
-	spec := internal_{{.Method.Service.GetName}}_{{.Method.Service.ServiceId}}_spec
 {{if .Body}}
 	newReader, berr := utilities.IOReaderFactory(req.Body)
 	if berr != nil {
-		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", berr)
+		return nil, metadata, runtime.RequestBodyError(berr)
 	}
 	if err := marshaler.NewDecoder(newReader()).Decode(&{{.Body.AssignableExpr "protoReq"}}); err != nil && err != io.EOF  {
-		runtime.RequestHandled(ctx, spec, "{{.Method.Service.GetName}}", "{{.Method.GetName}}", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
 	{{- if and $AllowPatchFeature (eq (.HTTPMethod) "PATCH") (.FieldMaskField) (not (eq "*" .GetBodyFieldPath)) }}
@@ -553,7 +555,7 @@ var (
 	{{$enum := $binding.LookupEnum $param}}
 	val, ok = pathParams[{{$param | printf "%q"}}]
 	if !ok {
-		runtime.RequestHandled(ctx, spec, "{{.Method.Service.GetName}}", "{{.Method.GetName}}", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", {{$param | printf "%q"}})
 	}
 {{if $param.IsNestedProto3}}
@@ -615,17 +617,18 @@ var (
 	// Validate
 	// {{.Method.RequestType.GoType .Method.Service.File.GoPkg.Path}}
 	if err :={{.Method.RequestType.GetValidationMethodName}}(&protoReq); err != nil {
-		runtime.RequestHandled(ctx, spec, "{{.Method.Service.GetName}}", "{{.Method.GetName}}", nil, &metadata, err)
+		runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
 		return nil, metadata, err
 	}
 	{{end}}
-	runtime.RequestParsed(ctx, spec, "{{.Method.Service.GetName}}", "{{.Method.GetName}}", &protoReq, &metadata)
+	runtime.RequestParsedFor(ctx, meth, &protoReq, &metadata)
 	ctx = runtime.PreLoadBalance(ctx, "{{$.Method.Service.Balancer.String}}", "{{.Method.HashKey}}", &protoReq)
+	ctx = runtime.WithServiceMethod(ctx, meth)
 	msg, err := client.{{.Method.GetName}}(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
 	// if err != nil {
 	// 	grpclog.Errorf("client.%s returns error: %v", "{{.Method.GetName}}", err)		
 	// }
-	runtime.RequestHandled(ctx, spec, "{{.Method.Service.GetName}}", "{{.Method.GetName}}", msg, &metadata, err)
+	runtime.RequestHandledFor(ctx, meth, msg, &metadata, err)
 	return msg, metadata, err
 {{end}}
 }`))
@@ -673,6 +676,42 @@ var (
 	metadata.HeaderMD = header
 	return stream, metadata, nil
 }
+`))
+
+	_ = template.Must(handlerTemplate.New("websocket-request-func").Parse(`
+func websocket_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}(ctx context.Context, marshaler runtime.Marshaler, client {{.Method.Service.InstanceName}}Client, req *http.Request, pathParams map[string]string, meth *runtime.Method) (*runtime.WebSocketStream, error) {
+{{if .Method.GetClientStreaming}}
+	stream, err := client.{{.Method.GetName}}(ctx)
+	if err != nil {
+		grpclog.Infof("Failed to start streaming: %v", err)
+		return nil, err
+	}
+{{else}}
+	stream, _, err := request_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}(ctx, marshaler, client, req, pathParams, meth)
+	if err != nil {
+		return nil, err
+	}
+{{end}}
+	return &runtime.WebSocketStream{
+		Stream: stream,
+{{- if .Method.GetClientStreaming}}
+		NewRequest: func() proto.Message {
+			return new({{.Method.RequestType.GoType .Method.Service.File.GoPkg.Path}})
+		},
+{{- end}}
+		Recv: func() (proto.Message, error) {
+			msg := new({{.Method.ResponseType.GoType .Method.Service.File.GoPkg.Path}})
+			if err := stream.RecvMsg(msg); err != nil {
+				return nil, err
+			}
+{{- if .ResponseBody}}
+			return response_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}{msg}, nil
+{{- else}}
+			return msg, nil
+{{- end}}
+		},
+	}, nil
+}
 `))
 
 	localHandlerTemplate = template.Must(template.New("local-handler").Parse(`
@@ -698,7 +737,7 @@ func local_request_{{.Method.Service.GetName}}_{{.Method.GetName}}_{{.Index}}(ct
 {{if .Body}}
 	newReader, berr := utilities.IOReaderFactory(req.Body)
 	if berr != nil {
-		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", berr)
+		return nil, metadata, runtime.RequestBodyError(berr)
 	}
 	if err := marshaler.NewDecoder(newReader()).Decode(&{{.Body.AssignableExpr "protoReq"}}); err != nil && err != io.EOF  {
 		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
@@ -853,8 +892,10 @@ func init() {
 {{range $svc := .Services}}
 	spec = internal_{{$svc.GetName}}_{{$svc.ServiceId}}_spec
 	s = &runtime.Service {
-		Spec    : *spec,
+		Spec    : spec,
 		Name    : "{{$svc.GetName}}",
+		FullName: "{{$svc.File.GetPackage}}.{{$svc.GetName}}",
+		Balancer: "{{$svc.Balancer.String}}",
 		Register: Register{{$svc.GetName}}{{$.RegisterFuncSuffix}}FromEndpoint,
 		Enable  : Enable{{$svc.GetName}}_Service,
 		Disable : Disable{{$svc.GetName}}_Service,
@@ -912,12 +953,25 @@ func Register{{$svc.GetName}}{{$.RegisterFuncSuffix}}Client(ctx context.Context,
 
 	{{range $m := $svc.Methods}}
 	{{range $b := $m.Bindings}}
-	runtime.AddMethod(spec, "{{$svc.GetName}}", "{{$m.GetName}}", "{{$b.PathTmpl.Template}}", {{$b.HTTPMethod | printf "%q"}}, {{$m.LoginRequired}}, {{$m.ClientSignRequired}}, {{$m.IsThirdParty}}, "{{$m.SpecSourceType}}", "{{$m.ApiSource}}", "{{$m.TokenType}}", "{{$m.Timeout}}")
-	mux.Handle({{$b.HTTPMethod | printf "%q"}}, pattern_{{$svc.GetName}}_{{$m.GetName}}_{{$b.Index}}, vexpb.ServiceId_{{$svc.ServiceId}}, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
-		// TODO(mojz): review all locking/unlocking logic.
-		// internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock.RLock()
-		// defer internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock.RUnlock()
-		client := internal_{{$svc.GetName}}_{{$svc.ServiceId}}_client
+	method_{{$m.GetName}}_{{$b.Index}} := runtime.AddMethod(spec, "{{$svc.GetName}}", "{{$m.GetName}}", "{{$b.PathTmpl.Template}}", {{$b.HTTPMethod | printf "%q"}}, &runtime.MethodOptions{
+		LoginRequired:      {{$m.LoginRequired}},
+		ClientSignRequired: {{$m.ClientSignRequired}},
+		IsThirdParty:       {{$m.IsThirdParty}},
+		SpecSourceType:     "{{$m.SpecSourceType}}",
+		ApiSource:          "{{$m.ApiSource}}",
+		TokenType:          "{{$m.TokenType}}",
+		Timeout:            "{{$m.Timeout}}",
+		RateLimit:          {{$m.RateLimit}},
+		RateLimitBurst:     {{$m.RateLimitBurst}},
+		MaxAttempts:        {{$m.MaxAttempts}},
+		Hedge:              {{$m.Hedge}},
+		HedgeDelay:         "{{$m.HedgeDelay}}",
+		MaxBodyBytes:       {{$m.MaxBodyBytes}},
+		ClientStreaming:    {{$m.GetClientStreaming}},
+		ServerStreaming:    {{$m.GetServerStreaming}},
+	})
+	mux.HandleMethod({{$b.HTTPMethod | printf "%q"}}, pattern_{{$svc.GetName}}_{{$m.GetName}}_{{$b.Index}}, vexpb.ServiceId_{{$svc.ServiceId}}, method_{{$m.GetName}}_{{$b.Index}}, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		client, _ := internal_{{$svc.GetName}}_{{$svc.ServiceId}}_client.Load().({{$svc.InstanceName}}Client)
 		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
 		if client == nil {
 			err := status.Error(codes.Internal, "service disabled")
@@ -925,7 +979,7 @@ func Register{{$svc.GetName}}{{$.RegisterFuncSuffix}}Client(ctx context.Context,
 			return
 		}
 
-		ctx, err := runtime.RequestAccepted(inctx, internal_{{$svc.GetName}}_{{$svc.ServiceId}}_spec, "{{$svc.GetName}}", "{{$m.GetName}}", w, req)
+		ctx, err := runtime.RequestAcceptedFor(inctx, method_{{$m.GetName}}_{{$b.Index}}, w, req)
 		if err != nil {
 			grpclog.Errorf("runtime.RequestAccepted returns error: %v", err)
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -949,7 +1003,15 @@ func Register{{$svc.GetName}}{{$.RegisterFuncSuffix}}Client(ctx context.Context,
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
 			return
 		}
-		resp, md, err := request_{{$svc.GetName}}_{{$m.GetName}}_{{$b.Index}}(ctx, inboundMarshaler, client, req, pathParams)
+		{{- if or $m.GetClientStreaming $m.GetServerStreaming}}
+		if runtime.IsWebSocketRequest(req) {
+			runtime.ForwardWebSocket(ctx, mux, inboundMarshaler, outboundMarshaler, w, req, method_{{$m.GetName}}_{{$b.Index}}, func(ctx context.Context) (*runtime.WebSocketStream, error) {
+				return websocket_{{$svc.GetName}}_{{$m.GetName}}_{{$b.Index}}(ctx, inboundMarshaler, client, req, pathParams, method_{{$m.GetName}}_{{$b.Index}})
+			})
+			return
+		}
+		{{- end}}
+		resp, md, err := request_{{$svc.GetName}}_{{$m.GetName}}_{{$b.Index}}(ctx, inboundMarshaler, client, req, pathParams, method_{{$m.GetName}}_{{$b.Index}})
 		ctx = runtime.NewServerMetadataContext(ctx, md)
 		if err != nil {
 			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
@@ -994,29 +1056,30 @@ func (m response_{{$svc.GetName}}_{{$m.GetName}}_{{$b.Index}}) XXX_ResponseBody(
 
 {{if $svc.GenController}}
 func Disable_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_ServiceGroup() {
-	// internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock.Lock()
-	// defer internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock.Unlock()
+	internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock.Lock()
+	defer internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock.Unlock()
 	if internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_skycli != nil {
 		spec := internal_{{$svc.GetName}}_{{$svc.ServiceId}}_spec
 		sg := runtime.GetServiceGroup(spec)
 		for _, svc := range sg.Services {
 			svc.Disable()
+			svc.SetConn(nil)
 		}
 
-		internal_{{$svc.GetName}}_{{$svc.ServiceId}}_client = nil
 		internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_skycli.Shutdown()
 		internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_skycli = nil
 	}
 }
 
 func Enable_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_ServiceGroup() {
-	// internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock.Lock()
-	// defer internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock.Unlock()
+	internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock.Lock()
+	defer internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock.Unlock()
 
 	internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_skycli = client.NewServiceCli(runtime.CallerServiceId)
 
 	// Resolve service
 	spec := internal_{{$svc.GetName}}_{{$svc.ServiceId}}_spec
+	internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_skycli.AddUnaryInterceptor(runtime.ClientInterceptor(spec))
 
 	{{if eq $svc.Balancer.String "ROUND_ROBIN"}}
 	internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_skycli.Resolve(spec)
@@ -1027,6 +1090,7 @@ func Enable_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_ServiceGro
 	internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_skycli.Start(func(spec *skypb.ServiceSpec, conn *grpc.ClientConn) {
 		sg := runtime.GetServiceGroup(spec)
 		for _, svc := range sg.Services {
+			svc.SetConn(conn)
 			svc.Enable(spec, conn)
 		}
 	})
@@ -1034,11 +1098,11 @@ func Enable_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_ServiceGro
 {{end}}
 
 func Enable{{$svc.GetName}}_Service(spec *skypb.ServiceSpec, conn *grpc.ClientConn) {
-	internal_{{$svc.GetName}}_{{$svc.ServiceId}}_client = New{{$svc.GetName}}Client(conn)
+	internal_{{$svc.GetName}}_{{$svc.ServiceId}}_client.Store(New{{$svc.GetName}}Client(conn))
 }
 
 func Disable{{$svc.GetName}}_Service() {
-	internal_{{$svc.GetName}}_{{$svc.ServiceId}}_client = nil
+	internal_{{$svc.GetName}}_{{$svc.ServiceId}}_client.Store(nil)
 }
 
 var (
@@ -1059,11 +1123,11 @@ var (
 
 var (
 	internal_{{$svc.GetName}}_{{$svc.ServiceId}}_spec = client.NewServiceSpec("{{$svc.Namespace}}", vexpb.ServiceId_{{$svc.ServiceId}}, "{{$svc.PortName}}")
-	internal_{{$svc.GetName}}_{{$svc.ServiceId}}_client {{$svc.GetName}}Client
+	internal_{{$svc.GetName}}_{{$svc.ServiceId}}_client runtime.ServiceClient
 	{{if $svc.GenController}}
 	internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_skycli client.ServiceCli
 
-	internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock =sync.RWMutex{}
+	internal_{{$svc.ServiceId}}__{{$svc.Namespace}}__{{$svc.PortName}}_lock sync.RWMutex
 	{{end}}
 )
 
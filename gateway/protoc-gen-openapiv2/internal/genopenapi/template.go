@@ -1349,6 +1349,20 @@ func renderServices(services []*descriptor.Service, paths openapiPathsObject, re
 						}
 					}
 				}
+				if b.Body != nil && meth.MaxBodyBytes > 0 {
+					// The gateway rejects larger request bodies.
+					tooLarge := openapiResponseObject{
+						Description: fmt.Sprintf("Request body larger than %d bytes.", meth.MaxBodyBytes),
+					}
+					if errDef, hasErrDef := fullyQualifiedNameToOpenAPIName(".google.rpc.Status", reg); hasErrDef {
+						tooLarge.Schema = openapiSchemaObject{
+							schemaCore: schemaCore{
+								Ref: fmt.Sprintf("#/definitions/%s", errDef),
+							},
+						}
+					}
+					operationObject.Responses["413"] = tooLarge
+				}
 				operationObject.OperationID = fmt.Sprintf("%s_%s", svc.GetName(), meth.GetName())
 				if reg.GetSimpleOperationIDs() {
 					operationObject.OperationID = meth.GetName()
@@ -1461,6 +1475,12 @@ func renderServices(services []*descriptor.Service, paths openapiPathsObject, re
 
 					// TODO(ivucica): add remaining fields of operation object
 				}
+				if b.Body != nil && meth.MaxBodyBytes > 0 {
+					operationObject.extensions = append(operationObject.extensions, extension{
+						key:   "x-max-body-bytes",
+						value: json.RawMessage(strconv.FormatInt(meth.MaxBodyBytes, 10)),
+					})
+				}
 
 				switch b.HTTPMethod {
 				case "DELETE":
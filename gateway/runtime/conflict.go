@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+)
+
+// RouteConflict reports a route which is identical to or fully shadows a
+// route of another owner registered before it. Which one of the two serves
+// the requests depends on the registration order, which in turn depends on
+// the init order of the services.
+type RouteConflict struct {
+	// HttpMethod is the HTTP method of both routes.
+	HttpMethod string
+	// Pattern and Owner are the route registered later, which wins the
+	// requests both routes match.
+	Pattern Pattern
+	Owner   string
+	// Existing and ExistingOwner are the route registered before.
+	Existing      Pattern
+	ExistingOwner string
+	// Identical reports whether both routes match the same paths; otherwise
+	// one of them matches a subset of the paths of the other.
+	Identical bool
+}
+
+func (c RouteConflict) String() string {
+	rel := "shadows"
+	if c.Identical {
+		rel = "is identical to"
+	}
+	return fmt.Sprintf("%s %s of %s %s %s %s of %s", c.HttpMethod, c.Pattern, c.Owner, rel, c.HttpMethod, c.Existing, c.ExistingOwner)
+}
+
+// routeSeg is a path segment of a pattern: a literal, "*" or "**".
+type routeSeg struct {
+	lit  string
+	code utilities.OpCode
+}
+
+// segments returns the path segments the pattern matches.
+func (p Pattern) segments() []routeSeg {
+	var segs []routeSeg
+	for _, op := range p.ops {
+		switch op.code {
+		case utilities.OpLitPush:
+			segs = append(segs, routeSeg{lit: p.pool[op.operand], code: op.code})
+		case utilities.OpPush, utilities.OpPushM:
+			segs = append(segs, routeSeg{code: op.code})
+		}
+	}
+	return segs
+}
+
+// covers reports whether every path matched by the segments p is matched by
+// the segments q.
+func covers(q, p []routeSeg) bool {
+	for len(q) > 0 {
+		switch q[0].code {
+		case utilities.OpPushM:
+			// "**" matches any number of segments, including none.
+			for k := 0; k <= len(p); k++ {
+				if covers(q[1:], p[k:]) {
+					return true
+				}
+			}
+			return false
+		case utilities.OpPush:
+			if len(p) == 0 || p[0].code == utilities.OpPushM {
+				return false
+			}
+		default:
+			if len(p) == 0 || p[0].code != utilities.OpLitPush || p[0].lit != q[0].lit {
+				return false
+			}
+		}
+		q, p = q[1:], p[1:]
+	}
+	return len(p) == 0
+}
+
+// conflict returns the conflict of the handler h with the handler e
+// registered before it, if any.
+func (h handler) conflict(meth string, e handler) (RouteConflict, bool) {
+	if h.sameOwner(e) || h.pat.verb != e.pat.verb {
+		return RouteConflict{}, false
+	}
+	newer, older := covers(h.segs, e.segs), covers(e.segs, h.segs)
+	if !newer && !older {
+		return RouteConflict{}, false
+	}
+	return RouteConflict{
+		HttpMethod:    meth,
+		Pattern:       h.pat,
+		Owner:         h.owner(),
+		Existing:      e.pat,
+		ExistingOwner: e.owner(),
+		Identical:     newer && older,
+	}, true
+}
+
+// sameOwner reports whether both handlers belong to the same service. The
+// handlers registered without a method belong to their service ID.
+func (h handler) sameOwner(e handler) bool {
+	if h.meth != nil && e.meth != nil {
+		return h.meth.Service() == e.meth.Service()
+	}
+	return h.meth == nil && e.meth == nil && h.serviceId == e.serviceId
+}
+
+// owner describes the service and the method of the handler.
+func (h handler) owner() string {
+	if svc := h.meth.Service(); svc != nil {
+		return fmt.Sprintf("%s.%s (%s)", svc.Name, h.meth.Name, h.serviceId)
+	}
+	return h.serviceId.String()
+}
@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/binchencoder/gateway-proto/data"
+)
+
+func TestRouteConflicts(t *testing.T) {
+	svcA := &Service{Name: "ServiceA"}
+	svcB := &Service{Name: "ServiceB"}
+	methodA := &Method{Name: "Get", svc: svcA}
+	methodA2 := &Method{Name: "List", svc: svcA}
+	methodB := &Method{Name: "Get", svc: svcB}
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, pathParams map[string]string) {}
+
+	for _, spec := range []struct {
+		name      string
+		existing  string
+		exMethod  *Method
+		path      string
+		method    *Method
+		conflict  bool
+		identical bool
+	}{
+		{name: "identical", existing: "/v1/foo/{id}", exMethod: methodA, path: "/v1/foo/{name}", method: methodB, conflict: true, identical: true},
+		{name: "new shadows", existing: "/v1/foo/bar", exMethod: methodA, path: "/v1/foo/{id}", method: methodB, conflict: true},
+		{name: "existing shadows", existing: "/v1/foo/{id=**}", exMethod: methodA, path: "/v1/foo/bar/baz", method: methodB, conflict: true},
+		{name: "deep wildcard matches none", existing: "/v1/foo/{id=**}", exMethod: methodA, path: "/v1/foo", method: methodB, conflict: true},
+		{name: "root wildcard shadows", existing: "/{name=**}", exMethod: methodA, path: "/v1/foo/bar", method: methodB, conflict: true},
+		{name: "new root wildcard", existing: "/v1/foo/bar", exMethod: methodA, path: "/{name=**}", method: methodB, conflict: true},
+		{name: "same service", existing: "/v1/foo/{id}", exMethod: methodA, path: "/v1/foo/bar", method: methodA2},
+		{name: "disjoint", existing: "/v1/foo/{id}", exMethod: methodA, path: "/v1/bar/{id}", method: methodB},
+		{name: "overlapping", existing: "/v1/{a}/bar", exMethod: methodA, path: "/v1/foo/{b}", method: methodB},
+		{name: "different length", existing: "/v1/foo/{id}", exMethod: methodA, path: "/v1/foo/{id}/bar", method: methodB},
+		{name: "different verb", existing: "/v1/foo/{id}:run", exMethod: methodA, path: "/v1/foo/{id}", method: methodB},
+		{name: "unowned", existing: "/v1/foo", path: "/v1/foo", method: methodB, conflict: true, identical: true},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			mux := NewServeMux()
+			for _, r := range []struct {
+				path string
+				m    *Method
+			}{{spec.existing, spec.exMethod}, {spec.path, spec.method}} {
+				pat, err := parsePattern(r.path)
+				if err != nil {
+					t.Fatalf("parsing %q failed with %v", r.path, err)
+				}
+				mux.HandleMethod("GET", pat, data.ServiceId(1), r.m, noop)
+			}
+
+			conflicts := mux.RouteConflicts()
+			if got := len(conflicts) > 0; got != spec.conflict {
+				t.Fatalf("mux.RouteConflicts() = %v; want conflict %t", conflicts, spec.conflict)
+			}
+			if !spec.conflict {
+				return
+			}
+			c := conflicts[0]
+			if c.Identical != spec.identical {
+				t.Errorf("conflict.Identical = %t; want %t", c.Identical, spec.identical)
+			}
+			if c.Pattern.String() == "" || c.Owner == c.ExistingOwner {
+				t.Errorf("conflict = %s; want both owners", c)
+			}
+		})
+	}
+}
+
+func parsePattern(path string) (Pattern, error) {
+	var p Pattern
+	mux := NewServeMux()
+	if err := mux.HandlePath("GET", path, 0, nil); err != nil {
+		return p, err
+	}
+	return mux.handlers["GET"][0].pat, nil
+}
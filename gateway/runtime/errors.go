@@ -36,6 +36,27 @@ func (e *HTTPStatusError) Error() string {
 	return e.Err.Error()
 }
 
+// statusOf returns the gRPC status of the error, unwrapping a
+// HTTPStatusError, whose HTTP status does not apply to gRPC responses.
+func statusOf(err error) *status.Status {
+	var customStatus *HTTPStatusError
+	if errors.As(err, &customStatus) {
+		err = customStatus.Err
+	}
+	return status.Convert(err)
+}
+
+// RequestBodyError returns the error of reading the request body, which is
+// InvalidArgument unless it is a HTTPStatusError, e.g. returned by the body
+// limited by the gateway hook.
+func RequestBodyError(err error) error {
+	var customStatus *HTTPStatusError
+	if errors.As(err, &customStatus) {
+		return err
+	}
+	return status.Errorf(codes.InvalidArgument, "%v", err)
+}
+
 // HTTPStatusFromCode converts a gRPC error code into the corresponding HTTP response status.
 // See: https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
 func HTTPStatusFromCode(code codes.Code) int {
@@ -0,0 +1,221 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const grpcContentType = "application/grpc"
+
+// IsGRPCRequest reports whether the request is a gRPC call over HTTP/2. The
+// calls with a content subtype other than proto are rejected by ServeGRPC.
+func IsGRPCRequest(r *http.Request) bool {
+	if r.ProtoMajor != 2 || r.Method != http.MethodPost {
+		return false
+	}
+	ct := r.Header.Get("Content-Type")
+	return ct == grpcContentType || strings.HasPrefix(ct, grpcContentType+"+") || strings.HasPrefix(ct, grpcContentType+";")
+}
+
+// ServeGRPC proxies the gRPC call of the request to the connection of the
+// service which the generated handlers call, by the gRPC method name in the
+// path. Only the methods with HTTP bindings are served. The call goes through
+// the stages of GatewayServiceHook like the REST calls of the method, with the
+// metadata of the client as the HTTP headers, but its messages are passed
+// through without being decoded.
+//
+// The compressed messages of a call with grpc-encoding are sent to the
+// backend with the same encoding, which the responses are compressed with as
+// well if the client accepts it.
+func (s *ServeMux) ServeGRPC(w http.ResponseWriter, r *http.Request) {
+	gw := &grpcWriter{w: w}
+	ctx, err := RequestReceived(w, r)
+	if err != nil {
+		gw.writeStatus(nil, err)
+		return
+	}
+	if err := checkProxyContentType(r.Header.Get("Content-Type"), grpcContentType); err != nil {
+		gw.writeStatus(nil, err)
+		return
+	}
+	var comp encoding.Compressor
+	var opts []grpc.CallOption
+	if name := r.Header.Get("Grpc-Encoding"); name != "" && name != "identity" {
+		if comp = encoding.GetCompressor(name); comp == nil {
+			gw.writeStatus(nil, status.Errorf(codes.Unimplemented, "grpc: Decompressor is not installed for grpc-encoding %q", name))
+			return
+		}
+		opts = append(opts, grpc.UseCompressor(name))
+		if acceptsGRPCEncoding(r, name) {
+			gw.comp = comp
+		}
+	}
+
+	m, conn, err := resolveProxyMethod(r.URL.Path)
+	if err != nil {
+		gw.writeStatus(nil, err)
+		return
+	}
+	if actx, err := RequestAcceptedFor(ctx, m, w, r); err != nil {
+		gw.writeStatus(nil, err)
+		return
+	} else if actx != nil {
+		ctx = actx
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx, err = AnnotateContext(ctx, s, r, r.URL.Path)
+	if err != nil {
+		gw.writeStatus(nil, err)
+		return
+	}
+
+	var md ServerMetadata
+	peer := &grpcPeer{r: r, w: gw, limit: messageLimit(m, MaxProxyMessageBytes), dc: comp}
+	err = proxyCall(ctx, conn, r.URL.Path, m, peer, &md, opts...)
+	RequestHandledFor(ctx, m, nil, &md, err)
+	gw.writeHeader(md.HeaderMD)
+	gw.writeStatus(md.TrailerMD, err)
+}
+
+// grpcPeer is the client of a gRPC call, whose request messages are read
+// while the responses are written.
+type grpcPeer struct {
+	r     *http.Request
+	w     *grpcWriter
+	limit int64
+	// dc decompresses the request messages, if the client compresses them.
+	dc encoding.Compressor
+}
+
+// acceptsGRPCEncoding reports whether the client accepts the responses
+// compressed with the encoding.
+func acceptsGRPCEncoding(r *http.Request, name string) bool {
+	for _, v := range r.Header.Values("Grpc-Accept-Encoding") {
+		for _, e := range strings.Split(v, ",") {
+			if strings.TrimSpace(e) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *grpcPeer) RecvMsg() ([]byte, error) {
+	return readGRPCMessage(p.r.Body, p.limit, p.dc)
+}
+
+func (p *grpcPeer) SendHeader(md metadata.MD) error {
+	p.w.writeHeader(md)
+	// The header is sent before the first response, which a bidi streaming
+	// client may wait for.
+	if f, ok := p.w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+func (p *grpcPeer) SendMsg(msg []byte) error {
+	return p.w.writeMessage(msg)
+}
+
+// grpcWriter writes a gRPC response, whose status is sent in the HTTP
+// trailers.
+type grpcWriter struct {
+	w           http.ResponseWriter
+	wroteHeader bool
+	// comp compresses the response messages, if not nil.
+	comp encoding.Compressor
+}
+
+// writeHeader writes the header metadata as the HTTP headers, once.
+func (g *grpcWriter) writeHeader(md metadata.MD) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	h := g.w.Header()
+	for k, vs := range md {
+		if k == "content-type" || strings.HasPrefix(k, ":") {
+			continue
+		}
+		for _, v := range vs {
+			h.Add(k, encodeMetadataValue(k, v))
+		}
+	}
+	h.Set("Content-Type", grpcContentType)
+	if g.comp != nil {
+		h.Set("Grpc-Encoding", g.comp.Name())
+	}
+	g.w.WriteHeader(http.StatusOK)
+}
+
+// writeMessage writes a length-prefixed message, compressed with the
+// encoding of the response.
+func (g *grpcWriter) writeMessage(msg []byte) error {
+	g.writeHeader(nil)
+	var flag byte
+	if g.comp != nil {
+		var buf bytes.Buffer
+		cw, err := g.comp.Compress(&buf)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to compress the response: %v", err)
+		}
+		if _, err := cw.Write(msg); err != nil {
+			return status.Errorf(codes.Internal, "failed to compress the response: %v", err)
+		}
+		if err := cw.Close(); err != nil {
+			return status.Errorf(codes.Internal, "failed to compress the response: %v", err)
+		}
+		msg, flag = buf.Bytes(), 1
+	}
+	b := make([]byte, 5+len(msg))
+	b[0] = flag
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	copy(b[5:], msg)
+	_, err := g.w.Write(b)
+	if f, ok := g.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return err
+}
+
+// writeStatus writes the status of err and the trailer metadata as the HTTP
+// trailers.
+func (g *grpcWriter) writeStatus(trailer metadata.MD, err error) {
+	g.writeHeader(nil)
+	st := statusOf(err)
+	h := g.w.Header()
+	h.Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(int(st.Code())))
+	if msg := st.Message(); msg != "" {
+		h.Set(http.TrailerPrefix+"Grpc-Message", encodeGRPCMessage(msg))
+	}
+	if len(st.Details()) > 0 {
+		if b, err := proto.Marshal(st.Proto()); err == nil {
+			h.Set(http.TrailerPrefix+"Grpc-Status-Details-Bin", base64.RawStdEncoding.EncodeToString(b))
+		}
+	}
+	for k, vs := range trailer {
+		// The trailers-only responses of the backend have the content type
+		// in the trailer.
+		if k == "content-type" || strings.HasPrefix(k, ":") {
+			continue
+		}
+		for _, v := range vs {
+			h.Add(http.TrailerPrefix+k, encodeMetadataValue(k, v))
+		}
+	}
+}
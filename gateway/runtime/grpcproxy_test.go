@@ -0,0 +1,303 @@
+package runtime
+
+import (
+	"bytes"
+	stdgzip "compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// newTestGRPCProxy returns a client connection to a gateway serving the gRPC
+// calls with ServeGRPC, and the REST calls with 404, and the gateway.
+func newTestGRPCProxy(t *testing.T) (*grpc.ClientConn, *httptest.Server) {
+	mux := NewServeMux()
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsGRPCRequest(r) {
+			mux.ServeGRPC(w, r)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	t.Cleanup(s.Close)
+
+	creds := credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})
+	conn, err := grpc.Dial(s.Listener.Addr().String(), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, s
+}
+
+// grpcFrame returns a length-prefixed message of the gRPC wire format.
+func grpcFrame(flag byte, msg []byte) []byte {
+	b := make([]byte, 5+len(msg))
+	b[0] = flag
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	copy(b[5:], msg)
+	return b
+}
+
+func TestServeGRPC(t *testing.T) {
+	defer func(h GatewayServiceHook) { hook = h }(hook)
+	hook = nil
+	newTestProxyBackend(t, "grpcproxy.test")
+	conn, _ := newTestGRPCProxy(t)
+	ctx := context.Background()
+
+	t.Run("unary", func(t *testing.T) {
+		var header metadata.MD
+		out := new(wrapperspb.StringValue)
+		if err := conn.Invoke(ctx, "/grpcproxy.test.EchoService/Echo", wrapperspb.String("hello"), out, grpc.Header(&header)); err != nil {
+			t.Fatal(err)
+		}
+		if out.Value != "hello" {
+			t.Errorf("response = %q; want hello", out.Value)
+		}
+		if got := header.Get("x-echo"); len(got) != 1 || got[0] != "1" {
+			t.Errorf("x-echo header = %q; want [1]", got)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var trailer metadata.MD
+		err := conn.Invoke(ctx, "/grpcproxy.test.EchoService/Echo", wrapperspb.String("fail"), new(wrapperspb.StringValue), grpc.Trailer(&trailer))
+		if st := status.Convert(err); st.Code() != codes.NotFound || st.Message() != "no such echo" {
+			t.Errorf("status = %v; want NotFound, no such echo", st)
+		}
+		if got := trailer.Get("x-reason"); len(got) != 1 || got[0] != "failed" {
+			t.Errorf("x-reason trailer = %q; want [failed]", got)
+		}
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		out := new(wrapperspb.StringValue)
+		if err := conn.Invoke(ctx, "/grpcproxy.test.EchoService/Echo", wrapperspb.String("hello"), out, grpc.UseCompressor(gzip.Name)); err != nil {
+			t.Fatal(err)
+		}
+		if out.Value != "hello" {
+			t.Errorf("response = %q; want hello", out.Value)
+		}
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		err := conn.Invoke(ctx, "/grpcproxy.test.EchoService/Nope", wrapperspb.String("hello"), new(wrapperspb.StringValue))
+		if code := status.Code(err); code != codes.Unimplemented {
+			t.Errorf("status code = %v; want Unimplemented", code)
+		}
+	})
+
+	t.Run("server streaming", func(t *testing.T) {
+		stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/grpcproxy.test.EchoService/Repeat")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.SendMsg(wrapperspb.String("hi")); err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.CloseSend(); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for {
+			out := new(wrapperspb.StringValue)
+			if err := stream.RecvMsg(out); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, out.Value)
+		}
+		if len(got) != 3 {
+			t.Errorf("responses = %q; want 3 of hi", got)
+		}
+	})
+
+	t.Run("bidi streaming", func(t *testing.T) {
+		stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, "/grpcproxy.test.EchoService/Chat")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Each response is received before the next request is sent.
+		for _, in := range []string{"a", "b", "c"} {
+			if err := stream.SendMsg(wrapperspb.String(in)); err != nil {
+				t.Fatal(err)
+			}
+			out := new(wrapperspb.StringValue)
+			if err := stream.RecvMsg(out); err != nil {
+				t.Fatal(err)
+			}
+			if out.Value != in {
+				t.Errorf("response = %q; want %q", out.Value, in)
+			}
+		}
+		if err := stream.CloseSend(); err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.RecvMsg(new(wrapperspb.StringValue)); err != io.EOF {
+			t.Errorf("RecvMsg() after CloseSend = %v; want io.EOF", err)
+		}
+	})
+}
+
+func TestServeGRPCRaw(t *testing.T) {
+	defer func(h GatewayServiceHook) { hook = h }(hook)
+	hook = nil
+	newTestProxyBackend(t, "grpcproxy.raw")
+	_, s := newTestGRPCProxy(t)
+
+	msg, err := proto.Marshal(wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	zw := stdgzip.NewWriter(&gz)
+	zw.Write(msg)
+	zw.Close()
+
+	for _, spec := range []struct {
+		name     string
+		header   http.Header
+		body     []byte
+		code     codes.Code
+		encoding string
+	}{
+		{
+			name:   "identity",
+			header: http.Header{"Content-Type": {"application/grpc+proto"}},
+			body:   grpcFrame(0, msg),
+			code:   codes.OK,
+		},
+		{
+			name:   "json subtype",
+			header: http.Header{"Content-Type": {"application/grpc+json"}},
+			body:   grpcFrame(0, []byte(`"hello"`)),
+			code:   codes.Unimplemented,
+		},
+		{
+			name:   "gzip",
+			header: http.Header{"Content-Type": {"application/grpc"}, "Grpc-Encoding": {"gzip"}},
+			body:   grpcFrame(1, gz.Bytes()),
+			code:   codes.OK,
+		},
+		{
+			name:     "gzip accepted",
+			header:   http.Header{"Content-Type": {"application/grpc"}, "Grpc-Encoding": {"gzip"}, "Grpc-Accept-Encoding": {"identity, gzip"}},
+			body:     grpcFrame(1, gz.Bytes()),
+			code:     codes.OK,
+			encoding: "gzip",
+		},
+		{
+			name:   "unknown encoding",
+			header: http.Header{"Content-Type": {"application/grpc"}, "Grpc-Encoding": {"lz4"}},
+			body:   grpcFrame(1, msg),
+			code:   codes.Unimplemented,
+		},
+		{
+			name:   "compressed without encoding",
+			header: http.Header{"Content-Type": {"application/grpc"}},
+			body:   grpcFrame(1, gz.Bytes()),
+			code:   codes.Unimplemented,
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", s.URL+"/grpcproxy.raw.EchoService/Echo", bytes.NewReader(spec.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header = spec.header
+			req.Header.Set("Te", "trailers")
+			resp, err := s.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.Trailer.Get("Grpc-Status"); got != strconv.Itoa(int(spec.code)) {
+				t.Fatalf("grpc-status = %q (%s); want %d", got, resp.Trailer.Get("Grpc-Message"), spec.code)
+			}
+			if spec.code != codes.OK {
+				return
+			}
+			if got := resp.Header.Get("Grpc-Encoding"); got != spec.encoding {
+				t.Errorf("grpc-encoding = %q; want %q", got, spec.encoding)
+			}
+			if len(body) < 5 {
+				t.Fatalf("response = %q; want a message", body)
+			}
+			out := body[5:]
+			if spec.encoding != "" {
+				if body[0] != 1 {
+					t.Fatalf("response flag = %d; want compressed", body[0])
+				}
+				zr, err := stdgzip.NewReader(bytes.NewReader(out))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if out, err = io.ReadAll(zr); err != nil {
+					t.Fatal(err)
+				}
+			} else if body[0] != 0 {
+				t.Fatalf("response flag = %d; want uncompressed", body[0])
+			}
+			got := new(wrapperspb.StringValue)
+			if err := proto.Unmarshal(out, got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Value != "hello" {
+				t.Errorf("response = %q; want hello", got.Value)
+			}
+		})
+	}
+}
+
+func TestGRPCWriterHTTPStatusError(t *testing.T) {
+	w := httptest.NewRecorder()
+	g := &grpcWriter{w: w}
+	g.writeStatus(nil, &HTTPStatusError{HTTPStatus: http.StatusRequestEntityTooLarge, Err: status.Error(codes.InvalidArgument, "too large")})
+	h := w.Header()
+	if got, msg := h.Get(http.TrailerPrefix+"Grpc-Status"), h.Get(http.TrailerPrefix+"Grpc-Message"); got != strconv.Itoa(int(codes.InvalidArgument)) || msg != "too large" {
+		t.Errorf("grpc-status = %q (%s); want %d (too large)", got, msg, codes.InvalidArgument)
+	}
+}
+
+func TestIsGRPCRequest(t *testing.T) {
+	for _, spec := range []struct {
+		proto      int
+		method, ct string
+		want       bool
+	}{
+		{proto: 2, method: "POST", ct: "application/grpc", want: true},
+		{proto: 2, method: "POST", ct: "application/grpc+proto", want: true},
+		{proto: 2, method: "POST", ct: "application/grpc-web", want: false},
+		{proto: 1, method: "POST", ct: "application/grpc", want: false},
+		{proto: 2, method: "GET", ct: "application/grpc", want: false},
+	} {
+		req := httptest.NewRequest(spec.method, "/pkg.EchoService/Echo", nil)
+		req.ProtoMajor = spec.proto
+		req.Header.Set("Content-Type", spec.ct)
+		if got := IsGRPCRequest(req); got != spec.want {
+			t.Errorf("IsGRPCRequest(HTTP/%d %s %s) = %v; want %v", spec.proto, spec.method, spec.ct, got, spec.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+	grpcWebTrailerFlag     = 0x80
+)
+
+// IsGRPCWebRequest reports whether the request is a gRPC-Web call, in the
+// binary or the text format. The calls of the subtypes other than proto are
+// rejected by ServeGRPCWeb.
+func IsGRPCWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), grpcWebContentType)
+}
+
+// ServeGRPCWeb proxies the gRPC-Web call of the request to the connection of
+// the service which the generated handlers call, by the gRPC method name in
+// the path, e.g. /pkg.EchoService/Echo. Only the methods with HTTP bindings
+// are served. The call goes through the stages of GatewayServiceHook like the
+// REST calls of the method, but its messages are passed through as they are.
+// The status of the call is sent in the trailer frame of the response.
+func (s *ServeMux) ServeGRPCWeb(w http.ResponseWriter, r *http.Request) {
+	ct := r.Header.Get("Content-Type")
+	gw := &grpcWebWriter{w: w, contentType: ct, text: strings.HasPrefix(ct, grpcWebTextContentType)}
+	ctx, err := RequestReceived(w, r)
+	if err != nil {
+		gw.writeStatus(nil, err)
+		return
+	}
+	base := grpcWebContentType
+	if gw.text {
+		base = grpcWebTextContentType
+	}
+	if err := checkProxyContentType(ct, base); err != nil {
+		gw.writeStatus(nil, err)
+		return
+	}
+
+	m, conn, err := resolveProxyMethod(r.URL.Path)
+	if err != nil {
+		gw.writeStatus(nil, err)
+		return
+	}
+	if actx, err := RequestAcceptedFor(ctx, m, w, r); err != nil {
+		gw.writeStatus(nil, err)
+		return
+	} else if actx != nil {
+		ctx = actx
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx, err = AnnotateContext(ctx, s, r, r.URL.Path)
+	if err != nil {
+		gw.writeStatus(nil, err)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if gw.text {
+		body = &grpcWebTextReader{r: bufio.NewReader(r.Body)}
+	}
+	// The request messages are read before the response is written, which
+	// may end the request body of HTTP/1.x. They are held until the call, so
+	// their total size is limited.
+	var md ServerMetadata
+	peer := &grpcWebPeer{w: gw}
+	remaining := messageLimit(m, MaxProxyMessageBytes)
+	for {
+		msg, err := readGRPCMessage(body, remaining, nil)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			RequestHandledFor(ctx, m, nil, &md, err)
+			gw.writeStatus(nil, err)
+			return
+		}
+		peer.reqs = append(peer.reqs, msg)
+		remaining -= int64(len(msg))
+	}
+
+	err = proxyCall(ctx, conn, r.URL.Path, m, peer, &md)
+	RequestHandledFor(ctx, m, nil, &md, err)
+	gw.writeHeader(md.HeaderMD)
+	gw.writeStatus(md.TrailerMD, err)
+}
+
+// grpcWebPeer is the client of a gRPC-Web call, whose request messages are
+// read in advance.
+type grpcWebPeer struct {
+	w    *grpcWebWriter
+	reqs [][]byte
+}
+
+func (p *grpcWebPeer) RecvMsg() ([]byte, error) {
+	if len(p.reqs) == 0 {
+		return nil, io.EOF
+	}
+	msg := p.reqs[0]
+	p.reqs = p.reqs[1:]
+	return msg, nil
+}
+
+func (p *grpcWebPeer) SendHeader(md metadata.MD) error {
+	p.w.writeHeader(md)
+	return nil
+}
+
+func (p *grpcWebPeer) SendMsg(msg []byte) error {
+	return p.w.writeFrame(0, msg)
+}
+
+// grpcWebWriter writes the frames of a gRPC-Web response.
+type grpcWebWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	text        bool
+	wroteHeader bool
+}
+
+// writeHeader writes the header metadata as the HTTP headers, once.
+func (g *grpcWebWriter) writeHeader(md metadata.MD) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	h := g.w.Header()
+	for k, vs := range md {
+		if k == "content-type" || strings.HasPrefix(k, ":") {
+			continue
+		}
+		for _, v := range vs {
+			h.Add(k, encodeMetadataValue(k, v))
+		}
+	}
+	h.Set("Content-Type", g.contentType)
+	g.w.WriteHeader(http.StatusOK)
+}
+
+// writeFrame writes a length-prefixed frame, encoded with base64 in the text
+// format.
+func (g *grpcWebWriter) writeFrame(flag byte, payload []byte) error {
+	g.writeHeader(nil)
+	b := make([]byte, 5+len(payload))
+	b[0] = flag
+	binary.BigEndian.PutUint32(b[1:], uint32(len(payload)))
+	copy(b[5:], payload)
+	if g.text {
+		b = []byte(base64.StdEncoding.EncodeToString(b))
+	}
+	_, err := g.w.Write(b)
+	if f, ok := g.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return err
+}
+
+// writeStatus writes the trailer frame with the status of err and the
+// trailer metadata.
+func (g *grpcWebWriter) writeStatus(trailer metadata.MD, err error) {
+	st := statusOf(err)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "grpc-status: %d\r\n", st.Code())
+	if msg := st.Message(); msg != "" {
+		fmt.Fprintf(&buf, "grpc-message: %s\r\n", encodeGRPCMessage(msg))
+	}
+	if len(st.Details()) > 0 {
+		if b, err := proto.Marshal(st.Proto()); err == nil {
+			fmt.Fprintf(&buf, "grpc-status-details-bin: %s\r\n", base64.RawStdEncoding.EncodeToString(b))
+		}
+	}
+	for k, vs := range trailer {
+		for _, v := range vs {
+			fmt.Fprintf(&buf, "%s: %s\r\n", strings.ToLower(k), encodeMetadataValue(k, v))
+		}
+	}
+	g.writeFrame(grpcWebTrailerFlag, buf.Bytes())
+}
+
+// encodeMetadataValue encodes the binary metadata values with base64.
+func encodeMetadataValue(k, v string) string {
+	if strings.HasSuffix(k, "-bin") {
+		return base64.RawStdEncoding.EncodeToString([]byte(v))
+	}
+	return v
+}
+
+// encodeGRPCMessage percent-encodes the status message as the grpc-message
+// header.
+func encodeGRPCMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// grpcWebTextReader decodes the body of the gRPC-Web text format, which may
+// be the concatenation of padded base64 chunks, 4 characters at a time.
+type grpcWebTextReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func (t *grpcWebTextReader) Read(p []byte) (int, error) {
+	for len(t.buf) == 0 {
+		var q [4]byte
+		if _, err := io.ReadFull(t.r, q[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = status.Error(codes.InvalidArgument, "truncated base64 body")
+			}
+			return 0, err
+		}
+		var d [3]byte
+		n, err := base64.StdEncoding.Decode(d[:], q[:])
+		if err != nil {
+			return 0, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		t.buf = append(t.buf[:0], d[:n]...)
+	}
+	n := copy(p, t.buf)
+	t.buf = t.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,237 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// newTestProxyBackend adds the service <pkg>.EchoService, with the unary
+// method Echo, the server streaming method Repeat and the bidi streaming
+// method Chat, and enables it with a backend serving them. Each test uses its
+// own pkg, since the first service of a full name is looked up.
+func newTestProxyBackend(t *testing.T, pkg string) *Service {
+	spec := newTestSpec(pkg)
+	svc := &Service{Spec: spec, Name: "EchoService", FullName: pkg + ".EchoService"}
+	AddService(svc, nil, nil)
+	defaultRegistry.AddMethod(spec, "EchoService", &Method{Name: "Echo", Path: "/v1/echo", HttpMethod: "POST"})
+	defaultRegistry.AddMethod(spec, "EchoService", &Method{Name: "Repeat", Path: "/v1/repeat", HttpMethod: "POST", ServerStreaming: true})
+	defaultRegistry.AddMethod(spec, "EchoService", &Method{Name: "Chat", Path: "/v1/chat", HttpMethod: "POST", ClientStreaming: true, ServerStreaming: true})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer(grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		name, _ := grpc.MethodFromServerStream(stream)
+		in := new(wrapperspb.StringValue)
+		if err := stream.RecvMsg(in); err != nil {
+			return err
+		}
+		switch strings.TrimPrefix(name, "/"+svc.FullName) {
+		case "/Echo":
+			if in.Value == "fail" {
+				stream.SetTrailer(metadata.Pairs("x-reason", "failed"))
+				return status.Error(codes.NotFound, "no such echo")
+			}
+			stream.SetHeader(metadata.Pairs("x-echo", "1"))
+			return stream.SendMsg(in)
+		case "/Repeat":
+			for i := 0; i < 3; i++ {
+				if err := stream.SendMsg(in); err != nil {
+					return err
+				}
+			}
+			return nil
+		case "/Chat":
+			for {
+				if err := stream.SendMsg(in); err != nil {
+					return err
+				}
+				if err := stream.RecvMsg(in); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+			}
+		}
+		return status.Error(codes.Unimplemented, name)
+	}))
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	svc.SetConn(conn)
+	return svc
+}
+
+func grpcWebFrame(t *testing.T, flag byte, msg proto.Message) []byte {
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := make([]byte, 5, 5+len(b))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(b)))
+	return append(frame, b...)
+}
+
+// parseGRPCWebResponse returns the messages and the trailer of a gRPC-Web
+// response body.
+func parseGRPCWebResponse(t *testing.T, body []byte, text bool) ([]string, string) {
+	var r io.Reader = bytes.NewReader(body)
+	if text {
+		r = &grpcWebTextReader{r: bufio.NewReader(r)}
+	}
+	var msgs []string
+	for {
+		var h [5]byte
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			t.Fatalf("response %q has no trailer frame: %v", body, err)
+		}
+		b := make([]byte, binary.BigEndian.Uint32(h[1:]))
+		if _, err := io.ReadFull(r, b); err != nil {
+			t.Fatal(err)
+		}
+		if h[0]&grpcWebTrailerFlag != 0 {
+			return msgs, string(b)
+		}
+		msg := new(wrapperspb.StringValue)
+		if err := proto.Unmarshal(b, msg); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg.Value)
+	}
+}
+
+func TestServeGRPCWeb(t *testing.T) {
+	defer func(h GatewayServiceHook) { hook = h }(hook)
+	hook = nil
+	newTestProxyBackend(t, "grpcweb.test")
+	mux := NewServeMux()
+
+	for _, spec := range []struct {
+		name, path, contentType string
+		in                      string
+		wantMsgs                []string
+		wantTrailer             []string
+		wantHeader              string
+	}{
+		{
+			name: "unary", path: "/grpcweb.test.EchoService/Echo", contentType: "application/grpc-web+proto",
+			in: "hello", wantMsgs: []string{"hello"}, wantTrailer: []string{"grpc-status: 0\r\n"}, wantHeader: "1",
+		},
+		{
+			name: "server streaming text", path: "/grpcweb.test.EchoService/Repeat", contentType: "application/grpc-web-text",
+			in: "hi", wantMsgs: []string{"hi", "hi", "hi"}, wantTrailer: []string{"grpc-status: 0\r\n"},
+		},
+		{
+			name: "error", path: "/grpcweb.test.EchoService/Echo", contentType: "application/grpc-web-text+proto",
+			in: "fail", wantTrailer: []string{"grpc-status: 5\r\n", "grpc-message: no such echo\r\n", "x-reason: failed\r\n"},
+		},
+		{
+			name: "unknown method", path: "/grpcweb.test.EchoService/Nope", contentType: "application/grpc-web",
+			in: "hello", wantTrailer: []string{"grpc-status: 12\r\n"},
+		},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			text := strings.HasPrefix(spec.contentType, grpcWebTextContentType)
+			body := grpcWebFrame(t, 0, wrapperspb.String(spec.in))
+			if text {
+				body = []byte(base64.StdEncoding.EncodeToString(body))
+			}
+			req := httptest.NewRequest("POST", spec.path, bytes.NewReader(body))
+			req.Header.Set("Content-Type", spec.contentType)
+			if !IsGRPCWebRequest(req) {
+				t.Fatalf("IsGRPCWebRequest() = false; want true")
+			}
+			w := httptest.NewRecorder()
+			mux.ServeGRPCWeb(w, req)
+
+			if w.Code != http.StatusOK || w.Header().Get("Content-Type") != spec.contentType {
+				t.Errorf("status = %d, Content-Type = %q; want 200, %q", w.Code, w.Header().Get("Content-Type"), spec.contentType)
+			}
+			if got := w.Header().Get("x-echo"); got != spec.wantHeader {
+				t.Errorf("x-echo header = %q; want %q", got, spec.wantHeader)
+			}
+			msgs, trailer := parseGRPCWebResponse(t, w.Body.Bytes(), text)
+			if strings.Join(msgs, ",") != strings.Join(spec.wantMsgs, ",") {
+				t.Errorf("messages = %q; want %q", msgs, spec.wantMsgs)
+			}
+			for _, want := range spec.wantTrailer {
+				if !strings.Contains(trailer, want) {
+					t.Errorf("trailer = %q; want %q in it", trailer, want)
+				}
+			}
+		})
+	}
+}
+
+func TestServeGRPCWebRejected(t *testing.T) {
+	defer func(h GatewayServiceHook) { hook = h }(hook)
+	hook = nil
+	defer func(n int64) { MaxProxyMessageBytes = n }(MaxProxyMessageBytes)
+	MaxProxyMessageBytes = 16
+	newTestProxyBackend(t, "grpcweb.limit")
+	mux := NewServeMux()
+
+	// Each message is under the limit, but not all of them.
+	var body []byte
+	for i := 0; i < 3; i++ {
+		body = append(body, grpcWebFrame(t, 0, wrapperspb.String("hello"))...)
+	}
+	for _, spec := range []struct {
+		name, contentType string
+		wantTrailer       string
+	}{
+		{name: "messages larger than max in total", contentType: "application/grpc-web+proto", wantTrailer: "grpc-status: 8\r\n"},
+		{name: "json subtype", contentType: "application/grpc-web+json", wantTrailer: "grpc-status: 12\r\n"},
+	} {
+		req := httptest.NewRequest("POST", "/grpcweb.limit.EchoService/Chat", bytes.NewReader(body))
+		req.Header.Set("Content-Type", spec.contentType)
+		w := httptest.NewRecorder()
+		mux.ServeGRPCWeb(w, req)
+
+		msgs, trailer := parseGRPCWebResponse(t, w.Body.Bytes(), false)
+		if len(msgs) != 0 || !strings.Contains(trailer, spec.wantTrailer) {
+			t.Errorf("%s: response = %q, trailer %q; want no message, %q in the trailer", spec.name, msgs, trailer, spec.wantTrailer)
+		}
+	}
+}
+
+func TestGRPCWebWriterHTTPStatusError(t *testing.T) {
+	w := httptest.NewRecorder()
+	g := &grpcWebWriter{w: w, contentType: grpcWebContentType}
+	g.writeStatus(nil, &HTTPStatusError{HTTPStatus: http.StatusRequestEntityTooLarge, Err: status.Error(codes.InvalidArgument, "too large")})
+	_, trailer := parseGRPCWebResponse(t, w.Body.Bytes(), false)
+	if want := "grpc-status: 3\r\ngrpc-message: too large\r\n"; !strings.HasPrefix(trailer, want) {
+		t.Errorf("trailer = %q; want %q", trailer, want)
+	}
+}
+
+func TestGRPCWebTextReader(t *testing.T) {
+	// The body of a client which encodes each chunk separately.
+	body := base64.StdEncoding.EncodeToString([]byte("ab")) + base64.StdEncoding.EncodeToString([]byte("cdef"))
+	got, err := io.ReadAll(&grpcWebTextReader{r: bufio.NewReader(strings.NewReader(body))})
+	if err != nil || string(got) != "abcdef" {
+		t.Errorf("decoding %q = %q, %v; want abcdef", body, got, err)
+	}
+}
@@ -91,7 +91,7 @@ var (
 // are properly registered). That said, do not call it in function init().
 func SetGatewayServiceHook(h GatewayServiceHook) error {
 	hook = h
-	if err := hook.Bootstrap(availableServiceGroups); err != nil {
+	if err := hook.Bootstrap(GetServicGroups()); err != nil {
 		return err
 	}
 	return nil
@@ -112,9 +112,8 @@ func RequestAccepted(ctx context.Context, spec *pb.ServiceSpec, name string, met
 		return nil, nil
 	}
 
-	sg := GetServiceGroup(spec)
-	s := sg.Services[name]
-	return hook.RequestAccepted(ctx, s, getMethod(s, methodName), w, r)
+	s, m := defaultRegistry.Lookup(spec, name, methodName)
+	return hook.RequestAccepted(ctx, s, m, w, r)
 }
 
 // RequestParsed forwards the call to the RequestParsed method of
@@ -124,25 +123,40 @@ func RequestParsed(ctx context.Context, spec *pb.ServiceSpec, name string, metho
 		return nil
 	}
 
-	sg := GetServiceGroup(spec)
-	s := sg.Services[name]
-	return hook.RequestParsed(ctx, s, getMethod(s, methodName), reqProto, meta)
+	s, m := defaultRegistry.Lookup(spec, name, methodName)
+	return hook.RequestParsed(ctx, s, m, reqProto, meta)
 }
 
 // RequestHandled will forward call to the hook if been set otherwise noop.
 func RequestHandled(ctx context.Context, spec *pb.ServiceSpec, name string, methodName string, out proto.Message, meta *ServerMetadata, err error) {
 	if hook != nil {
-		sg := GetServiceGroup(spec)
-		s := sg.Services[name]
-		hook.RequestHandled(ctx, s, getMethod(s, methodName), out, meta, err)
+		s, m := defaultRegistry.Lookup(spec, name, methodName)
+		hook.RequestHandled(ctx, s, m, out, meta, err)
 	}
 }
 
-func getMethod(s *Service, methodName string) *Method {
-	for _, m := range s.Methods {
-		if m.Name == methodName {
-			return m
-		}
+// RequestAcceptedFor is RequestAccepted for the method returned by
+// AddMethod, which saves the registry lookup on every request.
+func RequestAcceptedFor(ctx context.Context, m *Method, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	if hook == nil {
+		return nil, nil
+	}
+
+	return hook.RequestAccepted(ctx, m.Service(), m, w, r)
+}
+
+// RequestParsedFor is RequestParsed for the method returned by AddMethod.
+func RequestParsedFor(ctx context.Context, m *Method, reqProto proto.Message, meta *ServerMetadata) error {
+	if hook == nil {
+		return nil
+	}
+
+	return hook.RequestParsed(ctx, m.Service(), m, reqProto, meta)
+}
+
+// RequestHandledFor is RequestHandled for the method returned by AddMethod.
+func RequestHandledFor(ctx context.Context, m *Method, out proto.Message, meta *ServerMetadata, err error) {
+	if hook != nil {
+		hook.RequestHandled(ctx, m.Service(), m, out, meta, err)
 	}
-	return nil
 }
@@ -0,0 +1,76 @@
+package runtime
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	lgr "github.com/binchencoder/letsgo/grpc"
+	skypb "github.com/binchencoder/skylb-api/proto"
+)
+
+// ClientInterceptorFunc returns the gRPC client interceptor for calls from the
+// gateway to the backend services of the service group with the given spec.
+type ClientInterceptorFunc func(spec *skypb.ServiceSpec) grpc.UnaryClientInterceptor
+
+var (
+	clientInterceptors []ClientInterceptorFunc
+)
+
+type (
+	methodKey struct{}
+
+	serviceMethod struct {
+		svc *Service
+		m   *Method
+	}
+)
+
+// AddClientInterceptor adds a client interceptor to all service groups. The
+// interceptors are executed in the order they are added.
+//
+// It should be called before the service groups are enabled, typically in
+// GatewayServiceHook.Bootstrap().
+func AddClientInterceptor(f ClientInterceptorFunc) {
+	clientInterceptors = append(clientInterceptors, f)
+}
+
+// ClientInterceptor returns the chain of all added client interceptors for
+// the service group with the given spec. The generated code installs it on
+// the connection of each service group when the group is enabled.
+func ClientInterceptor(spec *skypb.ServiceSpec) grpc.UnaryClientInterceptor {
+	incepts := make([]grpc.UnaryClientInterceptor, 0, len(clientInterceptors))
+	for _, f := range clientInterceptors {
+		if i := f(spec); i != nil {
+			incepts = append(incepts, i)
+		}
+	}
+	return lgr.ChainUnaryClient(incepts...)
+}
+
+// WithMethod annotates the context of a backend call with the API method, so
+// that the client interceptors can get it with MethodFromContext.
+func WithMethod(ctx context.Context, spec *skypb.ServiceSpec, name, methodName string) context.Context {
+	s, m := defaultRegistry.Lookup(spec, name, methodName)
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, methodKey{}, serviceMethod{svc: s, m: m})
+}
+
+// WithServiceMethod is WithMethod for the method returned by AddMethod.
+func WithServiceMethod(ctx context.Context, m *Method) context.Context {
+	if m.Service() == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, methodKey{}, serviceMethod{svc: m.Service(), m: m})
+}
+
+// MethodFromContext returns the service and the API method of the backend
+// call, if the context was annotated by WithMethod.
+func MethodFromContext(ctx context.Context) (*Service, *Method, bool) {
+	sm, ok := ctx.Value(methodKey{}).(serviceMethod)
+	if !ok || sm.m == nil {
+		return nil, nil, false
+	}
+	return sm.svc, sm.m, true
+}
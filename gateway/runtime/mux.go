@@ -67,6 +67,16 @@ type ServeMux struct {
 	routingErrorHandler       RoutingErrorHandlerFunc
 	disablePathLengthFallback bool
 	unescapingMode            UnescapingMode
+	webSocketOriginChecker    func(r *http.Request) bool
+
+	// routes maps HTTP method to the routing trie of its handlers.
+	routes map[string]*routeTrie
+	// seq numbers the handlers in the order they are added.
+	seq int
+	// hasVerbs reports whether any handler pattern has a verb.
+	hasVerbs bool
+	// conflicts are the route conflicts found by Handle.
+	conflicts []RouteConflict
 }
 
 // ServeMuxOption is an option that can be given to a ServeMux on construction.
@@ -260,6 +270,7 @@ func WithHealthzEndpoint(healthCheckClient grpc_health_v1.HealthClient, sid data
 func NewServeMux(opts ...ServeMuxOption) *ServeMux {
 	serveMux := &ServeMux{
 		handlers:               make(map[string][]handler),
+		routes:                 make(map[string]*routeTrie),
 		forwardResponseOptions: make([]func(context.Context, http.ResponseWriter, proto.Message) error, 0),
 		marshalers:             makeMarshalerMIMERegistry(),
 		errorHandler:           DefaultHTTPErrorHandler,
@@ -287,23 +298,61 @@ func NewServeMux(opts ...ServeMuxOption) *ServeMux {
 
 // Handle associates "h" to the pair of HTTP method and path pattern.
 func (s *ServeMux) Handle(meth string, pat Pattern, sid data.ServiceId, h HandlerFunc) {
-	s.handlers[meth] = append([]handler{handler{pat: pat, h: h, serviceId: sid}}, s.handlers[meth]...)
+	s.HandleMethod(meth, pat, sid, nil, h)
+}
+
+// HandleMethod is Handle for the handler of the API method "m", which is
+// reported as the owner of the route in the route conflicts.
+func (s *ServeMux) HandleMethod(meth string, pat Pattern, sid data.ServiceId, m *Method, h HandlerFunc) {
+	s.seq++
+	hd := handler{pat: pat, h: h, serviceId: sid, meth: m, seq: s.seq, segs: pat.segments()}
+	t := s.routes[meth]
+	if t == nil {
+		t = &routeTrie{}
+		s.routes[meth] = t
+	}
+	for _, e := range t.related(pat.literalPrefix()) {
+		if c, ok := hd.conflict(meth, e); ok {
+			s.conflicts = append(s.conflicts, c)
+		}
+	}
+	s.handlers[meth] = append([]handler{hd}, s.handlers[meth]...)
+	t.add(hd)
+	if pat.Verb() != "" {
+		s.hasVerbs = true
+	}
+}
+
+// RouteConflicts returns the routes registered so far which are identical to
+// or shadow a route of another service, in the order they are registered.
+func (s *ServeMux) RouteConflicts() []RouteConflict {
+	return s.conflicts
 }
 
 // HandlePath allows users to configure custom path handlers.
 // refer: https://grpc-ecosystem.github.io/grpc-gateway/docs/operations/inject_router/
 func (s *ServeMux) HandlePath(meth string, pathPattern string, sid data.ServiceId, h HandlerFunc) error {
+	pattern, err := ParsePattern(pathPattern)
+	if err != nil {
+		return err
+	}
+	s.Handle(meth, pattern, sid, h)
+	return nil
+}
+
+// ParsePattern compiles the path template of an HttpRule, e.g.
+// "/v1/{name=books/*}:publish", into a Pattern.
+func ParsePattern(pathPattern string) (Pattern, error) {
 	compiler, err := httprule.Parse(pathPattern)
 	if err != nil {
-		return fmt.Errorf("parsing path pattern: %w", err)
+		return Pattern{}, fmt.Errorf("parsing path pattern: %w", err)
 	}
 	tp := compiler.Compile()
 	pattern, err := NewPattern(tp.Version, tp.OpCodes, tp.Pool, tp.Verb)
 	if err != nil {
-		return fmt.Errorf("creating new pattern: %w", err)
+		return Pattern{}, fmt.Errorf("creating new pattern: %w", err)
 	}
-	s.Handle(meth, pattern, sid, h)
-	return nil
+	return pattern, nil
 }
 
 // ServeHTTP dispatches the request to the first handler whose pattern matches to r.Method and r.URL.Path.
@@ -314,29 +363,6 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path := r.URL.Path
-	if !strings.HasPrefix(path, "/") {
-		_, outboundMarshaler := MarshalerForRequest(s, r)
-		s.routingErrorHandler(ctx, s, outboundMarshaler, w, r, http.StatusBadRequest)
-		return
-	}
-
-	// TODO(v3): remove UnescapingModeLegacy
-	if s.unescapingMode != UnescapingModeLegacy && r.URL.RawPath != "" {
-		path = r.URL.RawPath
-	}
-
-	var components []string
-	// since in UnescapeModeLegacy, the URL will already have been fully unescaped, if we also split on "%2F"
-	// in this escaping mode we would be double unescaping but in UnescapingModeAllCharacters, we still do as the
-	// path is the RawPath (i.e. unescaped). That does mean that the behavior of this function will change its default
-	// behavior when the UnescapingModeDefault gets changed from UnescapingModeLegacy to UnescapingModeAllExceptReserved
-	if s.unescapingMode == UnescapingModeAllCharacters {
-		components = encodedPathSplitter.Split(path[1:], -1)
-	} else {
-		components = strings.Split(path[1:], "/")
-	}
-
 	if override := r.Header.Get("X-HTTP-Method-Override"); override != "" && s.isPathLengthFallback(r) {
 		r.Method = strings.ToUpper(override)
 		if err := r.ParseForm(); err != nil {
@@ -347,10 +373,70 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	rt := s.route(r)
+	switch {
+	case rt.status == http.StatusOK:
+		// X-HTTP-Method-Override is optional. Always allow fallback to POST.
+		if rt.meth != r.Method && s.isPathLengthFallback(r) {
+			if err := r.ParseForm(); err != nil {
+				_, outboundMarshaler := MarshalerForRequest(s, r)
+				sterr := status.Error(codes.InvalidArgument, err.Error())
+				s.errorHandler(ctx, s, outboundMarshaler, w, r, sterr)
+				return
+			}
+		}
+		rt.h.h(ctx, w, r, rt.pathParams)
+	case rt.err != nil:
+		_, outboundMarshaler := MarshalerForRequest(s, r)
+		s.errorHandler(ctx, s, outboundMarshaler, w, r, &HTTPStatusError{
+			HTTPStatus: rt.status,
+			Err:        rt.err,
+		})
+	default:
+		_, outboundMarshaler := MarshalerForRequest(s, r)
+		s.routingErrorHandler(ctx, s, outboundMarshaler, w, r, rt.status)
+	}
+}
+
+// routeResult is the route ServeMux dispatches a request to.
+type routeResult struct {
+	// h is the handler of the route, or nil if no route matches the path.
+	h *handler
+	// meth is the HTTP method of the route, which differs from the one of
+	// the request if the request falls back to it.
+	meth       string
+	pathParams map[string]string
+	// status is http.StatusOK if h handles the request, or the status of the
+	// routing error otherwise.
+	status int
+	// err is the MalformedSequenceError of the path, if any.
+	err error
+	// tried is the number of routes of the method of the request which do
+	// not match the path.
+	tried int
+}
+
+// route finds the handler of the request, by its method and path: the first
+// handler of the method whose pattern matches the path, or else a handler of
+// another method the request falls back to, for a form POST or a WebSocket
+// handshake.
+func (s *ServeMux) route(r *http.Request) routeResult {
+	if !strings.HasPrefix(r.URL.Path, "/") {
+		return routeResult{status: http.StatusBadRequest}
+	}
+	components := s.pathComponents(r)
+
 	// Verb out here is to memoize for the fallback case below
 	var verb string
 
-	for _, h := range s.handlers[r.Method] {
+	// A verb in the last component is stripped while walking the handlers
+	// below, which changes the components for the following handlers; keep
+	// the linear walk for it.
+	useTrie := !s.hasVerbs || !strings.Contains(components[len(components)-1], ":")
+
+	tried := 0
+	for _, h := range s.methodHandlers(r.Method, components, useTrie) {
+		h := h
 		// If the pattern has a verb, explicitly look for a suffix in the last
 		// component that matches a colon plus the verb. This allows us to
 		// handle some cases that otherwise can't be correctly handled by the
@@ -367,9 +453,7 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			idx = len(lastComponent) - len(patVerb) - 1
 		}
 		if idx == 0 {
-			_, outboundMarshaler := MarshalerForRequest(s, r)
-			s.routingErrorHandler(ctx, s, outboundMarshaler, w, r, http.StatusNotFound)
-			return
+			return routeResult{h: &h, meth: r.Method, status: http.StatusNotFound, tried: tried}
 		}
 		if idx > 0 {
 			components[l-1], verb = lastComponent[:idx], lastComponent[idx+1:]
@@ -378,57 +462,44 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		pathParams, err := h.pat.MatchAndEscape(components, verb, s.unescapingMode)
 		if err != nil {
 			var mse MalformedSequenceError
-			if ok := errors.As(err, &mse); ok {
-				_, outboundMarshaler := MarshalerForRequest(s, r)
-				s.errorHandler(ctx, s, outboundMarshaler, w, r, &HTTPStatusError{
-					HTTPStatus: http.StatusBadRequest,
-					Err:        mse,
-				})
+			if errors.As(err, &mse) {
+				return routeResult{h: &h, meth: r.Method, status: http.StatusBadRequest, err: mse, tried: tried}
 			}
+			tried++
 			continue
 		}
-		h.h(ctx, w, r, pathParams)
-		return
+		return routeResult{h: &h, meth: r.Method, pathParams: pathParams, status: http.StatusOK, tried: tried}
 	}
 
 	// lookup other methods to handle fallback from GET to POST and
 	// to determine if it is NotImplemented or NotFound.
-	for m, handlers := range s.handlers {
+	for _, m := range s.httpMethods() {
 		if m == r.Method {
 			continue
 		}
+		handlers := s.handlers[m]
+		if useTrie {
+			handlers = s.routes[m].match(components)
+		}
 		for _, h := range handlers {
+			h := h
 			pathParams, err := h.pat.MatchAndEscape(components, verb, s.unescapingMode)
 			if err != nil {
 				var mse MalformedSequenceError
-				if ok := errors.As(err, &mse); ok {
-					_, outboundMarshaler := MarshalerForRequest(s, r)
-					s.errorHandler(ctx, s, outboundMarshaler, w, r, &HTTPStatusError{
-						HTTPStatus: http.StatusBadRequest,
-						Err:        mse,
-					})
+				if errors.As(err, &mse) {
+					return routeResult{h: &h, meth: m, status: http.StatusBadRequest, err: mse, tried: tried}
 				}
 				continue
 			}
-			// X-HTTP-Method-Override is optional. Always allow fallback to POST.
-			if s.isPathLengthFallback(r) {
-				if err := r.ParseForm(); err != nil {
-					_, outboundMarshaler := MarshalerForRequest(s, r)
-					sterr := status.Error(codes.InvalidArgument, err.Error())
-					s.errorHandler(ctx, s, outboundMarshaler, w, r, sterr)
-					return
-				}
-				h.h(ctx, w, r, pathParams)
-				return
+			// A WebSocket handshake is a GET, whatever the method the
+			// streaming method is bound to.
+			if s.isPathLengthFallback(r) || h.meth.IsStreaming() && IsWebSocketRequest(r) {
+				return routeResult{h: &h, meth: m, pathParams: pathParams, status: http.StatusOK, tried: tried}
 			}
-			_, outboundMarshaler := MarshalerForRequest(s, r)
-			s.routingErrorHandler(ctx, s, outboundMarshaler, w, r, http.StatusMethodNotAllowed)
-			return
+			return routeResult{h: &h, meth: m, status: http.StatusMethodNotAllowed, tried: tried}
 		}
 	}
-
-	_, outboundMarshaler := MarshalerForRequest(s, r)
-	s.routingErrorHandler(ctx, s, outboundMarshaler, w, r, http.StatusNotFound)
+	return routeResult{status: http.StatusNotFound, tried: tried}
 }
 
 // GetForwardResponseOptions returns the ForwardResponseOptions associated with this ServeMux.
@@ -436,6 +507,37 @@ func (s *ServeMux) GetForwardResponseOptions() []func(context.Context, http.Resp
 	return s.forwardResponseOptions
 }
 
+// methodHandlers returns the handlers of the HTTP method to match the path
+// components against, in the order they are to be tried.
+func (s *ServeMux) methodHandlers(meth string, components []string, useTrie bool) []handler {
+	if !useTrie {
+		return s.handlers[meth]
+	}
+	if t := s.routes[meth]; t != nil {
+		return t.match(components)
+	}
+	return nil
+}
+
+// pathComponents splits the path of the request, which starts with "/", into
+// the components to match the patterns against.
+func (s *ServeMux) pathComponents(r *http.Request) []string {
+	path := r.URL.Path
+	// TODO(v3): remove UnescapingModeLegacy
+	if s.unescapingMode != UnescapingModeLegacy && r.URL.RawPath != "" {
+		path = r.URL.RawPath
+	}
+
+	// since in UnescapeModeLegacy, the URL will already have been fully unescaped, if we also split on "%2F"
+	// in this escaping mode we would be double unescaping but in UnescapingModeAllCharacters, we still do as the
+	// path is the RawPath (i.e. unescaped). That does mean that the behavior of this function will change its default
+	// behavior when the UnescapingModeDefault gets changed from UnescapingModeLegacy to UnescapingModeAllExceptReserved
+	if s.unescapingMode == UnescapingModeAllCharacters {
+		return encodedPathSplitter.Split(path[1:], -1)
+	}
+	return strings.Split(path[1:], "/")
+}
+
 func (s *ServeMux) isPathLengthFallback(r *http.Request) bool {
 	return !s.disablePathLengthFallback && r.Method == "POST" && r.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
 }
@@ -444,4 +546,8 @@ type handler struct {
 	pat       Pattern
 	h         HandlerFunc
 	serviceId data.ServiceId
+	meth      *Method
+	seq       int
+	// segs are the segments of pat, which the route conflicts are found by.
+	segs []routeSeg
 }
@@ -738,3 +738,50 @@ func (g *dummyHealthCheckClient) Check(ctx context.Context, r *grpc_health_v1.He
 func (g *dummyHealthCheckClient) Watch(ctx context.Context, r *grpc_health_v1.HealthCheckRequest, opts ...grpc.CallOption) (grpc_health_v1.Health_WatchClient, error) {
 	return nil, status.Error(codes.Unimplemented, "unimplemented")
 }
+
+// BenchmarkServeMuxRouting routes requests through a mux with 5000 routes
+// spread over 50 services, the last registered of which is matched first.
+func BenchmarkServeMuxRouting(b *testing.B) {
+	mux := runtime.NewServeMux()
+	testFn := func(ctx context.Context, w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	}
+	for svc := 0; svc < 50; svc++ {
+		for res := 0; res < 100; res++ {
+			var path, meth string
+			switch res % 4 {
+			case 0:
+				meth, path = "GET", fmt.Sprintf("/v1/svc%d/res%d/{id}", svc, res)
+			case 1:
+				meth, path = "POST", fmt.Sprintf("/v1/svc%d/res%d", svc, res)
+			case 2:
+				meth, path = "GET", fmt.Sprintf("/v1/svc%d/res%d/{id}/items/{item}", svc, res)
+			case 3:
+				meth, path = "DELETE", fmt.Sprintf("/v1/svc%d/{name=res%d/*}", svc, res)
+			}
+			if err := mux.HandlePath(meth, path, 1 /* sid */, testFn); err != nil {
+				b.Fatalf("mux.HandlePath(%q, %q) failed with %v; want success", meth, path, err)
+			}
+		}
+	}
+
+	for _, req := range []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"first", "GET", "/v1/svc0/res0/abc"},
+		{"last", "GET", "/v1/svc49/res98/abc/items/def"},
+		{"deep", "DELETE", "/v1/svc25/res51/xyz"},
+		{"not-allowed", "PUT", "/v1/svc10/res1"},
+		{"not-found", "GET", "/v1/svc99/res0/abc"},
+	} {
+		b.Run(req.name, func(b *testing.B) {
+			r := httptest.NewRequest(req.method, req.path, nil)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				mux.ServeHTTP(httptest.NewRecorder(), r)
+			}
+		})
+	}
+}
@@ -237,6 +237,12 @@ func (p Pattern) Match(components []string, verb string) (map[string]string, err
 // Verb returns the verb part of the Pattern.
 func (p Pattern) Verb() string { return p.verb }
 
+// Vars returns the names of the variables bound by the pattern, which are
+// field paths of the request message.
+func (p Pattern) Vars() []string {
+	return append([]string(nil), p.vars...)
+}
+
 func (p Pattern) String() string {
 	var stack []string
 	for _, op := range p.ops {
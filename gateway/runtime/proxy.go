@@ -0,0 +1,244 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MaxProxyMessageBytes limits the size of a request message of the calls
+// proxied without the generated handlers, for the methods without
+// MaxBodyBytes. The request messages of a gRPC-Web call, which are read
+// before the call, are limited in total.
+var MaxProxyMessageBytes int64 = 4 << 20
+
+// rawCodec passes the messages of the proxied calls through as they are.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T of a proxied call", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T of a proxied call", v)
+	}
+	*b = append([]byte(nil), data...)
+	return nil
+}
+
+// Name is the content subtype of the proxied calls; the messages are
+// expected to be protocol buffers.
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// proxyPeer is the client side of a proxied call.
+type proxyPeer interface {
+	// RecvMsg returns the next request message, or io.EOF after the last
+	// one.
+	RecvMsg() ([]byte, error)
+	// SendHeader sends the header metadata of the backend.
+	SendHeader(metadata.MD) error
+	// SendMsg sends a response message.
+	SendMsg([]byte) error
+}
+
+// resolveProxyMethod returns the method of the gRPC method name, and the
+// connection of its service.
+func resolveProxyMethod(fullMethod string) (*Method, *grpc.ClientConn, error) {
+	svc, m := LookupFullMethod(fullMethod)
+	if m == nil {
+		return nil, nil, status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod)
+	}
+	conn := svc.Conn()
+	if conn == nil {
+		return nil, nil, status.Error(codes.Internal, "service disabled")
+	}
+	return m, conn, nil
+}
+
+// proxyCall forwards the call of the method between the peer and the
+// backend on conn, with the messages passed through as they are, so
+// RequestParsed gets a nil request. The unary calls go through the client
+// interceptors like the calls of the generated handlers. The metadata of the
+// backend is recorded in md, and opts are added to the call options.
+func proxyCall(ctx context.Context, conn *grpc.ClientConn, fullMethod string, m *Method, peer proxyPeer, md *ServerMetadata, opts ...grpc.CallOption) error {
+	if err := RequestParsedFor(ctx, m, nil, md); err != nil {
+		return err
+	}
+	ctx = PreLoadBalance(ctx, m.Service().Balancer, "", nil)
+	ctx = WithServiceMethod(ctx, m)
+
+	if !m.IsStreaming() {
+		req, err := peer.RecvMsg()
+		if err == io.EOF {
+			return status.Error(codes.InvalidArgument, "missing the request message")
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := peer.RecvMsg(); err != io.EOF {
+			if err == nil {
+				err = status.Error(codes.InvalidArgument, "more than one request message of a unary call")
+			}
+			return err
+		}
+		var resp []byte
+		opts = append(opts, grpc.ForceCodec(rawCodec{}), grpc.Header(&md.HeaderMD), grpc.Trailer(&md.TrailerMD))
+		if err := conn.Invoke(ctx, fullMethod, &req, &resp, opts...); err != nil {
+			return err
+		}
+		if err := peer.SendHeader(md.HeaderMD); err != nil {
+			return err
+		}
+		return peer.SendMsg(resp)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	desc := &grpc.StreamDesc{ClientStreams: m.ClientStreaming, ServerStreams: m.ServerStreaming}
+	stream, err := conn.NewStream(ctx, desc, fullMethod, append(opts, grpc.ForceCodec(rawCodec{}))...)
+	if err != nil {
+		return err
+	}
+
+	// The sender reports why it stops before it cancels the stream, so that
+	// the error is seen once the responses stop.
+	sendErr := make(chan error, 1)
+	go func() {
+		err := proxyRequests(peer, stream)
+		sendErr <- err
+		if err != nil {
+			cancel()
+		}
+	}()
+
+	header, err := stream.Header()
+	if err == nil {
+		md.HeaderMD = header
+		if err := peer.SendHeader(header); err != nil {
+			return err
+		}
+	}
+	for {
+		var resp []byte
+		err := stream.RecvMsg(&resp)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			md.TrailerMD = stream.Trailer()
+			select {
+			case serr := <-sendErr:
+				if serr != nil {
+					return serr
+				}
+			default:
+			}
+			return err
+		}
+		if err := peer.SendMsg(resp); err != nil {
+			return err
+		}
+	}
+	md.TrailerMD = stream.Trailer()
+	return nil
+}
+
+// proxyRequests sends the request messages of the peer on the stream.
+func proxyRequests(peer proxyPeer, stream grpc.ClientStream) error {
+	for {
+		msg, err := peer.RecvMsg()
+		if err == io.EOF {
+			return stream.CloseSend()
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(&msg); err != nil {
+			if err == io.EOF {
+				// The stream is ended by the backend, whose status is
+				// returned by RecvMsg.
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// checkProxyContentType returns an error unless the content type is the base
+// type or its proto subtype, since the messages are passed to the backend as
+// protocol buffers.
+func checkProxyContentType(contentType, base string) error {
+	ct := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	if ct == base || ct == base+"+proto" {
+		return nil
+	}
+	return status.Errorf(codes.Unimplemented, "unsupported content type %q", contentType)
+}
+
+// readGRPCMessage reads a length-prefixed message of the gRPC wire format,
+// or returns io.EOF after the last one. A compressed message is decompressed
+// with dc, and is rejected if dc is nil.
+func readGRPCMessage(r io.Reader, limit int64, dc encoding.Compressor) ([]byte, error) {
+	var h [5]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, status.Error(codes.InvalidArgument, "truncated message prefix")
+		}
+		return nil, err
+	}
+	compressed := h[0]&1 != 0
+	if compressed && dc == nil {
+		return nil, status.Error(codes.Unimplemented, "compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(h[1:])
+	if int64(n) > limit {
+		return nil, status.Errorf(codes.ResourceExhausted, "message larger than max (%d vs. %d)", n, limit)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, status.Error(codes.InvalidArgument, "truncated message")
+		}
+		return nil, err
+	}
+	if !compressed {
+		return b, nil
+	}
+	dr, err := dc.Decompress(bytes.NewReader(b))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decompress the message: %v", err)
+	}
+	b, err = io.ReadAll(io.LimitReader(dr, limit+1))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decompress the message: %v", err)
+	}
+	if int64(len(b)) > limit {
+		return nil, status.Errorf(codes.ResourceExhausted, "decompressed message larger than max %d", limit)
+	}
+	return b, nil
+}
+
+// messageLimit returns the size limit of a request message of the method.
+func messageLimit(m *Method, dflt int64) int64 {
+	if m != nil && m.MaxBodyBytes > 0 {
+		return m.MaxBodyBytes
+	}
+	return dflt
+}
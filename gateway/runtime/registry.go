@@ -0,0 +1,260 @@
+package runtime
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	options "github.com/binchencoder/janus-gateway/httpoptions"
+	skypb "github.com/binchencoder/skylb-api/proto"
+)
+
+// serviceKey identifies a service group in the registry.
+type serviceKey struct {
+	namespace   string
+	serviceName string
+	portName    string
+}
+
+func keyOf(spec *skypb.ServiceSpec) serviceKey {
+	return serviceKey{
+		namespace:   spec.GetNamespace(),
+		serviceName: spec.GetServiceName(),
+		portName:    spec.GetPortName(),
+	}
+}
+
+// methodID identifies a method of a service in the registry.
+type methodID struct {
+	serviceKey
+	svcName    string
+	methodName string
+}
+
+// Registry holds the service groups known by the gateway. It is safe for
+// concurrent use: services and methods can be added while requests look
+// them up.
+type Registry struct {
+	mu      sync.RWMutex
+	groups  map[serviceKey]*ServiceGroup
+	methods map[methodID]*Method
+	// services maps the full names to the services which have one.
+	services map[string]*Service
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		groups:   map[serviceKey]*ServiceGroup{},
+		methods:  map[methodID]*Method{},
+		services: map[string]*Service{},
+	}
+}
+
+// AddService adds a service handler to the registry. enabler and disabler,
+// if not nil, replace the Enable and Disable functions of the service group.
+func (r *Registry) AddService(s *Service, enabler, disabler func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := keyOf(s.Spec)
+	sg, ok := r.groups[k]
+	if !ok {
+		sg = &ServiceGroup{
+			Spec:     s.Spec,
+			Services: map[string]*Service{},
+		}
+		r.groups[k] = sg
+	}
+	if enabler != nil {
+		sg.Enable = enabler
+	}
+	if disabler != nil {
+		sg.Disable = disabler
+	}
+	sg.Services[s.Name] = s
+	if _, ok := r.services[s.FullName]; s.FullName != "" && !ok {
+		r.services[s.FullName] = s
+	}
+}
+
+// AddMethod adds the method to the service with the given spec and name.
+// It returns nil if the service is not in the registry. A method added with
+// several HTTP bindings is looked up by name as its first binding; the
+// generated handlers keep the returned pointer of each binding instead.
+func (r *Registry) AddMethod(spec *skypb.ServiceSpec, svcName string, m *Method) *Method {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := keyOf(spec)
+	sg := r.groups[k]
+	if sg == nil {
+		return nil
+	}
+	svc := sg.Services[svcName]
+	if svc == nil {
+		return nil
+	}
+	m.svc = svc
+	svc.Methods = append(svc.Methods, m)
+	mk := methodID{serviceKey: k, svcName: svcName, methodName: m.Name}
+	if _, ok := r.methods[mk]; !ok {
+		r.methods[mk] = m
+	}
+	return m
+}
+
+// ServiceGroup returns the service group with the given spec, or nil.
+func (r *Registry) ServiceGroup(spec *skypb.ServiceSpec) *ServiceGroup {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.groups[keyOf(spec)]
+}
+
+// ServiceGroups returns a snapshot of the service groups keyed by the
+// string form of their spec.
+func (r *Registry) ServiceGroups() map[string]*ServiceGroup {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sgs := make(map[string]*ServiceGroup, len(r.groups))
+	for _, sg := range r.groups {
+		sgs[sg.Spec.String()] = sg
+	}
+	return sgs
+}
+
+// Lookup returns the service and the method with the given names. Either
+// one is nil if it's not found.
+func (r *Registry) Lookup(spec *skypb.ServiceSpec, svcName, methodName string) (*Service, *Method) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	k := keyOf(spec)
+	if m := r.methods[methodID{serviceKey: k, svcName: svcName, methodName: methodName}]; m != nil {
+		return m.svc, m
+	}
+	if sg := r.groups[k]; sg != nil {
+		return sg.Services[svcName], nil
+	}
+	return nil, nil
+}
+
+// LookupFullMethod returns the service and the method of the gRPC method
+// name, such as /pkg.EchoService/Echo, by the full name of the service.
+// Either one is nil if it's not found.
+func (r *Registry) LookupFullMethod(fullMethod string) (*Service, *Method) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	i := strings.LastIndex(name, "/")
+	if i < 0 {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	svc := r.services[name[:i]]
+	if svc == nil {
+		return nil, nil
+	}
+	return svc, r.methods[methodID{serviceKey: keyOf(svc.Spec), svcName: svc.Name, methodName: name[i+1:]}]
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the registry the generated gateway handlers are
+// added to.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// MethodOptions holds the annotations of an API method added by AddMethod.
+// The enum options take the names of their values in httpoptions.
+type MethodOptions struct {
+	LoginRequired      bool
+	ClientSignRequired bool
+	IsThirdParty       bool
+	SpecSourceType     string
+	ApiSource          string
+	TokenType          string
+	Timeout            string
+	RateLimit          float64
+	RateLimitBurst     int32
+	MaxAttempts        int32
+	Hedge              bool
+	HedgeDelay         string
+	MaxBodyBytes       int64
+	ClientStreaming    bool
+	ServerStreaming    bool
+}
+
+// AddMethod adds an API method to the service object with the given spec,
+// and returns the method for the handler of the binding to pass to the hook.
+func AddMethod(spec *skypb.ServiceSpec, svcName, methodName, path, httpMethod string, opts *MethodOptions) *Method {
+	m := &Method{
+		Name:               methodName,
+		Path:               path,
+		HttpMethod:         httpMethod,
+		LoginRequired:      opts.LoginRequired,
+		ClientSignRequired: opts.ClientSignRequired,
+		IsThirdParty:       opts.IsThirdParty,
+		SpecifiedSource:    options.SpecSourceType(options.SpecSourceType_value[opts.SpecSourceType]),
+		ApiSource:          options.ApiSourceType(options.ApiSourceType_value[opts.ApiSource]),
+		TokenType:          options.AuthTokenType(options.AuthTokenType_value[opts.TokenType]),
+		Timeout:            opts.Timeout,
+		RateLimit:          opts.RateLimit,
+		RateLimitBurst:     opts.RateLimitBurst,
+		MaxAttempts:        opts.MaxAttempts,
+		Hedge:              opts.Hedge,
+		HedgeDelay:         opts.HedgeDelay,
+		MaxBodyBytes:       opts.MaxBodyBytes,
+		ClientStreaming:    opts.ClientStreaming,
+		ServerStreaming:    opts.ServerStreaming,
+	}
+	m.SetEnabled(true)
+	return defaultRegistry.AddMethod(spec, svcName, m)
+}
+
+// AddService adds a service handler to the pool as available list.
+// This will not automatically call Regsiter.
+func AddService(s *Service, enabler, disabler func()) {
+	defaultRegistry.AddService(s, enabler, disabler)
+}
+
+// GetServicGroups returns the current available service groups.
+func GetServicGroups() map[string]*ServiceGroup {
+	return defaultRegistry.ServiceGroups()
+}
+
+// LookupFullMethod returns the service and the method of the gRPC method
+// name in the default registry.
+func LookupFullMethod(fullMethod string) (*Service, *Method) {
+	return defaultRegistry.LookupFullMethod(fullMethod)
+}
+
+// GetServiceGroup returns the ServiceGroup with the given spec.
+func GetServiceGroup(spec *skypb.ServiceSpec) *ServiceGroup {
+	return defaultRegistry.ServiceGroup(spec)
+}
+
+// ServiceClient holds the gRPC client of a service, which is swapped
+// atomically when the service is enabled or disabled while the handlers
+// read it. The zero value holds no client.
+type ServiceClient struct {
+	v atomic.Value
+}
+
+// clientBox lets ServiceClient store a nil client in atomic.Value.
+type clientBox struct {
+	client interface{}
+}
+
+// Load returns the current client, or nil if the service is disabled.
+func (c *ServiceClient) Load() interface{} {
+	b, _ := c.v.Load().(clientBox)
+	return b.client
+}
+
+// Store replaces the client; nil marks the service disabled.
+func (c *ServiceClient) Store(client interface{}) {
+	c.v.Store(clientBox{client: client})
+}
@@ -0,0 +1,294 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	vexpb "github.com/binchencoder/gateway-proto/data"
+	skypb "github.com/binchencoder/skylb-api/proto"
+)
+
+func newTestSpec(name string) *skypb.ServiceSpec {
+	return &skypb.ServiceSpec{Namespace: "default", ServiceName: name, PortName: "grpc"}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	r := NewRegistry()
+	spec := newTestSpec("echo")
+	r.AddService(&Service{Spec: spec, Name: "EchoService"}, nil, nil)
+	echo := r.AddMethod(spec, "EchoService", &Method{Name: "Echo"})
+	if echo == nil {
+		t.Fatal("AddMethod() = nil, want the method")
+	}
+	if m := r.AddMethod(spec, "NoService", &Method{Name: "Echo"}); m != nil {
+		t.Errorf("AddMethod() on an unknown service = %v, want nil", m)
+	}
+
+	s, m := r.Lookup(spec, "EchoService", "Echo")
+	if s == nil || s.Name != "EchoService" || m != echo {
+		t.Errorf("Lookup() = %v, %v; want EchoService, %v", s, m, echo)
+	}
+	if s, m := r.Lookup(spec, "EchoService", "Nope"); s == nil || m != nil {
+		t.Errorf("Lookup() of an unknown method = %v, %v; want the service and nil", s, m)
+	}
+	if s, m := r.Lookup(newTestSpec("nope"), "EchoService", "Echo"); s != nil || m != nil {
+		t.Errorf("Lookup() of an unknown spec = %v, %v; want nil, nil", s, m)
+	}
+
+	sgs := r.ServiceGroups()
+	r.AddService(&Service{Spec: newTestSpec("other"), Name: "OtherService"}, nil, nil)
+	if len(sgs) != 1 || sgs[spec.String()] == nil {
+		t.Errorf("ServiceGroups() = %v, want a snapshot with the echo group only", sgs)
+	}
+	if got := len(r.ServiceGroups()); got != 2 {
+		t.Errorf("len(ServiceGroups()) = %d, want 2", got)
+	}
+}
+
+func TestServiceClient(t *testing.T) {
+	var c ServiceClient
+	if got := c.Load(); got != nil {
+		t.Errorf("zero ServiceClient Load() = %v, want nil", got)
+	}
+	conn := &grpc.ClientConn{}
+	c.Store(conn)
+	if got := c.Load(); got != conn {
+		t.Errorf("Load() = %v, want %v", got, conn)
+	}
+	c.Store(nil)
+	if got := c.Load(); got != nil {
+		t.Errorf("Load() after Store(nil) = %v, want nil", got)
+	}
+}
+
+// TestRegistryEnableDisableInFlight serves requests through the registry
+// while the service group is enabled and disabled; run it with -race.
+func TestRegistryEnableDisableInFlight(t *testing.T) {
+	r := NewRegistry()
+	spec := newTestSpec("echo")
+
+	var client ServiceClient
+	svc := &Service{
+		Spec:    spec,
+		Name:    "EchoService",
+		Enable:  func(*skypb.ServiceSpec, *grpc.ClientConn) { client.Store(&grpc.ClientConn{}) },
+		Disable: func() { client.Store(nil) },
+	}
+	var sg *ServiceGroup
+	enable := func() {
+		for _, s := range sg.Services {
+			s.Enable(spec, nil)
+		}
+	}
+	disable := func() {
+		for _, s := range sg.Services {
+			s.Disable()
+		}
+	}
+	r.AddService(svc, enable, disable)
+	r.AddMethod(spec, "EchoService", &Method{Name: "Echo", enabled: 1})
+	sg = r.ServiceGroup(spec)
+
+	mux := NewServeMux()
+	if err := mux.HandlePath("GET", "/v1/echo", vexpb.ServiceId_JANUS_GATEWAY, func(ctx context.Context, w http.ResponseWriter, req *http.Request, _ map[string]string) {
+		s, m := r.Lookup(spec, "EchoService", "Echo")
+		if s == nil || m == nil || !m.IsEnabled() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if c, _ := client.Load().(*grpc.ClientConn); c == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}); err != nil {
+		t.Fatalf("HandlePath() = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			sg.SetEnabled(i%2 == 0)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			m := &Method{Name: "Other"}
+			r.AddMethod(spec, "EchoService", m)
+			m.SetEnabled(i%2 == 0)
+			r.ServiceGroups()
+		}
+	}()
+
+	var reqs sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		reqs.Add(1)
+		go func() {
+			defer reqs.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest("GET", "/v1/echo", nil))
+				if w.Code != http.StatusOK && w.Code != http.StatusServiceUnavailable {
+					t.Errorf("status = %d, want 200 or 503", w.Code)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	reqs.Wait()
+
+	sg.SetEnabled(true)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/v1/echo", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status after enabling = %d, want 200", w.Code)
+	}
+}
+
+func TestServiceGroupSwitch(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var disables int32
+	sg := &ServiceGroup{
+		Enable: func() {
+			close(started)
+			<-release
+		},
+		Disable: func() { atomic.AddInt32(&disables, 1) },
+	}
+	// Switch does not wait for Enable.
+	if !sg.Switch(true) {
+		t.Error("Switch(true) = false, want true")
+	}
+	<-started
+	if sg.Switch(true) {
+		t.Error("Switch(true) again = true, want false")
+	}
+	if got := sg.State(); got != ServiceGroupEnabling {
+		t.Errorf("State() while enabling = %s, want enabling", got)
+	}
+	if sg.IsEnabled() {
+		t.Error("IsEnabled() while enabling = true, want false")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := sg.Wait(ctx); err == nil {
+		t.Error("Wait() while enabling = nil, want the context error")
+	}
+
+	// A switch during Enable runs after it.
+	if !sg.Switch(false) {
+		t.Error("Switch(false) = false, want true")
+	}
+	if got := sg.State(); got != ServiceGroupDisabling {
+		t.Errorf("State() while enabling before disabling = %s, want disabling", got)
+	}
+	close(release)
+	if err := sg.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() failed with %v", err)
+	}
+	if got := sg.State(); got != ServiceGroupDisabled || sg.IsEnabled() {
+		t.Errorf("State() after disabling = %s, want disabled", got)
+	}
+	if got := atomic.LoadInt32(&disables); got != 1 {
+		t.Errorf("disables = %d, want 1", got)
+	}
+}
+
+// newBenchRegistry registers a service with n methods into the default
+// registry and returns the spec and the last method.
+func newBenchRegistry(b *testing.B, name string, n int) (*skypb.ServiceSpec, *Method) {
+	spec := newTestSpec(name)
+	AddService(&Service{Spec: spec, Name: "BenchService"}, nil, nil)
+	var m *Method
+	for i := 0; i < n; i++ {
+		m = AddMethod(spec, "BenchService", fmt.Sprintf("Method%d", i), fmt.Sprintf("/v1/bench/%d", i), "GET", &MethodOptions{})
+	}
+	if m == nil || m.Service() == nil {
+		b.Fatal("AddMethod() returned no method")
+	}
+	return spec, m
+}
+
+func withFakeHook(b *testing.B) {
+	old := hook
+	hook = &GatewayServiceHookFake{}
+	b.Cleanup(func() { hook = old })
+}
+
+// BenchmarkHookDispatchByName dispatches the three hook calls of a request
+// by looking the method up by name, the way handlers generated before
+// AddMethod returned the method did.
+func BenchmarkHookDispatchByName(b *testing.B) {
+	withFakeHook(b)
+	spec, m := newBenchRegistry(b, "bench-by-name", 500)
+	r := httptest.NewRequest("GET", m.Path, nil)
+	ctx := context.Background()
+	var meta ServerMetadata
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		RequestAccepted(ctx, spec, "BenchService", m.Name, nil, r)
+		RequestParsed(ctx, spec, "BenchService", m.Name, nil, &meta)
+		RequestHandled(ctx, spec, "BenchService", m.Name, nil, &meta, nil)
+	}
+}
+
+// BenchmarkHookDispatchByMethod dispatches the three hook calls of a request
+// with the method captured at registration.
+func BenchmarkHookDispatchByMethod(b *testing.B) {
+	withFakeHook(b)
+	_, m := newBenchRegistry(b, "bench-by-method", 500)
+	r := httptest.NewRequest("GET", m.Path, nil)
+	ctx := context.Background()
+	var meta ServerMetadata
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		RequestAcceptedFor(ctx, m, nil, r)
+		RequestParsedFor(ctx, m, nil, &meta)
+		RequestHandledFor(ctx, m, nil, &meta, nil)
+	}
+}
+
+func BenchmarkRegistryLookup(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("methods=%d", n), func(b *testing.B) {
+			r := NewRegistry()
+			spec := newTestSpec("bench")
+			r.AddService(&Service{Spec: spec, Name: "BenchService"}, nil, nil)
+			for i := 0; i < n; i++ {
+				r.AddMethod(spec, "BenchService", &Method{Name: fmt.Sprintf("Method%d", i)})
+			}
+			name := fmt.Sprintf("Method%d", n-1)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, m := r.Lookup(spec, "BenchService", name); m == nil {
+					b.Fatal("Lookup() = nil")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// RouteInfo describes a route registered to ServeMux.
+type RouteInfo struct {
+	HttpMethod string `json:"http_method"`
+	// Path is the path template of the route without the verb.
+	Path      string `json:"path"`
+	Verb      string `json:"verb,omitempty"`
+	ServiceId string `json:"service_id"`
+	// Service and Method are the names of the gRPC service and method, empty
+	// for the routes not registered by HandleMethod.
+	Service            string `json:"service,omitempty"`
+	Method             string `json:"method,omitempty"`
+	LoginRequired      bool   `json:"login_required"`
+	ClientSignRequired bool   `json:"client_sign_required"`
+	IsThirdParty       bool   `json:"is_third_party"`
+	TokenType          string `json:"token_type,omitempty"`
+	Timeout            string `json:"timeout,omitempty"`
+	Balancer           string `json:"balancer,omitempty"`
+	// Enabled reports whether both the method and its service group are
+	// enabled.
+	Enabled bool `json:"enabled"`
+}
+
+// RouteMatch reports which route ServeMux dispatches a request to, and why.
+type RouteMatch struct {
+	// Route is the route the request matches, or nil if it matches none.
+	Route *RouteInfo `json:"route,omitempty"`
+	// PathParams are the path parameters bound by the route.
+	PathParams map[string]string `json:"path_params,omitempty"`
+	// Status is http.StatusOK if the route handles the request, or the status
+	// of the routing error otherwise.
+	Status int    `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// Routes returns the routes of the mux by HTTP method, each in the order
+// they are matched.
+func (s *ServeMux) Routes() []RouteInfo {
+	routes := []RouteInfo{}
+	for _, meth := range s.httpMethods() {
+		for _, h := range s.handlers[meth] {
+			routes = append(routes, h.info(meth))
+		}
+	}
+	return routes
+}
+
+// Match returns the route ServeHTTP dispatches the request to, without
+// calling the hook or the handler.
+func (s *ServeMux) Match(r *http.Request) *RouteMatch {
+	rc := *r
+	r = &rc
+	if override := r.Header.Get("X-HTTP-Method-Override"); override != "" && s.isPathLengthFallback(r) {
+		r.Method = strings.ToUpper(override)
+	}
+
+	rt := s.route(r)
+	m := &RouteMatch{PathParams: rt.pathParams, Status: rt.status}
+	if rt.h != nil && rt.status != http.StatusNotFound {
+		info := rt.h.info(rt.meth)
+		m.Route = &info
+	}
+	switch {
+	case rt.h == nil && rt.status == http.StatusBadRequest:
+		m.Reason = "The path does not start with /."
+	case rt.h == nil:
+		m.Reason = fmt.Sprintf("No route matches the path; %d routes of %s are tried.", rt.tried, r.Method)
+	case rt.err != nil:
+		m.Reason = fmt.Sprintf("The path matches %s %s but has a %v.", rt.meth, rt.h.pat, rt.err)
+	case rt.status == http.StatusNotFound:
+		m.Reason = fmt.Sprintf("The last path segment is only the verb %q of %s %s.", rt.h.pat.Verb(), rt.meth, rt.h.pat)
+	case rt.status == http.StatusMethodNotAllowed:
+		m.Reason = fmt.Sprintf("No %s route matches the path, but %s %s does.", r.Method, rt.meth, rt.h.pat)
+	case rt.meth != r.Method && s.isPathLengthFallback(r):
+		m.Reason = fmt.Sprintf("No %s route matches the path; the form POST falls back to %s %s.", r.Method, rt.meth, rt.h.pat)
+	case rt.meth != r.Method:
+		m.Reason = fmt.Sprintf("No %s route matches the path; the WebSocket handshake falls back to the streaming %s %s.", r.Method, rt.meth, rt.h.pat)
+	default:
+		m.Reason = fmt.Sprintf("The path matches %s %s after %d routes of the method which do not.", rt.meth, rt.h.pat, rt.tried)
+	}
+	return m
+}
+
+// httpMethods returns the sorted HTTP methods of the handlers.
+func (s *ServeMux) httpMethods() []string {
+	meths := make([]string, 0, len(s.handlers))
+	for m := range s.handlers {
+		meths = append(meths, m)
+	}
+	sort.Strings(meths)
+	return meths
+}
+
+// info describes the route of the handler for the HTTP method.
+func (h handler) info(meth string) RouteInfo {
+	ri := RouteInfo{
+		HttpMethod: meth,
+		Path:       h.pat.String(),
+		Verb:       h.pat.Verb(),
+		ServiceId:  h.serviceId.String(),
+		Enabled:    true,
+	}
+	if m := h.meth; m != nil {
+		if m.Path != "" {
+			ri.Path = m.Path
+		}
+		ri.Method = m.Name
+		ri.LoginRequired = m.LoginRequired
+		ri.ClientSignRequired = m.ClientSignRequired
+		ri.IsThirdParty = m.IsThirdParty
+		ri.TokenType = m.TokenType.String()
+		ri.Timeout = m.Timeout
+		ri.Enabled = m.IsEnabled()
+		if svc := m.Service(); svc != nil {
+			ri.Service = svc.Name
+			ri.Balancer = svc.Balancer
+			if sg := defaultRegistry.ServiceGroup(svc.Spec); sg != nil && !sg.IsEnabled() {
+				ri.Enabled = false
+			}
+		}
+	}
+	if ri.Verb != "" {
+		ri.Path = strings.TrimSuffix(ri.Path, ":"+ri.Verb)
+	}
+	return ri
+}
@@ -0,0 +1,165 @@
+package runtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/binchencoder/gateway-proto/data"
+	options "github.com/binchencoder/janus-gateway/httpoptions"
+)
+
+// newTestRoutesMux returns a mux whose handlers answer with the X-Route header
+// of their path template.
+func newTestRoutesMux(t *testing.T, opts ...ServeMuxOption) *ServeMux {
+	svc := &Service{Name: "EchoService", Balancer: "ROUND_ROBIN"}
+	mux := NewServeMux(opts...)
+	for _, r := range []struct {
+		meth, path string
+		m          *Method
+	}{
+		{"GET", "/v1/echo/{id}", &Method{Name: "Echo", Path: "/v1/echo/{id}", LoginRequired: true, TokenType: options.AuthTokenType_JANUS_AUTH_TOKEN, Timeout: "3s", enabled: 1, svc: svc}},
+		{"POST", "/v1/echo/{id}:run", &Method{Name: "Run", Path: "/v1/echo/{id}:run", svc: svc}},
+		{"GET", "/healthz", nil},
+	} {
+		pat, err := parsePattern(r.path)
+		if err != nil {
+			t.Fatalf("parsing %q failed with %v", r.path, err)
+		}
+		path := r.path
+		mux.HandleMethod(r.meth, pat, data.ServiceId(1), r.m, func(ctx context.Context, w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			w.Header().Set("X-Route", path)
+		})
+	}
+	return mux
+}
+
+func TestServeMuxRoutes(t *testing.T) {
+	routes := newTestRoutesMux(t).Routes()
+	if len(routes) != 3 {
+		t.Fatalf("mux.Routes() = %+v; want 3 routes", routes)
+	}
+
+	if got := routes[0]; got.HttpMethod != "GET" || got.Path != "/healthz" || got.Service != "" || !got.Enabled {
+		t.Errorf("routes[0] = %+v; want enabled GET /healthz", got)
+	}
+	want := RouteInfo{
+		HttpMethod:    "GET",
+		Path:          "/v1/echo/{id}",
+		ServiceId:     data.ServiceId(1).String(),
+		Service:       "EchoService",
+		Method:        "Echo",
+		LoginRequired: true,
+		TokenType:     options.AuthTokenType_JANUS_AUTH_TOKEN.String(),
+		Timeout:       "3s",
+		Balancer:      "ROUND_ROBIN",
+		Enabled:       true,
+	}
+	if got := routes[1]; got != want {
+		t.Errorf("routes[1] = %+v; want %+v", got, want)
+	}
+	if got := routes[2]; got.Path != "/v1/echo/{id}" || got.Verb != "run" || got.Enabled {
+		t.Errorf("routes[2] = %+v; want disabled POST /v1/echo/{id} with verb run", got)
+	}
+}
+
+func TestServeMuxMatch(t *testing.T) {
+	mux := newTestRoutesMux(t)
+	for _, spec := range []struct {
+		method, target string
+		header         map[string]string
+		wantStatus     int
+		wantMethod     string
+		wantParams     map[string]string
+	}{
+		{method: "GET", target: "/v1/echo/1", wantStatus: http.StatusOK, wantMethod: "Echo", wantParams: map[string]string{"id": "1"}},
+		{method: "POST", target: "/v1/echo/1:run", wantStatus: http.StatusOK, wantMethod: "Run", wantParams: map[string]string{"id": "1"}},
+		{method: "POST", target: "/v1/echo/1", wantStatus: http.StatusMethodNotAllowed, wantMethod: "Echo"},
+		{method: "POST", target: "/v1/echo/1", header: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, wantStatus: http.StatusOK, wantMethod: "Echo"},
+		{method: "POST", target: "/v1/echo/:run", wantStatus: http.StatusNotFound},
+		{method: "GET", target: "/v2/echo", wantStatus: http.StatusNotFound},
+	} {
+		r := httptest.NewRequest(spec.method, spec.target, nil)
+		for k, v := range spec.header {
+			r.Header.Set(k, v)
+		}
+		m := mux.Match(r)
+		if m.Status != spec.wantStatus || m.Reason == "" {
+			t.Errorf("%s %s: match = %+v; want status %d", spec.method, spec.target, m, spec.wantStatus)
+			continue
+		}
+		method := ""
+		if m.Route != nil {
+			method = m.Route.Method
+		}
+		if method != spec.wantMethod {
+			t.Errorf("%s %s: matched method %q; want %q", spec.method, spec.target, method, spec.wantMethod)
+		}
+		for k, v := range spec.wantParams {
+			if m.PathParams[k] != v {
+				t.Errorf("%s %s: path params = %v; want %v", spec.method, spec.target, m.PathParams, spec.wantParams)
+			}
+		}
+		if r.Method != spec.method || !strings.HasPrefix(r.URL.Path, "/") {
+			t.Errorf("%s %s: mux.Match() changed the request", spec.method, spec.target)
+		}
+	}
+}
+
+// TestServeMuxMatchServeHTTP checks that ServeHTTP dispatches the requests as
+// Match reports.
+func TestServeMuxMatchServeHTTP(t *testing.T) {
+	defer func(h GatewayServiceHook) { hook = h }(hook)
+	hook = nil
+	mux := newTestRoutesMux(t, WithUnescapingMode(UnescapingModeAllExceptReserved))
+	for _, spec := range []struct {
+		method, target, rawPath string
+		header                  map[string]string
+		wantStatus              int
+	}{
+		{method: "GET", target: "/v1/echo/1", wantStatus: http.StatusOK},
+		{method: "POST", target: "/v1/echo/1:run", wantStatus: http.StatusOK},
+		{method: "POST", target: "/v1/echo/1", wantStatus: http.StatusMethodNotAllowed},
+		{method: "POST", target: "/v1/echo/1", header: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, wantStatus: http.StatusOK},
+		{method: "POST", target: "/v1/echo/:run", wantStatus: http.StatusNotFound},
+		{method: "GET", target: "/v2/echo", wantStatus: http.StatusNotFound},
+		{method: "GET", target: "/v1/echo/x", rawPath: "/v1/echo/%zz", wantStatus: http.StatusBadRequest},
+		{method: "POST", target: "/v1/echo/x", rawPath: "/v1/echo/%zz", wantStatus: http.StatusBadRequest},
+	} {
+		newRequest := func() *http.Request {
+			r := httptest.NewRequest(spec.method, spec.target, nil)
+			r.URL.RawPath = spec.rawPath
+			for k, v := range spec.header {
+				r.Header.Set(k, v)
+			}
+			return r
+		}
+		m := mux.Match(newRequest())
+		if m.Status != spec.wantStatus {
+			t.Errorf("%s %s: match = %+v; want status %d", spec.method, spec.target, m, spec.wantStatus)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, newRequest())
+		want := m.Status
+		if want == http.StatusMethodNotAllowed {
+			// The routing error handler answers it as Unimplemented.
+			want = http.StatusNotImplemented
+		}
+		if w.Code != want {
+			t.Errorf("%s %s: ServeHTTP status = %d; want %d", spec.method, spec.target, w.Code, want)
+		}
+		route := ""
+		if m.Status == http.StatusOK {
+			route = m.Route.Path
+			if m.Route.Verb != "" {
+				route += ":" + m.Route.Verb
+			}
+		}
+		if got := w.Header().Get("X-Route"); got != route {
+			t.Errorf("%s %s: ServeHTTP dispatched to %q; want %q of the match", spec.method, spec.target, got, route)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package runtime
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
+
 	"google.golang.org/grpc"
 
 	options "github.com/binchencoder/janus-gateway/httpoptions"
@@ -19,7 +23,6 @@ type Method struct {
 	Name               string
 	Path               string
 	HttpMethod         string
-	Enabled            bool
 	LoginRequired      bool
 	ClientSignRequired bool
 	IsThirdParty       bool
@@ -27,76 +30,201 @@ type Method struct {
 	ApiSource          options.ApiSourceType
 	TokenType          options.AuthTokenType
 	Timeout            string
+	RateLimit          float64
+	RateLimitBurst     int32
+	MaxAttempts        int32
+	Hedge              bool
+	HedgeDelay         string
+	MaxBodyBytes       int64
+	ClientStreaming    bool
+	ServerStreaming    bool
+
+	// enabled is 1 if the method is enabled, accessed atomically.
+	enabled int32
+	// svc is the service the method is added to by the registry.
+	svc *Service
+}
+
+// Service returns the service the method belongs to, or nil if the method
+// is not added to a registry.
+func (m *Method) Service() *Service {
+	if m == nil {
+		return nil
+	}
+	return m.svc
+}
+
+// IsStreaming reports whether the method streams the requests or the
+// responses.
+func (m *Method) IsStreaming() bool {
+	return m != nil && (m.ClientStreaming || m.ServerStreaming)
 }
 
 // Service is the controller class for each grpc service handler.
 type Service struct {
-	Spec     skypb.ServiceSpec
-	Name     string
+	Spec *skypb.ServiceSpec
+	Name string
+	// FullName is the name of the service qualified by its proto package,
+	// with which its methods are called over gRPC and gRPC-Web.
+	FullName string
+	Balancer string
 	Methods  []*Method
 	Register func(*ServeMux) error
 	Enable   func(spec *skypb.ServiceSpec, conn *grpc.ClientConn)
 	Disable  func()
+
+	// conn holds the connection the service is enabled with.
+	conn ServiceClient
+}
+
+// SetConn records the connection the service is enabled with, on which the
+// calls proxied without the generated handlers are sent; nil marks the
+// service disabled.
+func (s *Service) SetConn(conn *grpc.ClientConn) {
+	s.conn.Store(conn)
+}
+
+// Conn returns the connection the service is enabled with, or nil.
+func (s *Service) Conn() *grpc.ClientConn {
+	conn, _ := s.conn.Load().(*grpc.ClientConn)
+	return conn
 }
 
 // ServiceGroup groups services with the same spec.
 type ServiceGroup struct {
-	Spec     skypb.ServiceSpec
+	Spec     *skypb.ServiceSpec
 	Enable   func()
 	Disable  func()
 	Services map[string]*Service
+
+	mu sync.Mutex
+	// enabled is the state Enable or Disable last completed, and want is the
+	// state last requested.
+	enabled, want bool
+	// settled is closed when the group reaches the requested state. It is
+	// nil while no Enable or Disable runs.
+	settled chan struct{}
 }
 
-var (
-	availableServiceGroups = make(map[string]*ServiceGroup)
+// ServiceGroupState is the state of a service group.
+type ServiceGroupState int
+
+const (
+	ServiceGroupDisabled ServiceGroupState = iota
+	ServiceGroupEnabling
+	ServiceGroupEnabled
+	ServiceGroupDisabling
 )
 
-// AddMethod adds an API method to the service object with the given spec.
-func AddMethod(spec *skypb.ServiceSpec, svcName, methodName, path, httpMethod string, loginRequired, clientSignRequired, isThirdParty bool, specSource, apiSource, tokenType, timeout string) {
-	sg := availableServiceGroups[spec.String()]
-	svc := sg.Services[svcName]
-	m := Method{
-		Name:               methodName,
-		Path:               path,
-		HttpMethod:         httpMethod,
-		LoginRequired:      loginRequired,
-		ClientSignRequired: clientSignRequired,
-		IsThirdParty:       isThirdParty,
-		SpecifiedSource:    options.SpecSourceType(options.SpecSourceType_value[specSource]),
-		ApiSource:          options.ApiSourceType(options.ApiSourceType_value[apiSource]),
-		TokenType:          options.AuthTokenType(options.AuthTokenType_value[tokenType]),
-		Timeout:            timeout,
+func (s ServiceGroupState) String() string {
+	switch s {
+	case ServiceGroupEnabling:
+		return "enabling"
+	case ServiceGroupEnabled:
+		return "enabled"
+	case ServiceGroupDisabling:
+		return "disabling"
 	}
-	svc.Methods = append(svc.Methods, &m)
-}
-
-// AddService adds a service handler to the pool as available list.
-// This will not automatically call Regsiter.
-func AddService(s *Service, enabler, disabler func()) {
-	spec := s.Spec
-	sg, ok := availableServiceGroups[spec.String()]
-	if !ok {
-		sg = &ServiceGroup{
-			Spec:     spec,
-			Services: map[string]*Service{},
+	return "disabled"
+}
+
+// Switch requests the service group to be enabled or disabled, and returns
+// false if it is already requested. Enable and Disable run in the background,
+// one at a time, until the group reaches the state last requested, so that
+// an Enable which waits for the backends blocks neither the caller nor the
+// next switch.
+func (sg *ServiceGroup) Switch(enabled bool) bool {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	if sg.want == enabled {
+		return false
+	}
+	sg.want = enabled
+	if sg.settled == nil {
+		sg.settled = make(chan struct{})
+		go sg.apply(sg.settled)
+	}
+	return true
+}
+
+// apply runs Enable or Disable until the group is in the requested state,
+// and closes settled.
+func (sg *ServiceGroup) apply(settled chan struct{}) {
+	sg.mu.Lock()
+	for sg.enabled != sg.want {
+		enabled := sg.want
+		sg.mu.Unlock()
+		if enabled {
+			sg.Enable()
+		} else {
+			sg.Disable()
 		}
-		availableServiceGroups[spec.String()] = sg
+		sg.mu.Lock()
+		sg.enabled = enabled
 	}
-	if enabler != nil {
-		sg.Enable = enabler
+	sg.settled = nil
+	sg.mu.Unlock()
+	close(settled)
+}
+
+// Wait waits until the service group reaches the state last requested, or
+// the context is done.
+func (sg *ServiceGroup) Wait(ctx context.Context) error {
+	sg.mu.Lock()
+	settled := sg.settled
+	sg.mu.Unlock()
+	if settled == nil {
+		return nil
 	}
-	if disabler != nil {
-		sg.Disable = disabler
+	select {
+	case <-settled:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	sg.Services[s.Name] = s
 }
 
-// GetServicGroups returns the current available service groups.
-func GetServicGroups() map[string]*ServiceGroup {
-	return availableServiceGroups
+// SetEnabled enables or disables the service group like Switch, and waits
+// until it settles. It returns false if the state is already requested.
+func (sg *ServiceGroup) SetEnabled(enabled bool) bool {
+	changed := sg.Switch(enabled)
+	sg.Wait(context.Background())
+	return changed
 }
 
-// GetServiceGroup returns the ServiceGroup with the given spec.
-func GetServiceGroup(spec *skypb.ServiceSpec) *ServiceGroup {
-	return availableServiceGroups[spec.String()]
+// State returns the state of the service group.
+func (sg *ServiceGroup) State() ServiceGroupState {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	switch {
+	case sg.settled == nil && sg.enabled:
+		return ServiceGroupEnabled
+	case sg.settled == nil:
+		return ServiceGroupDisabled
+	case sg.want:
+		return ServiceGroupEnabling
+	}
+	return ServiceGroupDisabling
+}
+
+// IsEnabled returns whether the service group is enabled: its Enable has
+// returned, and it is not disabled since.
+func (sg *ServiceGroup) IsEnabled() bool {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	return sg.enabled
+}
+
+// IsEnabled returns whether the method is enabled.
+func (m *Method) IsEnabled() bool {
+	return atomic.LoadInt32(&m.enabled) == 1
+}
+
+// SetEnabled enables or disables the method.
+func (m *Method) SetEnabled(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&m.enabled, v)
 }
@@ -0,0 +1,123 @@
+package runtime
+
+import (
+	"sort"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+)
+
+// routeTrie indexes the handlers of an HTTP method by the literal segments
+// their patterns start with, so that ServeHTTP only matches the patterns
+// which can match the request path. The wildcard part of the patterns is
+// still matched by Pattern.MatchAndEscape.
+type routeTrie struct {
+	root routeNode
+}
+
+type routeNode struct {
+	children map[string]*routeNode
+	// handlers are the handlers whose literal prefix ends at this node,
+	// newest first like ServeMux.handlers.
+	handlers []handler
+}
+
+// add indexes the handler by the literal prefix of its pattern.
+func (t *routeTrie) add(h handler) {
+	n := &t.root
+	for _, lit := range h.pat.literalPrefix() {
+		c := n.children[lit]
+		if c == nil {
+			if n.children == nil {
+				n.children = make(map[string]*routeNode)
+			}
+			c = &routeNode{}
+			n.children[lit] = c
+		}
+		n = c
+	}
+	n.handlers = append([]handler{h}, n.handlers...)
+}
+
+// match returns the handlers whose literal prefix matches the path
+// components, in the order of ServeMux.handlers.
+func (t *routeTrie) match(components []string) []handler {
+	var buf [8][]handler
+	lists := buf[:0]
+	total := 0
+	n := &t.root
+	for i := 0; ; i++ {
+		if len(n.handlers) > 0 {
+			lists = append(lists, n.handlers)
+			total += len(n.handlers)
+		}
+		if i == len(components) {
+			break
+		}
+		if n = n.children[components[i]]; n == nil {
+			break
+		}
+	}
+
+	switch len(lists) {
+	case 0:
+		return nil
+	case 1:
+		return lists[0]
+	}
+	// Every list is sorted newest first; merge them by the handler sequence.
+	hs := make([]handler, 0, total)
+	for len(hs) < total {
+		next := -1
+		for i, l := range lists {
+			if len(l) > 0 && (next < 0 || l[0].seq > lists[next][0].seq) {
+				next = i
+			}
+		}
+		hs = append(hs, lists[next][0])
+		lists[next] = lists[next][1:]
+	}
+	return hs
+}
+
+// related returns the handlers whose patterns may match the same paths as a
+// pattern with the literal prefix, newest first: the handlers whose literal
+// prefix is a prefix of it, or starts with it. The literal prefixes of the
+// other handlers differ from it in a segment.
+func (t *routeTrie) related(prefix []string) []handler {
+	var hs []handler
+	n := &t.root
+	for i := 0; n != nil; i++ {
+		if i == len(prefix) {
+			hs = n.appendAll(hs)
+			break
+		}
+		hs = append(hs, n.handlers...)
+		n = n.children[prefix[i]]
+	}
+	sort.Slice(hs, func(i, j int) bool { return hs[i].seq > hs[j].seq })
+	return hs
+}
+
+// appendAll appends the handlers of the node and its descendants to hs.
+func (n *routeNode) appendAll(hs []handler) []handler {
+	hs = append(hs, n.handlers...)
+	for _, c := range n.children {
+		hs = c.appendAll(hs)
+	}
+	return hs
+}
+
+// literalPrefix returns the literal segments the pattern starts with, which
+// a path must have for the pattern to match.
+func (p Pattern) literalPrefix() []string {
+	var lits []string
+	for _, op := range p.ops {
+		switch op.code {
+		case utilities.OpLitPush:
+			lits = append(lits, p.pool[op.operand])
+		case utilities.OpPush, utilities.OpPushM:
+			return lits
+		}
+	}
+	return lits
+}
@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+)
+
+func TestRouteTrieMatch(t *testing.T) {
+	var (
+		lit  = int(utilities.OpLitPush)
+		push = int(utilities.OpPush)
+		pm   = int(utilities.OpPushM)
+		cat  = int(utilities.OpConcatN)
+		capt = int(utilities.OpCapture)
+	)
+	var trie routeTrie
+	var linear []handler
+	seq := 0
+	add := func(ops []int, pool []string) {
+		pat, err := NewPattern(1, ops, pool, "")
+		if err != nil {
+			t.Fatalf("NewPattern(1, %v, %v, \"\") failed with %v; want success", ops, pool, err)
+		}
+		seq++
+		h := handler{pat: pat, seq: seq}
+		trie.add(h)
+		linear = append([]handler{h}, linear...)
+	}
+	add(nil, nil)
+	add([]int{lit, 0}, []string{"foo"})
+	add([]int{lit, 0, lit, 1}, []string{"foo", "bar"})
+	add([]int{lit, 0, push, 0}, []string{"foo"})
+	add([]int{push, 0, lit, 0}, []string{"bar"})
+	add([]int{lit, 0, pm, 0}, []string{"foo"})
+	add([]int{lit, 0, lit, 1, push, 0, cat, 2, capt, 2}, []string{"foo", "bar", "name"})
+	add([]int{lit, 0, lit, 1}, []string{"foo", "baz"})
+	add([]int{lit, 0}, []string{"foo"})
+
+	for _, path := range []string{
+		"",
+		"foo",
+		"foo/bar",
+		"foo/baz",
+		"foo/bar/qux",
+		"foo/qux/bar",
+		"qux/bar",
+		"bar",
+		"qux",
+	} {
+		components := strings.Split(path, "/")
+		var want []string
+		for _, h := range linear {
+			if _, err := h.pat.MatchAndEscape(components, "", UnescapingModeDefault); err == nil {
+				want = append(want, fmt.Sprintf("%d:%s", h.seq, h.pat))
+			}
+		}
+		var got []string
+		for _, h := range trie.match(components) {
+			if _, err := h.pat.MatchAndEscape(components, "", UnescapingModeDefault); err == nil {
+				got = append(got, fmt.Sprintf("%d:%s", h.seq, h.pat))
+			}
+		}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("trie.match(%q) matched %v; want %v", path, got, want)
+		}
+	}
+}
+
+func TestRouteTrieRelated(t *testing.T) {
+	var (
+		lit  = int(utilities.OpLitPush)
+		push = int(utilities.OpPush)
+		pm   = int(utilities.OpPushM)
+	)
+	var trie routeTrie
+	var linear []handler
+	seq := 0
+	add := func(ops []int, pool []string) {
+		pat, err := NewPattern(1, ops, pool, "")
+		if err != nil {
+			t.Fatalf("NewPattern(1, %v, %v, \"\") failed with %v; want success", ops, pool, err)
+		}
+		seq++
+		h := handler{pat: pat, seq: seq}
+		trie.add(h)
+		linear = append([]handler{h}, linear...)
+	}
+	add([]int{pm, 0}, nil)
+	add([]int{lit, 0}, []string{"foo"})
+	add([]int{lit, 0, lit, 1}, []string{"foo", "bar"})
+	add([]int{lit, 0, push, 0, lit, 1}, []string{"foo", "bar"})
+	add([]int{lit, 0, lit, 1, lit, 2}, []string{"foo", "baz", "qux"})
+	add([]int{lit, 0}, []string{"bar"})
+
+	for _, prefix := range [][]string{
+		nil,
+		{"foo"},
+		{"foo", "bar"},
+		{"foo", "baz"},
+		{"foo", "qux"},
+		{"bar", "foo"},
+		{"qux"},
+	} {
+		var want []string
+		for _, h := range linear {
+			p := h.pat.literalPrefix()
+			n := len(p)
+			if len(prefix) < n {
+				n = len(prefix)
+			}
+			if strings.Join(p[:n], "/") == strings.Join(prefix[:n], "/") {
+				want = append(want, fmt.Sprintf("%d:%s", h.seq, h.pat))
+			}
+		}
+		var got []string
+		for _, h := range trie.related(prefix) {
+			got = append(got, fmt.Sprintf("%d:%s", h.seq, h.pat))
+		}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("trie.related(%q) = %v; want %v", prefix, got, want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// MaxWebSocketMessageBytes limits the size of an inbound WebSocket
+	// message of the methods without MaxBodyBytes.
+	MaxWebSocketMessageBytes int64 = 4 << 20
+
+	// WebSocketCloseTimeout is how long the gateway waits for the client to
+	// answer its close frame before it closes the connection.
+	WebSocketCloseTimeout = time.Second
+)
+
+// The WebSocket close codes of RFC 6455. A gRPC status other than OK closes
+// the connection with WebSocketCloseStatusBase plus its code, e.g. 4005 for
+// NotFound, and its message as the reason.
+const (
+	WebSocketCloseNormal        = websocket.CloseNormalClosure
+	WebSocketCloseProtocolError = websocket.CloseProtocolError
+	WebSocketCloseTooBig        = websocket.CloseMessageTooBig
+	WebSocketCloseStatusBase    = 4000
+)
+
+// WebSocketStream is the gRPC stream of a streaming method which a WebSocket
+// connection is bridged to.
+type WebSocketStream struct {
+	Stream grpc.ClientStream
+	// NewRequest returns the message an inbound frame is decoded into. It is
+	// nil for the methods which do not stream the requests, whose request is
+	// bound from the URL; their inbound frames are ignored.
+	NewRequest func() proto.Message
+	// Recv receives the next response from the stream.
+	Recv func() (proto.Message, error)
+}
+
+// WithWebSocketOriginChecker decides whether the WebSocket handshakes from an
+// Origin other than the Host of the request are allowed. Browsers do not
+// apply CORS to WebSocket, so by default only the handshakes of the same
+// origin, or without Origin, are allowed.
+func WithWebSocketOriginChecker(fn func(r *http.Request) bool) ServeMuxOption {
+	return func(serveMux *ServeMux) {
+		serveMux.webSocketOriginChecker = fn
+	}
+}
+
+// IsWebSocketRequest reports whether the request is a WebSocket handshake.
+func IsWebSocketRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet && websocket.IsWebSocketUpgrade(r)
+}
+
+// checkWebSocketOrigin allows the handshakes without Origin or of the same
+// origin, and the others allowed by the origin checker of the mux.
+func (s *ServeMux) checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return s.webSocketOriginChecker != nil && s.webSocketOriginChecker(r)
+}
+
+// ForwardWebSocket upgrades the request of the streaming method "meth" to a
+// WebSocket connection, and bridges it to the gRPC stream returned by open:
+// each inbound text or binary message is decoded with the inbound marshaler
+// and sent on the stream, and each response is written as a message with the
+// outbound marshaler. An empty message closes the sending direction of the
+// stream; a close frame cancels it. The connection is closed with the code
+// mapped from the gRPC status of the stream when it ends.
+//
+// The handshake is checked before open is called, and fails as a plain HTTP
+// request. open is called with a context which is canceled when the
+// connection ends.
+func ForwardWebSocket(ctx context.Context, mux *ServeMux, inboundMarshaler, outboundMarshaler Marshaler, w http.ResponseWriter, req *http.Request, meth *Method, open func(context.Context) (*WebSocketStream, error)) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var md ServerMetadata
+	upgrader := websocket.Upgrader{
+		CheckOrigin: mux.checkWebSocketOrigin,
+		Error: func(w http.ResponseWriter, r *http.Request, code int, reason error) {
+			c := codes.InvalidArgument
+			switch code {
+			case http.StatusForbidden:
+				c = codes.PermissionDenied
+			case http.StatusInternalServerError:
+				c = codes.Internal
+			}
+			err := &HTTPStatusError{HTTPStatus: code, Err: status.Error(c, reason.Error())}
+			RequestHandledFor(ctx, meth, nil, &md, err)
+			HTTPError(ctx, mux, outboundMarshaler, w, r, err)
+		},
+	}
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		// The error response is written by the upgrader.
+		return
+	}
+	defer conn.Close()
+	limit := MaxWebSocketMessageBytes
+	if meth != nil && meth.MaxBodyBytes > 0 {
+		limit = meth.MaxBodyBytes
+	}
+	conn.SetReadLimit(limit)
+
+	stream, err := open(ctx)
+	if err != nil {
+		RequestHandledFor(ctx, meth, nil, &md, err)
+		code, reason := webSocketCloseStatus(err)
+		closeWebSocket(conn, code, reason, nil)
+		return
+	}
+
+	// The reader reports why it stops before it cancels the stream, so that
+	// the error is seen once the responses stop.
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- readWebSocketRequests(ctx, conn, inboundMarshaler, meth, stream)
+		cancel()
+	}()
+
+	err = writeWebSocketResponses(conn, outboundMarshaler, meth, stream)
+	select {
+	case rerr := <-readErr:
+		readErr = nil
+		// The close frame of the client is echoed by the connection, and
+		// the stream keeps its status.
+		var ce *websocket.CloseError
+		if !errors.As(rerr, &ce) {
+			err = rerr
+		}
+	default:
+	}
+	md.HeaderMD, _ = stream.Stream.Header()
+	md.TrailerMD = stream.Stream.Trailer()
+	RequestHandledFor(ctx, meth, nil, &md, err)
+
+	code, reason := webSocketCloseStatus(err)
+	closeWebSocket(conn, code, reason, readErr)
+}
+
+// closeWebSocket writes the close frame, unless one is sent already, and
+// waits for the close frame of the client, seen by the reader if readErr is
+// not nil.
+func closeWebSocket(conn *websocket.Conn, code int, reason string, readErr chan error) {
+	// The reason of a control frame is limited to 123 bytes.
+	if len(reason) > 123 {
+		reason = strings.ToValidUTF8(reason[:123], "")
+	}
+	deadline := time.Now().Add(WebSocketCloseTimeout)
+	if err := conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline); err != nil {
+		return
+	}
+	conn.SetReadDeadline(deadline)
+	if readErr != nil {
+		<-readErr
+		return
+	}
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+// webSocketCloseStatus returns the close code and reason for the error the
+// stream ends with.
+func webSocketCloseStatus(err error) (int, string) {
+	if err == nil {
+		return WebSocketCloseNormal, ""
+	}
+	st := status.Convert(err)
+	if st.Code() == codes.OK {
+		return WebSocketCloseNormal, ""
+	}
+	return WebSocketCloseStatusBase + int(st.Code()), st.Message()
+}
+
+// readWebSocketRequests sends the inbound messages on the stream until the
+// client closes the connection, which returns a *websocket.CloseError, or an
+// error.
+func readWebSocketRequests(ctx context.Context, conn *websocket.Conn, marshaler Marshaler, meth *Method, stream *WebSocketStream) error {
+	sending := stream.NewRequest != nil
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if !sending {
+			continue
+		}
+		if len(data) == 0 {
+			sending = false
+			if err := stream.Stream.CloseSend(); err != nil {
+				grpclog.Infof("Failed to terminate client stream: %v", err)
+				return err
+			}
+			continue
+		}
+
+		protoReq := stream.NewRequest()
+		if err := marshaler.Unmarshal(data, protoReq); err != nil {
+			return status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		var metadata ServerMetadata
+		if err := RequestParsedFor(ctx, meth, protoReq, &metadata); err != nil {
+			return err
+		}
+		if err := stream.Stream.SendMsg(protoReq); err != nil {
+			if err == io.EOF {
+				// The stream is ended by the server, whose status is
+				// returned by Recv.
+				sending = false
+				continue
+			}
+			grpclog.Infof("Failed to send request: %v", err)
+			return err
+		}
+	}
+}
+
+// writeWebSocketResponses writes the responses of the stream as messages
+// until it ends, and returns the error it ends with.
+func writeWebSocketResponses(conn *websocket.Conn, marshaler Marshaler, meth *Method, stream *WebSocketStream) error {
+	typ := websocket.BinaryMessage
+	if ct := marshaler.ContentType(nil); strings.Contains(ct, "json") || strings.HasPrefix(ct, "text/") {
+		typ = websocket.TextMessage
+	}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var buf []byte
+		if rb, ok := resp.(responseBody); ok {
+			buf, err = marshaler.Marshal(rb.XXX_ResponseBody())
+		} else {
+			buf, err = marshaler.Marshal(resp)
+		}
+		if err != nil {
+			grpclog.Infof("Failed to marshal response chunk: %v", err)
+			return status.Errorf(codes.Internal, "%v", err)
+		}
+		if err := conn.WriteMessage(typ, buf); err != nil {
+			return status.Errorf(codes.Canceled, "%v", err)
+		}
+		if meth != nil && !meth.ServerStreaming {
+			return nil
+		}
+	}
+}
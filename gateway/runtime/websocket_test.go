@@ -0,0 +1,255 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/binchencoder/gateway-proto/data"
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// echoClientStream echoes the messages sent on it, and ends with err once
+// its sending direction is closed.
+type echoClientStream struct {
+	ctx context.Context
+	ch  chan string
+	err error
+}
+
+func (s *echoClientStream) Header() (metadata.MD, error) { return nil, nil }
+func (s *echoClientStream) Trailer() metadata.MD         { return nil }
+func (s *echoClientStream) Context() context.Context     { return s.ctx }
+
+func (s *echoClientStream) CloseSend() error {
+	close(s.ch)
+	return nil
+}
+
+func (s *echoClientStream) SendMsg(m interface{}) error {
+	s.ch <- m.(*wrapperspb.StringValue).Value
+	return nil
+}
+
+func (s *echoClientStream) RecvMsg(m interface{}) error {
+	select {
+	case v, ok := <-s.ch:
+		if !ok {
+			if s.err != nil {
+				return s.err
+			}
+			return io.EOF
+		}
+		m.(*wrapperspb.StringValue).Value = v
+		return nil
+	case <-s.ctx.Done():
+		return status.FromContextError(s.ctx.Err()).Err()
+	}
+}
+
+// newTestWebSocketServer returns the address of a server of the method Chat,
+// which echoes the messages or ends with streamErr, and the count of the
+// streams opened.
+func newTestWebSocketServer(t *testing.T, streamErr error, opts ...ServeMuxOption) (string, *int32) {
+	mux := NewServeMux(opts...)
+	meth := &Method{Name: "Chat", Path: "/v1/chat", HttpMethod: "POST", ClientStreaming: true, ServerStreaming: true}
+	pat, err := ParsePattern("/v1/chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var opened int32
+	mux.HandleMethod("POST", pat, data.ServiceId(1), meth, func(ctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		inboundMarshaler, outboundMarshaler := MarshalerForRequest(mux, req)
+		if !IsWebSocketRequest(req) {
+			HTTPError(ctx, mux, outboundMarshaler, w, req, status.Error(codes.Unimplemented, "not a websocket"))
+			return
+		}
+		ForwardWebSocket(req.Context(), mux, inboundMarshaler, outboundMarshaler, w, req, meth, func(ctx context.Context) (*WebSocketStream, error) {
+			atomic.AddInt32(&opened, 1)
+			stream := &echoClientStream{ctx: ctx, ch: make(chan string, 8), err: streamErr}
+			if streamErr != nil {
+				close(stream.ch)
+			}
+			return &WebSocketStream{
+				Stream:     stream,
+				NewRequest: func() proto.Message { return new(wrapperspb.StringValue) },
+				Recv: func() (proto.Message, error) {
+					msg := new(wrapperspb.StringValue)
+					if err := stream.RecvMsg(msg); err != nil {
+						return nil, err
+					}
+					return msg, nil
+				},
+			}, nil
+		})
+	})
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s.Listener.Addr().String(), &opened
+}
+
+func dialTestWebSocket(t *testing.T, addr string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	d := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	conn, resp, err := d.Dial("ws://"+addr+"/v1/chat", header)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	}
+	return conn, resp, err
+}
+
+// readClose reads until the close frame of the server.
+func readClose(t *testing.T, conn *websocket.Conn) (int, string) {
+	for {
+		_, _, err := conn.ReadMessage()
+		if ce, ok := err.(*websocket.CloseError); ok {
+			return ce.Code, ce.Text
+		}
+		if err != nil {
+			t.Fatalf("ReadMessage() = %v; want a close frame", err)
+		}
+	}
+}
+
+func TestForwardWebSocket(t *testing.T) {
+	addr, _ := newTestWebSocketServer(t, nil)
+	conn, _, err := dialTestWebSocket(t, addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{`"hello"`, `"world"`} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		if typ, payload, err := conn.ReadMessage(); err != nil || typ != websocket.TextMessage || string(payload) != msg {
+			t.Errorf("ReadMessage() = %d %s, %v; want text %s", typ, payload, err, msg)
+		}
+	}
+
+	// An empty message closes the sending direction, which ends the stream.
+	conn.WriteMessage(websocket.TextMessage, nil)
+	if code, reason := readClose(t, conn); code != WebSocketCloseNormal {
+		t.Errorf("close = %d %q; want %d", code, reason, WebSocketCloseNormal)
+	}
+}
+
+func TestForwardWebSocketErrors(t *testing.T) {
+	addr, _ := newTestWebSocketServer(t, status.Error(codes.NotFound, "no such chat"))
+	conn, _, err := dialTestWebSocket(t, addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, reason := readClose(t, conn); code != WebSocketCloseStatusBase+int(codes.NotFound) || reason != "no such chat" {
+		t.Errorf("close = %d %q; want %d %q", code, reason, WebSocketCloseStatusBase+int(codes.NotFound), "no such chat")
+	}
+
+	addr, _ = newTestWebSocketServer(t, nil)
+	conn, _, err = dialTestWebSocket(t, addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"value"`))
+	if code, reason := readClose(t, conn); code != WebSocketCloseStatusBase+int(codes.InvalidArgument) {
+		t.Errorf("close = %d %q; want %d", code, reason, WebSocketCloseStatusBase+int(codes.InvalidArgument))
+	}
+}
+
+func TestForwardWebSocketHandshake(t *testing.T) {
+	allowed := WithWebSocketOriginChecker(func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "https://app.example.com"
+	})
+	for _, spec := range []struct {
+		name     string
+		opts     []ServeMuxOption
+		header   http.Header
+		wantCode int
+	}{
+		{
+			name:     "no origin",
+			wantCode: http.StatusSwitchingProtocols,
+		},
+		{
+			name:     "same origin",
+			header:   http.Header{"Origin": {"http://HOST"}},
+			wantCode: http.StatusSwitchingProtocols,
+		},
+		{
+			name:     "cross origin",
+			header:   http.Header{"Origin": {"https://evil.example.com"}},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "cross origin allowed",
+			opts:     []ServeMuxOption{allowed},
+			header:   http.Header{"Origin": {"https://app.example.com"}},
+			wantCode: http.StatusSwitchingProtocols,
+		},
+		{
+			name:     "cross origin not allowed",
+			opts:     []ServeMuxOption{allowed},
+			header:   http.Header{"Origin": {"https://evil.example.com"}},
+			wantCode: http.StatusForbidden,
+		},
+	} {
+		addr, opened := newTestWebSocketServer(t, nil, spec.opts...)
+		if o := spec.header.Get("Origin"); o == "http://HOST" {
+			spec.header.Set("Origin", "http://"+addr)
+		}
+		_, resp, err := dialTestWebSocket(t, addr, spec.header)
+		if resp == nil {
+			t.Errorf("%s: Dial() failed with %v", spec.name, err)
+			continue
+		}
+		if resp.StatusCode != spec.wantCode {
+			t.Errorf("%s: handshake status = %d; want %d", spec.name, resp.StatusCode, spec.wantCode)
+		}
+		// The stream is opened only after the handshake succeeds.
+		if spec.wantCode != http.StatusSwitchingProtocols && atomic.LoadInt32(opened) != 0 {
+			t.Errorf("%s: the stream is opened for a failed handshake", spec.name)
+		}
+	}
+
+	// An invalid handshake fails before the stream is opened.
+	addr, opened := newTestWebSocketServer(t, nil)
+	req, _ := http.NewRequest("GET", "http://"+addr+"/v1/chat", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "8")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || atomic.LoadInt32(opened) != 0 {
+		t.Errorf("handshake of version 8 = %d, %d streams opened; want %d, none", resp.StatusCode, atomic.LoadInt32(opened), http.StatusBadRequest)
+	}
+}
+
+func TestServeMuxWebSocketFallback(t *testing.T) {
+	mux := NewServeMux()
+	pat, _ := ParsePattern("/v1/chat")
+	mux.HandleMethod("POST", pat, data.ServiceId(1), &Method{Name: "Chat", ServerStreaming: true}, func(ctx context.Context, w http.ResponseWriter, r *http.Request, pathParams map[string]string) {})
+
+	// A plain GET does not fall back to the streaming POST route.
+	req := httptest.NewRequest("GET", "/v1/chat", nil)
+	if m := mux.Match(req); m.Status != http.StatusMethodNotAllowed {
+		t.Errorf("mux.Match() = %+v; want %d", m, http.StatusMethodNotAllowed)
+	}
+
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "WebSocket")
+	if m := mux.Match(req); m.Status != http.StatusOK || !strings.Contains(m.Reason, "WebSocket") {
+		t.Errorf("mux.Match() = %+v; want the streaming POST route", m)
+	}
+}
@@ -0,0 +1,312 @@
+package integrate
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/util"
+)
+
+var (
+	adminAddr  = flag.String("admin-addr", "", "The address of the admin listener, such as 127.0.0.1:8081. Empty disables the admin endpoints.")
+	adminToken = flag.String("admin-token", "", "The bearer token required by the admin endpoints.")
+	adminWait  = flag.Duration("admin-switch-wait", time.Second, "How long enabling or disabling a service group waits for it to settle before answering 202 with its state.")
+)
+
+// ServeAdmin serves the admin endpoints of the mux at the address of flag
+// --admin-addr. It returns nil at once if the flag is not set.
+func ServeAdmin(mux *runtime.ServeMux) error {
+	if *adminAddr == "" {
+		return nil
+	}
+	h, err := NewAdminHandler(*adminToken, mux)
+	if err != nil {
+		return err
+	}
+	util.Logf(util.ConfigLogger, "Serving admin endpoints at %s.", *adminAddr)
+	return http.ListenAndServe(*adminAddr, h)
+}
+
+// AdminHandler serves the admin endpoints to list the service groups and
+// their methods, to enable or disable them at runtime, and to inspect the
+// routes of the mux:
+//
+//	GET  /admin/groups
+//	POST /admin/groups/{enable|disable}?service=&namespace=&port_name=
+//	POST /admin/methods/{enable|disable}?service=&method=
+//	GET  /admin/routes
+//	GET  /admin/routes/match?method=&url=&content_type=&method_override=
+//
+// The service of a group is its skylb service name, optionally qualified by
+// the namespace and the port name. A group which does not settle within flag
+// --admin-switch-wait, e.g. while its backends are unreachable, is answered
+// with 202 and its state, enabling or disabling. The service of a method is its gRPC
+// service name. All the changes are audited to util.ConfigLogger.
+type AdminHandler struct {
+	token  string
+	groups func() map[string]*runtime.ServiceGroup
+	mux    *runtime.ServeMux
+}
+
+// NewAdminHandler returns an AdminHandler of the mux which requires the
+// bearer token.
+func NewAdminHandler(token string, mux *runtime.ServeMux) (*AdminHandler, error) {
+	if token == "" {
+		return nil, fmt.Errorf("the admin endpoints require flag --admin-token")
+	}
+	return &AdminHandler{token: token, groups: runtime.GetServicGroups, mux: mux}, nil
+}
+
+// adminGroup is the JSON view of a service group.
+type adminGroup struct {
+	Service   string          `json:"service"`
+	Namespace string          `json:"namespace"`
+	PortName  string          `json:"port_name"`
+	Enabled   bool            `json:"enabled"`
+	State     string          `json:"state"`
+	Services  []*adminService `json:"services"`
+}
+
+// adminService is the JSON view of a gRPC service.
+type adminService struct {
+	Name    string         `json:"name"`
+	Methods []*adminMethod `json:"methods"`
+}
+
+// adminMethod is the JSON view of an API method.
+type adminMethod struct {
+	Name       string `json:"name"`
+	HttpMethod string `json:"http_method"`
+	Path       string `json:"path"`
+	Enabled    bool   `json:"enabled"`
+}
+
+// adminResult is the JSON response of the changes.
+type adminResult struct {
+	Target  string `json:"target"`
+	Enabled bool   `json:"enabled"`
+	Changed bool   `json:"changed"`
+	// State is the state of a service group.
+	State string `json:"state,omitempty"`
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		h.audit(r, "auth", "", "unauthorized")
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	switch path := strings.TrimSuffix(r.URL.Path, "/"); path {
+	case "/admin/groups":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, h.listGroups())
+	case "/admin/groups/enable", "/admin/groups/disable":
+		h.change(w, r, path, h.setGroupEnabled)
+	case "/admin/methods/enable", "/admin/methods/disable":
+		h.change(w, r, path, h.setMethodEnabled)
+	case "/admin/routes":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, h.mux.Routes())
+	case "/admin/routes/match":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		m, code, err := h.matchRoute(r)
+		if err != nil {
+			http.Error(w, err.Error(), code)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, m)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// change enables or disables the target of the request by set, and audits
+// the result.
+func (h *AdminHandler) change(w http.ResponseWriter, r *http.Request, path string, set func(r *http.Request, enabled bool) (*adminResult, int, error)) {
+	action := path[strings.LastIndex(path, "/")+1:]
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+	res, code, err := set(r, action == "enable")
+	if err != nil {
+		h.audit(r, action, r.URL.RawQuery, err.Error())
+		http.Error(w, err.Error(), code)
+		return
+	}
+	h.audit(r, action, res.Target, fmt.Sprintf("changed:%t", res.Changed))
+	writeAdminJSON(w, code, res)
+}
+
+func (h *AdminHandler) authorized(r *http.Request) bool {
+	auth := r.Header.Get(Authorization)
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *AdminHandler) audit(r *http.Request, action, target, result string) {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	util.Logf(util.ConfigLogger, util.AdminAuditFormat, remote, r.Method, r.URL.Path, action, target, result)
+}
+
+func (h *AdminHandler) listGroups() []*adminGroup {
+	groups := []*adminGroup{}
+	for _, sg := range h.groups() {
+		g := &adminGroup{
+			Service:   sg.Spec.ServiceName,
+			Namespace: sg.Spec.Namespace,
+			PortName:  sg.Spec.PortName,
+			Enabled:   sg.IsEnabled(),
+			State:     sg.State().String(),
+			Services:  []*adminService{},
+		}
+		for _, svc := range sg.Services {
+			s := &adminService{Name: svc.Name, Methods: []*adminMethod{}}
+			for _, m := range svc.Methods {
+				s.Methods = append(s.Methods, &adminMethod{
+					Name:       m.Name,
+					HttpMethod: m.HttpMethod,
+					Path:       m.Path,
+					Enabled:    m.IsEnabled(),
+				})
+			}
+			g.Services = append(g.Services, s)
+		}
+		sort.Slice(g.Services, func(i, j int) bool { return g.Services[i].Name < g.Services[j].Name })
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groupKey(groups[i].Service, groups[i].Namespace, groups[i].PortName) <
+			groupKey(groups[j].Service, groups[j].Namespace, groups[j].PortName)
+	})
+	return groups
+}
+
+// setGroupEnabled enables or disables the service group selected by the
+// query parameters service, namespace and port_name.
+func (h *AdminHandler) setGroupEnabled(r *http.Request, enabled bool) (*adminResult, int, error) {
+	q := r.URL.Query()
+	service, ns, port := q.Get("service"), q.Get("namespace"), q.Get("port_name")
+	if service == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("parameter service is required")
+	}
+	var found *runtime.ServiceGroup
+	for _, sg := range h.groups() {
+		if sg.Spec.ServiceName != service || (ns != "" && sg.Spec.Namespace != ns) || (port != "" && sg.Spec.PortName != port) {
+			continue
+		}
+		if found != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("service %s is ambiguous, set parameters namespace and port_name", service)
+		}
+		found = sg
+	}
+	if found == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("service group %s not found", groupKey(service, ns, port))
+	}
+	res := &adminResult{
+		Target:  groupKey(found.Spec.ServiceName, found.Spec.Namespace, found.Spec.PortName),
+		Enabled: enabled,
+		Changed: found.Switch(enabled),
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), *adminWait)
+	defer cancel()
+	code := http.StatusOK
+	if err := found.Wait(ctx); err != nil {
+		code = http.StatusAccepted
+	}
+	res.State = found.State().String()
+	return res, code, nil
+}
+
+// setMethodEnabled enables or disables the method selected by the query
+// parameters service and method, including all its HTTP bindings.
+func (h *AdminHandler) setMethodEnabled(r *http.Request, enabled bool) (*adminResult, int, error) {
+	q := r.URL.Query()
+	service, method := q.Get("service"), q.Get("method")
+	if service == "" || method == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("parameters service and method are required")
+	}
+	res := &adminResult{Target: service + "." + method, Enabled: enabled}
+	found := false
+	for _, sg := range h.groups() {
+		svc, ok := sg.Services[service]
+		if !ok {
+			continue
+		}
+		for _, m := range svc.Methods {
+			if m.Name != method {
+				continue
+			}
+			found = true
+			if m.IsEnabled() != enabled {
+				m.SetEnabled(enabled)
+				res.Changed = true
+			}
+		}
+	}
+	if !found {
+		return nil, http.StatusNotFound, fmt.Errorf("method %s not found", res.Target)
+	}
+	return res, http.StatusOK, nil
+}
+
+// matchRoute reports the route of the mux which handles the request given
+// by the query parameters method, url and content_type.
+func (h *AdminHandler) matchRoute(r *http.Request) (*runtime.RouteMatch, int, error) {
+	q := r.URL.Query()
+	method, target := strings.ToUpper(q.Get("method")), q.Get("url")
+	if method == "" || target == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("parameters method and url are required")
+	}
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid url %q: %v", target, err)
+	}
+	if ct := q.Get("content_type"); ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+	if override := q.Get("method_override"); override != "" {
+		req.Header.Set("X-HTTP-Method-Override", override)
+	}
+	return h.mux.Match(req), http.StatusOK, nil
+}
+
+// groupKey returns the readable key of a service group.
+func groupKey(service, namespace, portName string) string {
+	return fmt.Sprintf("%s/%s:%s", namespace, service, portName)
+}
+
+func writeAdminJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		util.Logef(util.ErrorLogger, util.ErrorFormat, "", fmt.Sprintf("writing admin response: %v", err))
+	}
+}
@@ -0,0 +1,249 @@
+package integrate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	skypb "github.com/binchencoder/skylb-api/proto"
+)
+
+// newTestAdminHandler returns an AdminHandler of a service group with two
+// methods, and the counters of the group's Enable and Disable calls.
+func newTestAdminHandler(t *testing.T) (*AdminHandler, *runtime.ServiceGroup, *int, *int) {
+	mux := runtime.NewServeMux()
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, pathParams map[string]string) {}
+	if err := mux.HandlePath("GET", "/v1/echo/{id}", 1, noop); err != nil {
+		t.Fatalf("mux.HandlePath() failed with %v", err)
+	}
+	if err := mux.HandlePath("POST", "/v1/echo", 1, noop); err != nil {
+		t.Fatalf("mux.HandlePath() failed with %v", err)
+	}
+	h, err := NewAdminHandler("secret", mux)
+	if err != nil {
+		t.Fatalf("NewAdminHandler() failed with %v", err)
+	}
+	enables, disables := 0, 0
+	sg := &runtime.ServiceGroup{
+		Spec:    &skypb.ServiceSpec{ServiceName: "echo-service", Namespace: "default", PortName: "grpc"},
+		Enable:  func() { enables++ },
+		Disable: func() { disables++ },
+		Services: map[string]*runtime.Service{
+			"EchoService": {
+				Name: "EchoService",
+				Methods: []*runtime.Method{
+					{Name: "Echo", HttpMethod: "GET", Path: "/v1/echo/{id}"},
+					{Name: "Echo", HttpMethod: "POST", Path: "/v1/echo"},
+					{Name: "EchoBody", HttpMethod: "POST", Path: "/v1/echo_body"},
+				},
+			},
+		},
+	}
+	for _, m := range sg.Services["EchoService"].Methods {
+		m.SetEnabled(true)
+	}
+	h.groups = func() map[string]*runtime.ServiceGroup {
+		return map[string]*runtime.ServiceGroup{"echo": sg}
+	}
+	return h, sg, &enables, &disables
+}
+
+func serveAdmin(h http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		r.Header.Set(Authorization, "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestNewAdminHandlerRequiresToken(t *testing.T) {
+	if _, err := NewAdminHandler("", runtime.NewServeMux()); err == nil {
+		t.Errorf("NewAdminHandler(\"\") succeeded; want error")
+	}
+}
+
+func TestAdminHandlerAuth(t *testing.T) {
+	h, _, _, _ := newTestAdminHandler(t)
+	for _, token := range []string{"", "wrong", "secret2"} {
+		if w := serveAdmin(h, "GET", "/admin/groups", token); w.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: code = %d; want %d", token, w.Code, http.StatusUnauthorized)
+		}
+	}
+	if w := serveAdmin(h, "GET", "/admin/groups", "secret"); w.Code != http.StatusOK {
+		t.Errorf("code = %d; want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestAdminHandlerGroups(t *testing.T) {
+	h, sg, enables, disables := newTestAdminHandler(t)
+
+	for _, spec := range []struct {
+		method, target string
+		wantCode       int
+		wantChanged    bool
+		wantEnabled    bool
+	}{
+		{"POST", "/admin/groups/enable?service=echo-service", http.StatusOK, true, true},
+		{"POST", "/admin/groups/enable?service=echo-service&namespace=default", http.StatusOK, false, true},
+		{"POST", "/admin/groups/disable?service=echo-service&port_name=grpc", http.StatusOK, true, false},
+		{"POST", "/admin/groups/enable?service=echo-service&namespace=other", http.StatusNotFound, false, false},
+		{"POST", "/admin/groups/enable", http.StatusBadRequest, false, false},
+		{"GET", "/admin/groups/enable?service=echo-service", http.StatusMethodNotAllowed, false, false},
+	} {
+		w := serveAdmin(h, spec.method, spec.target, "secret")
+		if w.Code != spec.wantCode {
+			t.Errorf("%s %s: code = %d; want %d", spec.method, spec.target, w.Code, spec.wantCode)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		res := adminResult{}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s %s: unmarshaling %q failed with %v", spec.method, spec.target, w.Body.String(), err)
+		}
+		if res.Changed != spec.wantChanged || sg.IsEnabled() != spec.wantEnabled {
+			t.Errorf("%s %s: changed = %t, enabled = %t; want %t, %t", spec.method, spec.target, res.Changed, sg.IsEnabled(), spec.wantChanged, spec.wantEnabled)
+		}
+	}
+	if *enables != 1 || *disables != 1 {
+		t.Errorf("enables, disables = %d, %d; want 1, 1", *enables, *disables)
+	}
+}
+
+func TestAdminHandlerGroupsEnabling(t *testing.T) {
+	defer func(v time.Duration) { *adminWait = v }(*adminWait)
+	*adminWait = 10 * time.Millisecond
+	h, sg, _, disables := newTestAdminHandler(t)
+	release := make(chan struct{})
+	sg.Enable = func() { <-release }
+
+	for _, spec := range []struct {
+		target    string
+		wantState string
+	}{
+		{"/admin/groups/enable?service=echo-service", "enabling"},
+		{"/admin/groups/disable?service=echo-service", "disabling"},
+	} {
+		w := serveAdmin(h, "POST", spec.target, "secret")
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("%s: code = %d; want %d", spec.target, w.Code, http.StatusAccepted)
+		}
+		res := adminResult{}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s: unmarshaling %q failed with %v", spec.target, w.Body.String(), err)
+		}
+		if !res.Changed || res.State != spec.wantState {
+			t.Errorf("%s: changed = %t, state = %q; want true, %q", spec.target, res.Changed, res.State, spec.wantState)
+		}
+		if sg.IsEnabled() {
+			t.Errorf("%s: enabled while Enable runs", spec.target)
+		}
+	}
+
+	close(release)
+	if err := sg.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sg.State() != runtime.ServiceGroupDisabled || *disables != 1 {
+		t.Errorf("state = %s, disables = %d; want disabled, 1", sg.State(), *disables)
+	}
+}
+
+func TestAdminHandlerMethods(t *testing.T) {
+	h, sg, _, _ := newTestAdminHandler(t)
+	methods := sg.Services["EchoService"].Methods
+
+	w := serveAdmin(h, "POST", "/admin/methods/disable?service=EchoService&method=Echo", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d; want %d", w.Code, http.StatusOK)
+	}
+	if methods[0].IsEnabled() || methods[1].IsEnabled() || !methods[2].IsEnabled() {
+		t.Errorf("enabled = %t, %t, %t; want false, false, true", methods[0].IsEnabled(), methods[1].IsEnabled(), methods[2].IsEnabled())
+	}
+
+	w = serveAdmin(h, "GET", "/admin/groups", "secret")
+	groups := []*adminGroup{}
+	if err := json.Unmarshal(w.Body.Bytes(), &groups); err != nil {
+		t.Fatalf("unmarshaling %q failed with %v", w.Body.String(), err)
+	}
+	if len(groups) != 1 || len(groups[0].Services) != 1 || len(groups[0].Services[0].Methods) != 3 {
+		t.Fatalf("groups = %s; want 1 group of 3 methods", w.Body.String())
+	}
+	if m := groups[0].Services[0].Methods[0]; m.Name != "Echo" || m.Enabled {
+		t.Errorf("method = %+v; want disabled Echo", m)
+	}
+
+	if w := serveAdmin(h, "POST", "/admin/methods/enable?service=EchoService&method=Unknown", "secret"); w.Code != http.StatusNotFound {
+		t.Errorf("code = %d; want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAdminHandlerRoutes(t *testing.T) {
+	h, _, _, _ := newTestAdminHandler(t)
+
+	w := serveAdmin(h, "GET", "/admin/routes", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d; want %d", w.Code, http.StatusOK)
+	}
+	routes := []runtime.RouteInfo{}
+	if err := json.Unmarshal(w.Body.Bytes(), &routes); err != nil {
+		t.Fatalf("unmarshaling %q failed with %v", w.Body.String(), err)
+	}
+	if len(routes) != 2 || routes[0].HttpMethod != "GET" || routes[0].Path != "/v1/echo/{id=*}" {
+		t.Errorf("routes = %s; want GET /v1/echo/{id=*} and POST /v1/echo", w.Body.String())
+	}
+
+	for _, spec := range []struct {
+		target     string
+		wantCode   int
+		wantStatus int
+		wantPath   string
+	}{
+		{"/admin/routes/match?method=get&url=/v1/echo/1", http.StatusOK, http.StatusOK, "/v1/echo/{id=*}"},
+		{"/admin/routes/match?method=GET&url=/v1/echo", http.StatusOK, http.StatusMethodNotAllowed, "/v1/echo"},
+		{"/admin/routes/match?method=GET&url=/v2/echo", http.StatusOK, http.StatusNotFound, ""},
+		{"/admin/routes/match?method=GET", http.StatusBadRequest, 0, ""},
+	} {
+		w := serveAdmin(h, "GET", spec.target, "secret")
+		if w.Code != spec.wantCode {
+			t.Errorf("%s: code = %d; want %d", spec.target, w.Code, spec.wantCode)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		m := runtime.RouteMatch{}
+		if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+			t.Fatalf("%s: unmarshaling %q failed with %v", spec.target, w.Body.String(), err)
+		}
+		path := ""
+		if m.Route != nil {
+			path = m.Route.Path
+		}
+		if m.Status != spec.wantStatus || path != spec.wantPath || m.Reason == "" {
+			t.Errorf("%s: match = %s; want status %d of route %q", spec.target, w.Body.String(), spec.wantStatus, spec.wantPath)
+		}
+	}
+}
+
+func TestDisabledMethod(t *testing.T) {
+	gh := &gatewayHook{}
+	svc := &runtime.Service{Name: "EchoService"}
+	m := &runtime.Method{Name: "Echo", HttpMethod: "GET", Path: "/v1/echo"}
+	r := httptest.NewRequest("GET", "/v1/echo", nil)
+
+	_, err := gh.requestAccepted(context.Background(), svc, m, httptest.NewRecorder(), r)
+	if got := status.Code(err); got != codes.Unavailable {
+		t.Errorf("requestAccepted() of disabled method = %v; want %v", got, codes.Unavailable)
+	}
+}
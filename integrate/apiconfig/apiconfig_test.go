@@ -0,0 +1,159 @@
+package apiconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+mode: strict
+apis:
+  - http_method: GET
+    path: /v1/echo/{id}
+    source_allow: [web, client]
+    sign_required: true
+  - http_method: post
+    path: /v1/echo
+    enabled: false
+    rate_limit: 10
+    rate_limit_burst: 20
+`
+
+func writeConfig(t *testing.T, file, content string, modTime time.Time) {
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() failed with %v", err)
+	}
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatalf("Chtimes() failed with %v", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "apis.yaml")
+	writeConfig(t, file, testConfig, time.Now())
+
+	conf, err := LoadConfig(file)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed with %v", err)
+	}
+	if conf.Mode != Strict {
+		t.Errorf("Mode = %q; want %q", conf.Mode, Strict)
+	}
+
+	echo, ok := conf.GetApi("GET", "/v1/echo/{id}")
+	if !ok {
+		t.Fatalf("GetApi(GET /v1/echo/{id}) not found")
+	}
+	if !echo.IsEnabled() || echo.SignRequired == nil || !*echo.SignRequired || echo.LoginRequired != nil {
+		t.Errorf("GET /v1/echo/{id} = %+v; want enabled and sign required", echo)
+	}
+	for source, want := range map[string]bool{"web": true, "client": true, "third": false, "": false} {
+		if got := echo.AllowSource(source); got != want {
+			t.Errorf("AllowSource(%q) = %t; want %t", source, got, want)
+		}
+	}
+
+	post, ok := conf.GetApi("POST", "/v1/echo")
+	if !ok {
+		t.Fatalf("GetApi(POST /v1/echo) not found")
+	}
+	if post.IsEnabled() || post.RateLimit != 10 || post.RateLimitBurst != 20 || !post.AllowSource("third") {
+		t.Errorf("POST /v1/echo = %+v; want disabled with rate limit 10/20 for all sources", post)
+	}
+
+	if _, ok := conf.GetApi("GET", "/v1/echo"); ok {
+		t.Errorf("GetApi(GET /v1/echo) found; want unknown")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	for _, spec := range []struct {
+		name, content string
+	}{
+		{"unknown mode", "mode: lenient\n"},
+		{"unknown field", "apis:\n  - http_method: GET\n    path: /v1/echo\n    enable: true\n"},
+		{"relative path", "apis:\n  - http_method: GET\n    path: v1/echo\n"},
+		{"duplicated", "apis:\n  - http_method: GET\n    path: /v1/echo\n  - http_method: get\n    path: /v1/echo\n"},
+	} {
+		file := filepath.Join(t.TempDir(), "apis.yaml")
+		writeConfig(t, file, spec.content, time.Now())
+		if _, err := LoadConfig(file); err == nil {
+			t.Errorf("%s: LoadConfig() succeeded; want error", spec.name)
+		}
+	}
+}
+
+func TestFileStoreReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "apis.yaml")
+	t0 := time.Now().Add(-time.Hour)
+	writeConfig(t, file, testConfig, t0)
+
+	s, err := NewFileStore(file)
+	if err != nil {
+		t.Fatalf("NewFileStore() failed with %v", err)
+	}
+	defer s.Close()
+	if !s.Strict() {
+		t.Errorf("Strict() = false; want true")
+	}
+	if reloaded, err := s.Reload(); reloaded || err != nil {
+		t.Errorf("Reload() of unchanged file = %t, %v; want false, nil", reloaded, err)
+	}
+
+	// An invalid file keeps the current configuration.
+	writeConfig(t, file, "mode: lenient\n", t0.Add(time.Second))
+	if _, err := s.Reload(); err == nil {
+		t.Errorf("Reload() of invalid file succeeded; want error")
+	}
+	if _, ok := s.GetApi("POST", "/v1/echo"); !ok {
+		t.Errorf("GetApi(POST /v1/echo) not found after invalid reload")
+	}
+	if reloaded, err := s.Reload(); reloaded || err != nil {
+		t.Errorf("Reload() of unchanged invalid file = %t, %v; want false, nil", reloaded, err)
+	}
+
+	writeConfig(t, file, "apis:\n  - http_method: GET\n    path: /v1/echo\n", t0.Add(2*time.Second))
+	if reloaded, err := s.Reload(); !reloaded || err != nil {
+		t.Fatalf("Reload() = %t, %v; want true, nil", reloaded, err)
+	}
+	if s.Strict() {
+		t.Errorf("Strict() = true; want false")
+	}
+	if _, ok := s.GetApi("POST", "/v1/echo"); ok {
+		t.Errorf("GetApi(POST /v1/echo) found after reload; want unknown")
+	}
+}
+
+func TestFileStoreWatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "apis.yaml")
+	t0 := time.Now().Add(-time.Hour)
+	writeConfig(t, file, testConfig, t0)
+
+	s, err := NewFileStore(file)
+	if err != nil {
+		t.Fatalf("NewFileStore() failed with %v", err)
+	}
+	defer s.Close()
+	reloads := make(chan *Config, 1)
+	s.Watch(time.Millisecond, func(conf *Config, err error) {
+		if err == nil {
+			select {
+			case reloads <- conf:
+			default:
+			}
+		}
+	})
+
+	writeConfig(t, file, "mode: permissive\n", t0.Add(time.Second))
+	select {
+	case conf := <-reloads:
+		if conf.Mode != Permissive {
+			t.Errorf("reloaded Mode = %q; want %q", conf.Mode, Permissive)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the file change was not reloaded")
+	}
+}
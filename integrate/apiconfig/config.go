@@ -0,0 +1,120 @@
+package apiconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Mode decides how the APIs not in the configuration are handled.
+type Mode string
+
+const (
+	// Permissive lets the unknown APIs through with the policies of their
+	// annotations.
+	Permissive Mode = "permissive"
+	// Strict rejects the unknown APIs.
+	Strict Mode = "strict"
+)
+
+// SourceAll allows the requests from all sources.
+const SourceAll = "all"
+
+// Api is the policy of an API method, which overrides its annotations.
+type Api struct {
+	// HttpMethod and Path identify the API method, where Path is the path
+	// template of the HTTP binding, such as "/v1/users/{id}".
+	HttpMethod string `yaml:"http_method"`
+	Path       string `yaml:"path"`
+	// Enabled defaults to true.
+	Enabled *bool `yaml:"enabled"`
+	// SourceAllow lists the x-source values allowed to call the API. Empty
+	// or "all" allows all sources.
+	SourceAllow []string `yaml:"source_allow"`
+	// SignRequired and LoginRequired override the client_sign_required and
+	// login_not_required annotations if set.
+	SignRequired  *bool `yaml:"sign_required"`
+	LoginRequired *bool `yaml:"login_required"`
+	// RateLimit and RateLimitBurst override the rate_limit and
+	// rate_limit_burst annotations if RateLimit is positive.
+	RateLimit      float64 `yaml:"rate_limit"`
+	RateLimitBurst int32   `yaml:"rate_limit_burst"`
+}
+
+// IsEnabled returns whether the API is enabled.
+func (a *Api) IsEnabled() bool {
+	return a.Enabled == nil || *a.Enabled
+}
+
+// AllowSource returns whether the requests from the source are allowed.
+func (a *Api) AllowSource(source string) bool {
+	if len(a.SourceAllow) == 0 {
+		return true
+	}
+	for _, s := range a.SourceAllow {
+		if s == SourceAll || s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// Config is the API configuration.
+type Config struct {
+	// Mode defaults to Permissive.
+	Mode Mode   `yaml:"mode"`
+	Apis []*Api `yaml:"apis"`
+
+	apis map[string]*Api
+}
+
+// LoadConfig reads a YAML API configuration from the given file.
+func LoadConfig(file string) (*Config, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	conf := &Config{}
+	if err := yaml.UnmarshalStrict(b, conf); err != nil {
+		return nil, fmt.Errorf("parsing API config %s: %v", file, err)
+	}
+	if err := conf.init(); err != nil {
+		return nil, fmt.Errorf("API config %s: %v", file, err)
+	}
+	return conf, nil
+}
+
+// init validates the configuration and indexes the APIs.
+func (c *Config) init() error {
+	switch c.Mode {
+	case "":
+		c.Mode = Permissive
+	case Permissive, Strict:
+	default:
+		return fmt.Errorf("unknown mode %q", c.Mode)
+	}
+	c.apis = make(map[string]*Api, len(c.Apis))
+	for i, a := range c.Apis {
+		if a.HttpMethod == "" || !strings.HasPrefix(a.Path, "/") {
+			return fmt.Errorf("API #%d requires http_method and an absolute path", i)
+		}
+		key := apiKey(a.HttpMethod, a.Path)
+		if _, ok := c.apis[key]; ok {
+			return fmt.Errorf("API %s %s is duplicated", a.HttpMethod, a.Path)
+		}
+		c.apis[key] = a
+	}
+	return nil
+}
+
+// GetApi returns the policy of the API method, and false if it is unknown.
+func (c *Config) GetApi(httpMethod, path string) (*Api, bool) {
+	a, ok := c.apis[apiKey(httpMethod, path)]
+	return a, ok
+}
+
+func apiKey(httpMethod, path string) string {
+	return strings.ToUpper(httpMethod) + " " + path
+}
@@ -0,0 +1,100 @@
+package apiconfig
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// FileStore is the API configuration read from a local YAML file, which is
+// reloaded when the file changes.
+type FileStore struct {
+	file string
+
+	mu      sync.RWMutex
+	conf    *Config
+	modTime time.Time
+	size    int64
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewFileStore returns a FileStore of the given file.
+func NewFileStore(file string) (*FileStore, error) {
+	s := &FileStore{file: file, stop: make(chan struct{})}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetApi returns the policy of the API method, and false if it is unknown.
+func (s *FileStore) GetApi(httpMethod, path string) (*Api, bool) {
+	return s.Config().GetApi(httpMethod, path)
+}
+
+// Strict returns whether the unknown APIs are rejected.
+func (s *FileStore) Strict() bool {
+	return s.Config().Mode == Strict
+}
+
+// Config returns the current configuration.
+func (s *FileStore) Config() *Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.conf
+}
+
+// Reload reloads the file if it has changed since the last load, and returns
+// whether it is reloaded. The current configuration is kept if the file is
+// invalid, and its error is returned once, until the file changes again.
+func (s *FileStore) Reload() (bool, error) {
+	fi, err := os.Stat(s.file)
+	if err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	unchanged := s.conf != nil && fi.ModTime().Equal(s.modTime) && fi.Size() == s.size
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	conf, err := LoadConfig(s.file)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modTime, s.size = fi.ModTime(), fi.Size()
+	if err != nil {
+		return false, err
+	}
+	s.conf = conf
+	return true, nil
+}
+
+// Watch polls the file at the interval until Close, and calls onReload with
+// the result of every reload. Polling, rather than file notifications, also
+// follows the files replaced by renaming, such as mounted config maps.
+func (s *FileStore) Watch(interval time.Duration, onReload func(conf *Config, err error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				reloaded, err := s.Reload()
+				if reloaded || err != nil {
+					onReload(s.Config(), err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops watching the file.
+func (s *FileStore) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
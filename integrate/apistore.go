@@ -0,0 +1,165 @@
+package integrate
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/integrate/apiconfig"
+	"github.com/binchencoder/janus-gateway/util"
+	"github.com/binchencoder/letsgo/trace"
+)
+
+var (
+	apiConfig               = flag.String("api-config", "", "The YAML file of the API policies, which is reloaded when it changes.")
+	apiConfigReloadInterval = flag.Duration("api-config-reload-interval", 5*time.Second, "The interval to check the file of flag --api-config for changes.")
+
+	apiConfigStore ApiConfigStore
+	signVerifier   func(r *http.Request) error
+	authenticator  func(r *http.Request, m *runtime.Method) error
+)
+
+var (
+	errNoSignVerifier  = errors.New("no sign verifier is set")
+	errNoAuthenticator = errors.New("no authenticator is set")
+)
+
+// ApiConfigStore provides the policies of the API methods.
+type ApiConfigStore interface {
+	// GetApi returns the policy of the API method, and false if it is
+	// unknown.
+	GetApi(httpMethod, path string) (*apiconfig.Api, bool)
+	// Strict returns whether the unknown APIs are rejected.
+	Strict() bool
+}
+
+// SetApiConfigStore sets the store of the API policies, which takes
+// precedence over flag --api-config.
+func SetApiConfigStore(s ApiConfigStore) {
+	apiConfigStore = s
+}
+
+// SetSignVerifier sets the function to verify the signatures of the APIs
+// which require client sign. Without it those APIs are rejected.
+func SetSignVerifier(verify func(r *http.Request) error) {
+	signVerifier = verify
+}
+
+// SetAuthenticator sets the function to authenticate the requests of the
+// APIs which require login. Without it those APIs are rejected.
+func SetAuthenticator(authenticate func(r *http.Request, m *runtime.Method) error) {
+	authenticator = authenticate
+}
+
+// newApiConfigStore returns the store set by SetApiConfigStore, or the file
+// store of flag --api-config which is watched for changes. It returns nil if
+// neither is set.
+func newApiConfigStore() (ApiConfigStore, error) {
+	if apiConfigStore != nil {
+		return apiConfigStore, nil
+	}
+	if *apiConfig == "" {
+		return nil, nil
+	}
+	s, err := apiconfig.NewFileStore(*apiConfig)
+	if err != nil {
+		return nil, err
+	}
+	conf := s.Config()
+	util.Logf(util.ConfigLogger, "Loaded %d API policies in %s mode from %s.", len(conf.Apis), conf.Mode, *apiConfig)
+	s.Watch(*apiConfigReloadInterval, func(conf *apiconfig.Config, err error) {
+		if err != nil {
+			util.Logef(util.ErrorLogger, util.ErrorFormat, "", fmt.Sprintf("Reloading API config %s: %v, keeping the current policies.", *apiConfig, err))
+			return
+		}
+		util.Logf(util.ConfigLogger, "Reloaded %d API policies in %s mode from %s.", len(conf.Apis), conf.Mode, *apiConfig)
+	})
+	return s, nil
+}
+
+// apiPolicy checks the request against the policy of the API method in the
+// store, falling back on its annotations.
+func (gh *gatewayHook) apiPolicy(ctx context.Context, r *http.Request, svc *runtime.Service, m *runtime.Method) error {
+	// client.
+	clt := getClientFromHeader(r.Header)
+	// traceid.
+	tid := trace.GetTraceIdOrEmpty(ctx)
+	xt, _ := ctx.Value(RequestReceivedTime).(time.Time)
+
+	// 获取api存储配置信息.
+	api, ok := gh.getApi(m)
+	if !ok {
+		if gh.apis != nil && gh.apis.Strict() {
+			_, err := apiNil(ctx, svc, m, clt, tid, xt)
+			return err
+		}
+		api = &apiconfig.Api{}
+	}
+	// api开启状态.
+	if !api.IsEnabled() {
+		_, err := apiDisabled(ctx, svc, m, clt, tid, xt)
+		return err
+	}
+	if m.IsThirdParty {
+		return nil
+	}
+
+	// 判断SourceAllow.
+	if !api.AllowSource(r.Header.Get(XSource)) {
+		_, err := apiForbidden(ctx, svc, m, clt, tid, xt)
+		return err
+	}
+	// api验签校验.
+	signRequired := m.ClientSignRequired
+	if api.SignRequired != nil {
+		signRequired = *api.SignRequired
+	}
+	if signRequired {
+		err := errNoSignVerifier
+		if signVerifier != nil {
+			err = signVerifier(r)
+		}
+		if err != nil {
+			_, err = apiSignErr(ctx, svc, m, clt, tid, xt, err)
+			return err
+		}
+	}
+	// api登录校验.
+	loginRequired := m.LoginRequired
+	if api.LoginRequired != nil {
+		loginRequired = *api.LoginRequired
+	}
+	if loginRequired {
+		err := errNoAuthenticator
+		if authenticator != nil {
+			err = authenticator(r, m)
+		}
+		if err != nil {
+			_, err = apiLoginErr(ctx, svc, m, clt, tid, xt, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// getApi returns the policy of the API method in the store.
+func (gh *gatewayHook) getApi(m *runtime.Method) (*apiconfig.Api, bool) {
+	if gh.apis == nil {
+		return nil, false
+	}
+	return gh.apis.GetApi(m.HttpMethod, m.Path)
+}
+
+// rateLimit returns the rate limit of the API method, overridden by its
+// policy in the store.
+func (gh *gatewayHook) rateLimit(m *runtime.Method) (float64, int32) {
+	if api, ok := gh.getApi(m); ok && api.RateLimit > 0 {
+		return api.RateLimit, api.RateLimitBurst
+	}
+	return m.RateLimit, m.RateLimitBurst
+}
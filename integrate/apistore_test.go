@@ -0,0 +1,134 @@
+package integrate
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/integrate/apiconfig"
+)
+
+// fakeApiConfigStore is an ApiConfigStore of the APIs keyed by path.
+type fakeApiConfigStore struct {
+	apis   map[string]*apiconfig.Api
+	strict bool
+}
+
+func (s *fakeApiConfigStore) GetApi(httpMethod, path string) (*apiconfig.Api, bool) {
+	a, ok := s.apis[path]
+	return a, ok
+}
+
+func (s *fakeApiConfigStore) Strict() bool {
+	return s.strict
+}
+
+func TestApiPolicy(t *testing.T) {
+	defer SetSignVerifier(nil)
+	defer SetAuthenticator(nil)
+	SetSignVerifier(func(r *http.Request) error {
+		if r.Header.Get(XSign) != "ok" {
+			return errors.New("bad sign")
+		}
+		return nil
+	})
+	SetAuthenticator(func(r *http.Request, m *runtime.Method) error {
+		if r.Header.Get(Authorization) == "" {
+			return errors.New("no token")
+		}
+		return nil
+	})
+
+	yes, no := true, false
+	store := &fakeApiConfigStore{apis: map[string]*apiconfig.Api{
+		"/v1/disabled": {Enabled: &no},
+		"/v1/web":      {SourceAllow: []string{ResourceWeb}},
+		"/v1/signed":   {SignRequired: &yes},
+		"/v1/public":   {LoginRequired: &no},
+	}}
+	svc := &runtime.Service{Name: "EchoService"}
+	for _, spec := range []struct {
+		name    string
+		strict  bool
+		path    string
+		method  runtime.Method
+		headers map[string]string
+
+		wantCode codes.Code
+	}{
+		{name: "unknown permissive", path: "/v1/unknown", wantCode: codes.OK},
+		{name: "unknown strict", strict: true, path: "/v1/unknown", wantCode: codes.PermissionDenied},
+		{name: "disabled", path: "/v1/disabled", wantCode: codes.Unavailable},
+		{name: "source allowed", path: "/v1/web", headers: map[string]string{XSource: ResourceWeb}, wantCode: codes.OK},
+		{name: "source forbidden", path: "/v1/web", headers: map[string]string{XSource: ResourceClient}, wantCode: codes.PermissionDenied},
+		{name: "third party skips source", path: "/v1/web", method: runtime.Method{IsThirdParty: true}, wantCode: codes.OK},
+		{name: "sign overridden", path: "/v1/signed", wantCode: codes.InvalidArgument},
+		{name: "signed", path: "/v1/signed", headers: map[string]string{XSign: "ok"}, wantCode: codes.OK},
+		{name: "sign annotated", path: "/v1/unknown", method: runtime.Method{ClientSignRequired: true}, wantCode: codes.InvalidArgument},
+		{name: "login annotated", path: "/v1/unknown", method: runtime.Method{LoginRequired: true}, wantCode: codes.Unauthenticated},
+		{name: "login overridden", path: "/v1/public", method: runtime.Method{LoginRequired: true}, wantCode: codes.OK},
+		{name: "logged in", path: "/v1/unknown", method: runtime.Method{LoginRequired: true}, headers: map[string]string{Authorization: "Bearer t"}, wantCode: codes.OK},
+	} {
+		store.strict = spec.strict
+		gh := &gatewayHook{apis: store}
+		m := spec.method
+		m.HttpMethod, m.Path = "GET", spec.path
+		r := httptest.NewRequest("GET", spec.path, nil)
+		for k, v := range spec.headers {
+			r.Header.Set(k, v)
+		}
+
+		err := gh.apiPolicy(context.Background(), r, svc, &m)
+		if got := status.Code(err); got != spec.wantCode {
+			t.Errorf("%s: apiPolicy() = %v; want %v", spec.name, err, spec.wantCode)
+		}
+	}
+}
+
+func TestApiPolicyRateLimit(t *testing.T) {
+	gh := &gatewayHook{apis: &fakeApiConfigStore{apis: map[string]*apiconfig.Api{
+		"/v1/limited": {RateLimit: 10, RateLimitBurst: 20},
+	}}}
+	m := &runtime.Method{HttpMethod: "GET", Path: "/v1/limited", RateLimit: 1, RateLimitBurst: 2}
+	if rate, burst := gh.rateLimit(m); rate != 10 || burst != 20 {
+		t.Errorf("rateLimit() = %g, %d; want 10, 20", rate, burst)
+	}
+	m.Path = "/v1/other"
+	if rate, burst := gh.rateLimit(m); rate != 1 || burst != 2 {
+		t.Errorf("rateLimit() = %g, %d; want 1, 2", rate, burst)
+	}
+}
+
+func TestApiPolicyNoChecker(t *testing.T) {
+	SetSignVerifier(nil)
+	SetAuthenticator(nil)
+
+	gh := &gatewayHook{}
+	svc := &runtime.Service{Name: "EchoService"}
+	for _, spec := range []struct {
+		name   string
+		method runtime.Method
+
+		wantCode codes.Code
+	}{
+		{name: "public", wantCode: codes.OK},
+		{name: "sign required", method: runtime.Method{ClientSignRequired: true}, wantCode: codes.InvalidArgument},
+		{name: "login required", method: runtime.Method{LoginRequired: true}, wantCode: codes.Unauthenticated},
+	} {
+		m := spec.method
+		m.HttpMethod, m.Path = "GET", "/v1/echo"
+		r := httptest.NewRequest("GET", "/v1/echo", nil)
+		r.Header.Set(Authorization, "Bearer t")
+
+		err := gh.apiPolicy(context.Background(), r, svc, &m)
+		if got := status.Code(err); got != spec.wantCode {
+			t.Errorf("%s: apiPolicy() = %v; want %v", spec.name, err, spec.wantCode)
+		}
+	}
+}
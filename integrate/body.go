@@ -0,0 +1,134 @@
+package integrate
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+
+	fpb "github.com/binchencoder/gateway-proto/frontend"
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/integrate/metrics"
+	"github.com/binchencoder/janus-gateway/util"
+	"github.com/binchencoder/letsgo/trace"
+)
+
+var (
+	maxBodyBytes        = flag.Int64("max-body-bytes", 4<<20, "The max bytes of request bodies, overridden by the max_body_bytes option of API methods. Zero means no limit.")
+	maxRequestBodyBytes = flag.Int64("max-request-body-bytes", 32<<20, "The max bytes of request bodies read by the limits middleware before routing, which caps the max_body_bytes option of API methods too. Zero means no limit.")
+)
+
+// bodyLimit returns the max body bytes of the API method, or zero if the body
+// is not limited.
+func bodyLimit(m *runtime.Method) int64 {
+	if m.MaxBodyBytes > 0 {
+		return m.MaxBodyBytes
+	}
+	return *maxBodyBytes
+}
+
+// limitBody rejects the request if its Content-Length exceeds the body limit
+// of the API method, otherwise limits the bytes read from its body. The gRPC
+// and gRPC-Web calls, whose messages are limited by the runtime, are not
+// limited since their bodies carry the whole stream.
+func limitBody(ctx context.Context, w http.ResponseWriter, r *http.Request, svc *runtime.Service, m *runtime.Method) error {
+	limit := bodyLimit(m)
+	if limit <= 0 || r.Body == nil || r.Body == http.NoBody || runtime.IsGRPCRequest(r) || runtime.IsGRPCWebRequest(r) {
+		return nil
+	}
+	tooLarge := func() error {
+		return bodyTooLarge(ctx, w, svc, m, limit)
+	}
+	if r.ContentLength > limit {
+		return tooLarge()
+	}
+	r.Body = &maxBytesReader{ReadCloser: r.Body, n: limit, tooLarge: tooLarge}
+	return nil
+}
+
+// bodyTooLarge处理请求body超出限制情况.
+func bodyTooLarge(ctx context.Context, w http.ResponseWriter, svc *runtime.Service, m *runtime.Method, limit int64) error {
+	util.Logf(util.LimitLogger, util.LimitFormat, trace.GetTraceIdOrEmpty(ctx), "body", svc.Spec.GetServiceName(), m.HttpMethod, m.Path,
+		fmt.Sprintf("max:%d", limit))
+	metrics.ErrCount("body-too-large")
+
+	// The rest of the body is not read, close the connection.
+	w.Header().Set("Connection", "close")
+	return &runtime.HTTPStatusError{
+		HTTPStatus: http.StatusRequestEntityTooLarge,
+		Err:        grpcError(codes.InvalidArgument, fpb.ErrorCode_BAD_REQUEST, []string{fmt.Sprintf("Request body larger than %d bytes.", limit)}),
+	}
+}
+
+// maxBytesReader is like http.MaxBytesReader, but returns the error of
+// tooLarge once more than n bytes are read.
+type maxBytesReader struct {
+	io.ReadCloser
+	n        int64 // remaining bytes
+	tooLarge func() error
+	err      error
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// Read one more byte to tell whether the body exceeds the limit.
+	if int64(len(p)) > r.n+1 {
+		p = p[:r.n+1]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if int64(n) <= r.n {
+		r.n -= int64(n)
+		r.err = err
+		return n, err
+	}
+	n = int(r.n)
+	r.n = 0
+	r.err = r.tooLarge()
+	return n, r.err
+}
+
+// LimitsMiddleware limits the request bodies before they are decompressed and
+// routed, and rejects the larger ones with 413. The bodies are limited again
+// by the API methods after routing. The gRPC and gRPC-Web calls, whose
+// messages are limited by the runtime, are passed through.
+type LimitsMiddleware struct {
+	Handler http.Handler
+	// MaxBodyBytes is the max size in bytes of a request body.
+	MaxBodyBytes int64
+	// Mux writes the error responses with its error handler.
+	Mux *runtime.ServeMux
+}
+
+func (m *LimitsMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.MaxBodyBytes <= 0 || r.Body == nil || r.Body == http.NoBody || runtime.IsGRPCRequest(r) || runtime.IsGRPCWebRequest(r) {
+		m.Handler.ServeHTTP(w, r)
+		return
+	}
+	tooLarge := func() error {
+		util.Logf(util.LimitLogger, util.LimitFormat, r.Header.Get(XRequestId), "body", "", r.Method, r.URL.Path,
+			fmt.Sprintf("max:%d", m.MaxBodyBytes))
+		metrics.ErrCount("body-too-large")
+
+		// The rest of the body is not read, close the connection.
+		w.Header().Set("Connection", "close")
+		return &runtime.HTTPStatusError{
+			HTTPStatus: http.StatusRequestEntityTooLarge,
+			Err:        grpcError(codes.InvalidArgument, fpb.ErrorCode_BAD_REQUEST, []string{fmt.Sprintf("Request body larger than %d bytes.", m.MaxBodyBytes)}),
+		}
+	}
+	if r.ContentLength > m.MaxBodyBytes {
+		_, outboundMarshaler := runtime.MarshalerForRequest(m.Mux, r)
+		runtime.HTTPError(r.Context(), m.Mux, outboundMarshaler, w, r, tooLarge())
+		return
+	}
+	r.Body = &maxBytesReader{ReadCloser: r.Body, n: m.MaxBodyBytes, tooLarge: tooLarge}
+	m.Handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,178 @@
+package integrate
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+)
+
+func TestLimitBody(t *testing.T) {
+	defer func(v int64) { *maxBodyBytes = v }(*maxBodyBytes)
+	*maxBodyBytes = 8
+
+	svc := &runtime.Service{Name: "EchoService"}
+	for _, spec := range []struct {
+		name          string
+		body          string
+		contentLength int64
+		maxBodyBytes  int64
+
+		wantRejected bool
+		wantTooLarge bool
+	}{
+		{name: "within limit", body: "12345678", contentLength: 8},
+		{name: "content length too large", body: "123456789", contentLength: 9, wantRejected: true},
+		{name: "chunked within limit", body: "1234", contentLength: -1},
+		{name: "chunked too large", body: "123456789", contentLength: -1, wantTooLarge: true},
+		{name: "method override", body: "123456789", contentLength: 9, maxBodyBytes: 16},
+		{name: "method override too large", body: strings.Repeat("x", 17), contentLength: -1, maxBodyBytes: 16, wantTooLarge: true},
+	} {
+		m := &runtime.Method{HttpMethod: "POST", Path: "/v1/echo", MaxBodyBytes: spec.maxBodyBytes}
+		r := httptest.NewRequest("POST", "/v1/echo", strings.NewReader(spec.body))
+		r.ContentLength = spec.contentLength
+		w := httptest.NewRecorder()
+
+		err := limitBody(context.Background(), w, r, svc, m)
+		if spec.wantRejected {
+			checkBodyTooLarge(t, spec.name, err)
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: limitBody() failed with %v", spec.name, err)
+			continue
+		}
+
+		b, err := ioutil.ReadAll(r.Body)
+		if spec.wantTooLarge {
+			checkBodyTooLarge(t, spec.name, err)
+			continue
+		}
+		if err != nil || string(b) != spec.body {
+			t.Errorf("%s: read body = %q, %v; want %q, nil", spec.name, b, err, spec.body)
+		}
+	}
+}
+
+func checkBodyTooLarge(t *testing.T, name string, err error) {
+	var se *runtime.HTTPStatusError
+	if !errors.As(runtime.RequestBodyError(err), &se) || se.HTTPStatus != http.StatusRequestEntityTooLarge {
+		t.Errorf("%s: err = %v; want %d", name, err, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestLimitBodyDisabled(t *testing.T) {
+	defer func(v int64) { *maxBodyBytes = v }(*maxBodyBytes)
+	*maxBodyBytes = 0
+
+	body := strings.Repeat("x", 1<<10)
+	r := httptest.NewRequest("POST", "/v1/echo", strings.NewReader(body))
+	if err := limitBody(context.Background(), httptest.NewRecorder(), r, &runtime.Service{}, &runtime.Method{}); err != nil {
+		t.Fatalf("limitBody() failed with %v", err)
+	}
+	if b, err := ioutil.ReadAll(r.Body); err != nil || len(b) != len(body) {
+		t.Errorf("read %d bytes, %v; want %d bytes", len(b), err, len(body))
+	}
+}
+
+func TestLimitBodyGRPC(t *testing.T) {
+	defer func(v int64) { *maxBodyBytes = v }(*maxBodyBytes)
+	*maxBodyBytes = 8
+
+	body := strings.Repeat("x", 1<<10)
+	for _, spec := range []struct {
+		name        string
+		protoMajor  int
+		contentType string
+	}{
+		{name: "grpc", protoMajor: 2, contentType: "application/grpc"},
+		{name: "grpc-web", protoMajor: 1, contentType: "application/grpc-web+proto"},
+	} {
+		r := httptest.NewRequest("POST", "/pkg.EchoService/Chat", strings.NewReader(body))
+		r.ProtoMajor = spec.protoMajor
+		r.Header.Set("Content-Type", spec.contentType)
+		if err := limitBody(context.Background(), httptest.NewRecorder(), r, &runtime.Service{}, &runtime.Method{}); err != nil {
+			t.Errorf("%s: limitBody() failed with %v", spec.name, err)
+			continue
+		}
+		if b, err := ioutil.ReadAll(r.Body); err != nil || len(b) != len(body) {
+			t.Errorf("%s: read %d bytes, %v; want %d bytes", spec.name, len(b), err, len(body))
+		}
+	}
+}
+
+func TestLimitsMiddleware(t *testing.T) {
+	for _, spec := range []struct {
+		name          string
+		body          string
+		contentLength int64
+		header        map[string]string
+
+		wantStatus   int
+		wantTooLarge bool
+	}{
+		{name: "within limit", body: "12345678", contentLength: 8, wantStatus: http.StatusOK},
+		{name: "content length too large", body: "123456789", contentLength: 9, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "chunked within limit", body: "1234", contentLength: -1, wantStatus: http.StatusOK},
+		{name: "chunked too large", body: "123456789", contentLength: -1, wantStatus: http.StatusOK, wantTooLarge: true},
+		{name: "grpc-web", body: "123456789", contentLength: 9, header: map[string]string{"Content-Type": "application/grpc-web+proto"}, wantStatus: http.StatusOK},
+	} {
+		var readErr error
+		h := &LimitsMiddleware{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, readErr = ioutil.ReadAll(r.Body)
+			}),
+			MaxBodyBytes: 8,
+			Mux:          runtime.NewServeMux(),
+		}
+		r := httptest.NewRequest("POST", "/v1/echo", strings.NewReader(spec.body))
+		r.ContentLength = spec.contentLength
+		for k, v := range spec.header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != spec.wantStatus {
+			t.Errorf("%s: status = %d; want %d", spec.name, w.Code, spec.wantStatus)
+		}
+		if w.Code != http.StatusOK && !strings.Contains(w.Body.String(), `"code":100008`) {
+			t.Errorf("%s: body = %s; want the BAD_REQUEST error", spec.name, w.Body)
+		}
+		if spec.wantTooLarge {
+			checkBodyTooLarge(t, spec.name, readErr)
+		} else if readErr != nil {
+			t.Errorf("%s: read body failed with %v", spec.name, readErr)
+		}
+	}
+}
+
+func TestLimitsMiddlewareCompressed(t *testing.T) {
+	mux := runtime.NewServeMux()
+	data := gzipData(t, strings.Repeat("x", 1<<10))
+	h := &LimitsMiddleware{
+		Handler: &DecompressMiddleware{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("the handler is called with a compressed body over the limit")
+			}),
+			MaxSize: 1 << 20,
+			Mux:     mux,
+		},
+		MaxBodyBytes: int64(len(data)) - 1,
+		Mux:          mux,
+	}
+	r := httptest.NewRequest("POST", "/v1/echo", bytes.NewReader(data))
+	r.ContentLength = -1
+	r.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d; want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
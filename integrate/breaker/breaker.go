@@ -0,0 +1,219 @@
+// Package breaker implements circuit breakers for the upstream services of
+// the gateway.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrOpen is returned when the circuit is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// StateClosed lets all requests through.
+	StateClosed State = iota
+	// StateOpen rejects all requests.
+	StateOpen
+	// StateHalfOpen lets a limited number of probe requests through.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Options configures a Breaker.
+type Options struct {
+	// ConsecutiveFailures opens the circuit after that many failures in a
+	// row. Zero disables the threshold.
+	ConsecutiveFailures int `yaml:"consecutive_failures"`
+	// ErrorRate opens the circuit when the ratio of failures within Window
+	// reaches it, once at least MinRequests requests have been observed.
+	// Zero disables the threshold.
+	ErrorRate   float64       `yaml:"error_rate"`
+	MinRequests int           `yaml:"min_requests"`
+	Window      time.Duration `yaml:"window"`
+	// OpenTimeout is how long the circuit stays open before half-opening.
+	OpenTimeout time.Duration `yaml:"open_timeout"`
+	// HalfOpenProbes is the number of probe requests let through in half-open
+	// state. The circuit closes when all of them succeed.
+	HalfOpenProbes int `yaml:"half_open_probes"`
+}
+
+// Enabled returns whether any threshold is configured.
+func (o Options) Enabled() bool {
+	return o.ConsecutiveFailures > 0 || o.ErrorRate > 0
+}
+
+// Breaker is a circuit breaker.
+type Breaker struct {
+	name     string
+	opts     Options
+	onChange func(name string, from, to State)
+	now      func() time.Time
+
+	mu          sync.Mutex
+	state       State
+	openedAt    time.Time
+	windowStart time.Time
+	requests    int
+	failures    int
+	consecutive int
+	probes      int
+	successes   int
+}
+
+// NewBreaker returns a new closed Breaker. onChange, if not nil, is called on
+// every state transition with the lock held, so it must not call back into the
+// breaker.
+func NewBreaker(name string, opts Options, onChange func(name string, from, to State)) *Breaker {
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Second
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = 5 * time.Second
+	}
+	if opts.HalfOpenProbes <= 0 {
+		opts.HalfOpenProbes = 1
+	}
+	return &Breaker{
+		name:     name,
+		opts:     opts,
+		onChange: onChange,
+		now:      time.Now,
+	}
+}
+
+// Name returns the name of the breaker.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire(b.now())
+	return b.state
+}
+
+// Allow returns ErrOpen if the request must be short-circuited. Otherwise the
+// returned function must be called with the result of the request.
+func (b *Breaker) Allow() (func(error), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.expire(now)
+	switch b.state {
+	case StateOpen:
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.probes >= b.opts.HalfOpenProbes {
+			return nil, ErrOpen
+		}
+		b.probes++
+	}
+	state := b.state
+	return func(err error) {
+		b.done(state, IsFailure(err))
+	}, nil
+}
+
+// done records the result of a request admitted in the given state.
+func (b *Breaker) done(admitted State, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if admitted == StateHalfOpen {
+		if b.state != StateHalfOpen {
+			return
+		}
+		if failed {
+			b.transit(StateOpen, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.opts.HalfOpenProbes {
+			b.transit(StateClosed, now)
+		}
+		return
+	}
+	if b.state != StateClosed {
+		return
+	}
+
+	if now.Sub(b.windowStart) >= b.opts.Window {
+		b.windowStart, b.requests, b.failures = now, 0, 0
+	}
+	b.requests++
+	if !failed {
+		b.consecutive = 0
+		return
+	}
+	b.failures++
+	b.consecutive++
+
+	if b.opts.ConsecutiveFailures > 0 && b.consecutive >= b.opts.ConsecutiveFailures {
+		b.transit(StateOpen, now)
+		return
+	}
+	if b.opts.ErrorRate > 0 && b.requests >= b.opts.MinRequests &&
+		float64(b.failures)/float64(b.requests) >= b.opts.ErrorRate {
+		b.transit(StateOpen, now)
+	}
+}
+
+// expire half-opens the circuit when the open timeout has elapsed.
+func (b *Breaker) expire(now time.Time) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.opts.OpenTimeout {
+		b.transit(StateHalfOpen, now)
+	}
+}
+
+func (b *Breaker) transit(to State, now time.Time) {
+	from := b.state
+	b.state = to
+	b.windowStart, b.requests, b.failures, b.consecutive = now, 0, 0, 0
+	b.probes, b.successes = 0, 0
+	if to == StateOpen {
+		b.openedAt = now
+	}
+	if b.onChange != nil {
+		b.onChange(b.name, from, to)
+	}
+}
+
+// IsFailure returns whether the error of a request counts as a failure of the
+// backend, that is codes.Unavailable or codes.DeadlineExceeded.
+func IsFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
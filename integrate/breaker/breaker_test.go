@@ -0,0 +1,138 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errUnavailable = status.Error(codes.Unavailable, "unavailable")
+	errNotFound    = status.Error(codes.NotFound, "not found")
+)
+
+type transition struct {
+	from, to State
+}
+
+func newTestBreaker(opts Options) (*Breaker, *time.Time, *[]transition) {
+	now := time.Unix(1000, 0)
+	trans := []transition{}
+	b := NewBreaker("test", opts, func(name string, from, to State) {
+		trans = append(trans, transition{from, to})
+	})
+	b.now = func() time.Time { return now }
+	return b, &now, &trans
+}
+
+func call(t *testing.T, b *Breaker, err error) {
+	done, aerr := b.Allow()
+	if aerr != nil {
+		t.Fatalf("Allow() failed with %v", aerr)
+	}
+	done(err)
+}
+
+func TestIsFailure(t *testing.T) {
+	for _, spec := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errUnavailable, true},
+		{status.Error(codes.DeadlineExceeded, "timeout"), true},
+		{errNotFound, false},
+		{errors.New("other"), false},
+	} {
+		if got := IsFailure(spec.err); got != spec.want {
+			t.Errorf("IsFailure(%v) = %t; want %t", spec.err, got, spec.want)
+		}
+	}
+}
+
+func TestConsecutiveFailures(t *testing.T) {
+	b, now, trans := newTestBreaker(Options{ConsecutiveFailures: 3, OpenTimeout: time.Second, HalfOpenProbes: 2})
+
+	call(t, b, errUnavailable)
+	call(t, b, errUnavailable)
+	call(t, b, nil)
+	call(t, b, errNotFound)
+	call(t, b, errUnavailable)
+	call(t, b, errUnavailable)
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("State() = %v; want %v", got, StateClosed)
+	}
+	call(t, b, errUnavailable)
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("State() = %v; want %v", got, StateOpen)
+	}
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Errorf("Allow() = %v; want %v", err, ErrOpen)
+	}
+
+	*now = now.Add(time.Second)
+	p1, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() probe #1 failed with %v", err)
+	}
+	p2, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() probe #2 failed with %v", err)
+	}
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Errorf("Allow() probe #3 = %v; want %v", err, ErrOpen)
+	}
+	p1(nil)
+	p2(nil)
+	if got := b.State(); got != StateClosed {
+		t.Errorf("State() = %v; want %v", got, StateClosed)
+	}
+
+	want := []transition{{StateClosed, StateOpen}, {StateOpen, StateHalfOpen}, {StateHalfOpen, StateClosed}}
+	if len(*trans) != len(want) {
+		t.Fatalf("transitions = %v; want %v", *trans, want)
+	}
+	for i := range want {
+		if (*trans)[i] != want[i] {
+			t.Errorf("transitions[%d] = %v; want %v", i, (*trans)[i], want[i])
+		}
+	}
+}
+
+func TestHalfOpenProbeFailure(t *testing.T) {
+	b, now, _ := newTestBreaker(Options{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+
+	call(t, b, errUnavailable)
+	*now = now.Add(time.Second)
+	call(t, b, errUnavailable)
+	if got := b.State(); got != StateOpen {
+		t.Errorf("State() = %v; want %v", got, StateOpen)
+	}
+}
+
+func TestErrorRate(t *testing.T) {
+	b, now, _ := newTestBreaker(Options{ErrorRate: 0.5, MinRequests: 4, Window: time.Minute})
+
+	call(t, b, errUnavailable)
+	call(t, b, errUnavailable)
+	call(t, b, errUnavailable)
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("State() below min requests = %v; want %v", got, StateClosed)
+	}
+
+	// A new window starts over.
+	*now = now.Add(time.Minute)
+	call(t, b, nil)
+	call(t, b, nil)
+	call(t, b, errUnavailable)
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("State() = %v; want %v", got, StateClosed)
+	}
+	call(t, b, errUnavailable)
+	if got := b.State(); got != StateOpen {
+		t.Errorf("State() = %v; want %v", got, StateOpen)
+	}
+}
@@ -0,0 +1,48 @@
+package breaker
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// GroupOptions configures the circuit breakers of a service group.
+type GroupOptions struct {
+	Options `yaml:",inline"`
+	// PerMethod enables a breaker per method instead of one breaker for the
+	// whole service group.
+	PerMethod bool `yaml:"per_method"`
+}
+
+// Config is the circuit breaker configuration of the service groups.
+type Config struct {
+	// Default applies to the service groups not listed in Groups. Without
+	// any threshold, the service groups have no circuit breaker.
+	Default GroupOptions `yaml:"default"`
+	// Groups maps service names to their options.
+	Groups map[string]GroupOptions `yaml:"groups"`
+}
+
+// LoadConfig reads a YAML circuit breaker configuration from the given file.
+func LoadConfig(file string) (*Config, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	conf := Config{}
+	if err := yaml.UnmarshalStrict(b, &conf); err != nil {
+		return nil, fmt.Errorf("parsing circuit breaker config %s: %v", file, err)
+	}
+	return &conf, nil
+}
+
+// Options returns the options of the given service, and false if it has no
+// circuit breaker.
+func (c *Config) Options(serviceName string) (GroupOptions, bool) {
+	opts, ok := c.Groups[serviceName]
+	if !ok {
+		opts = c.Default
+	}
+	return opts, opts.Enabled()
+}
@@ -0,0 +1,102 @@
+package integrate
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+	gr "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	fpb "github.com/binchencoder/gateway-proto/frontend"
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/integrate/ipfilter"
+	"github.com/binchencoder/janus-gateway/util"
+	"github.com/binchencoder/letsgo/trace"
+)
+
+var (
+	trustedProxies = flag.String("trusted-proxies", "", "Comma separated CIDRs of the proxies whose X-Forwarded-For and Forwarded headers are trusted to resolve the client IP.")
+	ipAccessConfig = flag.String("ip-access-config", "", "The YAML file of the IP allow and deny lists of API methods.")
+)
+
+// newClientIPResolver returns the client IP resolver trusting the proxies
+// configured by flag --trusted-proxies.
+func newClientIPResolver() (*ipfilter.Resolver, error) {
+	return ipfilter.NewResolver(splitFlag(*trustedProxies))
+}
+
+// newIPFilter returns the IP filter with the rules configured by flag
+// --ip-access-config.
+func newIPFilter() (*ipfilter.Filter, error) {
+	rules := []*ipfilter.Rule{}
+	if *ipAccessConfig != "" {
+		conf, err := ipfilter.LoadConfig(*ipAccessConfig)
+		if err != nil {
+			return nil, err
+		}
+		rules = conf.Rules
+	}
+	f, err := ipfilter.NewFilter(rules)
+	if err != nil {
+		return nil, err
+	}
+	util.Logf(util.ConfigLogger, "Loaded %d IP access rules, trusted proxies: %s.", f.Len(), *trustedProxies)
+	return f, nil
+}
+
+// resolveClientIP sets header X-Real-Ip of the request to the resolved
+// client IP, which is then used by the logs, the rate limits and the
+// backend services. Header X-Forwarded-For is replaced by the resolved client
+// IP, or removed if the client is the peer, so that the x-forwarded-for
+// metadata the backend services receive from runtime.AnnotateContext, which
+// appends the peer, holds no hop supplied by the client.
+func (gh *gatewayHook) resolveClientIP(r *http.Request) {
+	ip := gh.ips.ClientIP(r)
+	r.Header.Del(XForwardedFor)
+	if ip == nil {
+		return
+	}
+	r.Header.Set(XRealIp, ip.String())
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err != nil || !net.ParseIP(host).Equal(ip) {
+		r.Header.Set(XForwardedFor, ip.String())
+	}
+}
+
+// clientIP returns the client IP of the request resolved by the gateway.
+func clientIP(r *http.Request) string {
+	return r.Header.Get(XRealIp)
+}
+
+// ipAccess checks the client IP against the IP access rules of the API
+// method.
+func (gh *gatewayHook) ipAccess(ctx context.Context, r *http.Request, svc *runtime.Service, m *runtime.Method) error {
+	ok, rule := gh.ipFilter.Allow(m.HttpMethod, m.Path, net.ParseIP(clientIP(r)))
+	if ok {
+		return nil
+	}
+	xt, _ := ctx.Value(RequestReceivedTime).(time.Time)
+	_, err := ipDenied(ctx, svc, m, getClientFromHeader(r.Header), trace.GetTraceIdOrEmpty(ctx), xt, rule, clientIP(r))
+	return err
+}
+
+// ipDenied处理客户端IP被拒绝情况.
+func ipDenied(ctx context.Context, svc *runtime.Service, m *runtime.Method, clt, tid string, xt time.Time, rule *ipfilter.Rule, ip string) (context.Context, error) {
+	// prometheus metrics.
+	ms := addMetrics(ctx, svc, m, codes.PermissionDenied, xt, clt)
+
+	// record limit logs.
+	util.Logf(util.LimitLogger, util.LimitFormat, tid, rule.Name, svc.Spec.GetServiceName(), m.HttpMethod, m.Path,
+		fmt.Sprintf("client:%s,ip:%s", clt, ip))
+	// record stat logs.
+	util.Logf(util.StatLogger, util.StatFormat, tid, svc.Spec.GetServiceName(), m.HttpMethod, m.Path, clt, "N", codes.PermissionDenied, ms)
+
+	ger := grpcError(codes.PermissionDenied, fpb.ErrorCode_NORIGHT_ERROR, []string{"IP address not allowed."})
+	// record rest logs.
+	util.Logf(util.RestLogger, util.ResponseRestFormat, tid, codes.PermissionDenied, gr.ErrorDesc(ger))
+
+	return ctx, ger
+}
@@ -0,0 +1,96 @@
+package integrate
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/integrate/ipfilter"
+)
+
+func newTestIPHook(t *testing.T) *gatewayHook {
+	ips, err := ipfilter.NewResolver([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("NewResolver() failed with %v", err)
+	}
+	f, err := ipfilter.NewFilter([]*ipfilter.Rule{
+		{Name: "admin", Path: "/v1/admin", Allow: []string{"192.168.0.0/16"}},
+	})
+	if err != nil {
+		t.Fatalf("NewFilter() failed with %v", err)
+	}
+	return &gatewayHook{
+		headers:  newHeaderPolicy([]string{XRealIp}, defaultForwardHeaders),
+		ips:      ips,
+		ipFilter: f,
+	}
+}
+
+func TestResolveClientIP(t *testing.T) {
+	gh := newTestIPHook(t)
+	for _, spec := range []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		xRealIp    string
+		want       string
+		wantXFF    string
+	}{
+		{"direct", "203.0.113.1:1234", "", "", "203.0.113.1", ""},
+		{"spoofed x-forwarded-for", "203.0.113.1:1234", "192.168.0.1", "", "203.0.113.1", ""},
+		{"spoofed x-real-ip", "203.0.113.1:1234", "", "192.168.0.1", "203.0.113.1", ""},
+		{"trusted proxy", "10.0.0.1:1234", "192.168.0.1", "", "192.168.0.1", "192.168.0.1"},
+		{"spoofed behind trusted proxy", "10.0.0.1:1234", "172.16.0.1, 192.168.0.1", "", "192.168.0.1", "192.168.0.1"},
+	} {
+		r := httptest.NewRequest("GET", "/v1/admin", nil)
+		r.RemoteAddr = spec.remoteAddr
+		if spec.xff != "" {
+			r.Header.Set(XForwardedFor, spec.xff)
+		}
+		if spec.xRealIp != "" {
+			r.Header.Set(XRealIp, spec.xRealIp)
+		}
+		gh.headers.strip(r.Header)
+		gh.resolveClientIP(r)
+
+		if got := clientIP(r); got != spec.want {
+			t.Errorf("%s: clientIP() = %q; want %q", spec.name, got, spec.want)
+		}
+		if got := gh.headers.outgoingMD(r.Header).Get(XRealIp); len(got) != 1 || got[0] != spec.want {
+			t.Errorf("%s: metadata %s = %v; want [%s]", spec.name, XRealIp, got, spec.want)
+		}
+		if got := r.Header.Get(XForwardedFor); got != spec.wantXFF {
+			t.Errorf("%s: header %s = %q; want %q", spec.name, XForwardedFor, got, spec.wantXFF)
+		}
+	}
+}
+
+func TestIPAccess(t *testing.T) {
+	gh := newTestIPHook(t)
+	svc := &runtime.Service{Name: "EchoService"}
+	for _, spec := range []struct {
+		path       string
+		remoteAddr string
+		xff        string
+		wantCode   codes.Code
+	}{
+		{"/v1/admin", "10.0.0.1:1234", "192.168.0.1", codes.OK},
+		{"/v1/admin", "203.0.113.1:1234", "192.168.0.1", codes.PermissionDenied},
+		{"/v1/echo", "203.0.113.1:1234", "", codes.OK},
+	} {
+		r := httptest.NewRequest("GET", spec.path, nil)
+		r.RemoteAddr = spec.remoteAddr
+		r.Header.Set(XForwardedFor, spec.xff)
+		gh.resolveClientIP(r)
+
+		m := &runtime.Method{HttpMethod: "GET", Path: spec.path}
+		err := gh.ipAccess(context.Background(), r, svc, m)
+		if got := status.Code(err); got != spec.wantCode {
+			t.Errorf("ipAccess(%s from %s, %s) = %v; want %v", spec.path, spec.remoteAddr, spec.xff, got, spec.wantCode)
+		}
+	}
+}
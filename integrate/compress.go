@@ -0,0 +1,365 @@
+package integrate
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/util"
+)
+
+var (
+	compressEncodings = flag.String("compress-encodings", "br,zstd,gzip", "The response content codings in the order of preference, the ones not registered are skipped.")
+	brLevel           = flag.Int("br-level", 5, "The brotli compression level, from 0 to 11.")
+	brMinSize         = flag.Int("br-min-size", 1024, "The min response size in bytes to compress with brotli.")
+	gzipLevel         = flag.Int("gzip-level", gzip.DefaultCompression, "The gzip compression level, from -3 to 9.")
+	gzipMinSize       = flag.Int("gzip-min-size", 1024, "The min response size in bytes to compress with gzip.")
+	zstdLevel         = flag.Int("zstd-level", 3, "The zstd compression level, from 1 to 22.")
+	zstdMinSize       = flag.Int("zstd-min-size", 1024, "The min response size in bytes to compress with zstd.")
+)
+
+// incompressibleTypes lists the content types, or their prefixes ending with
+// "/", which are already compressed.
+var incompressibleTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/grpc",
+}
+
+// compressible returns whether a response of the given content type is worth
+// compressing.
+func compressible(contentType string) bool {
+	ct := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	if ct == "image/svg+xml" {
+		return true
+	}
+	for _, t := range incompressibleTypes {
+		if strings.HasPrefix(ct, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Encoder compresses the response body of a content coding.
+type Encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Encoding is a content coding of the responses.
+type Encoding struct {
+	// Name is the token of the coding in Accept-Encoding and
+	// Content-Encoding.
+	Name string
+	// MinSize is the size in bytes under which the responses are not
+	// compressed.
+	MinSize int
+	// New returns a new encoder. The encoders are reused by Reset.
+	New func() Encoder
+
+	pool sync.Pool
+}
+
+func (e *Encoding) get(w io.Writer) Encoder {
+	enc, ok := e.pool.Get().(Encoder)
+	if !ok {
+		enc = e.New()
+	}
+	enc.Reset(w)
+	return enc
+}
+
+func (e *Encoding) put(enc Encoder) {
+	enc.Reset(nil)
+	e.pool.Put(enc)
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]*Encoding{}
+)
+
+// RegisterEncoding registers a content coding, which is used if listed by
+// flag --compress-encodings. It replaces the built-in br, zstd or gzip coding
+// of the same name. It should be called before NewHttpMux.
+func RegisterEncoding(e *Encoding) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	encodings[e.Name] = e
+}
+
+// builtinEncoding returns the built-in coding of the given name, configured
+// by the flags.
+func builtinEncoding(name string) (*Encoding, error) {
+	switch name {
+	case "br":
+		if *brLevel < brotli.BestSpeed || *brLevel > brotli.BestCompression {
+			return nil, fmt.Errorf("level %d out of range [%d, %d]", *brLevel, brotli.BestSpeed, brotli.BestCompression)
+		}
+		level := *brLevel
+		return &Encoding{
+			Name:    "br",
+			MinSize: *brMinSize,
+			New: func() Encoder {
+				return brotli.NewWriterLevel(nil, level)
+			},
+		}, nil
+	case "gzip":
+		if _, err := gzip.NewWriterLevel(nil, *gzipLevel); err != nil {
+			return nil, err
+		}
+		return &Encoding{
+			Name:    "gzip",
+			MinSize: *gzipMinSize,
+			New: func() Encoder {
+				w, _ := gzip.NewWriterLevel(nil, *gzipLevel)
+				return w
+			},
+		}, nil
+	case "zstd":
+		level := zstd.EncoderLevelFromZstd(*zstdLevel)
+		return &Encoding{
+			Name:    "zstd",
+			MinSize: *zstdMinSize,
+			New: func() Encoder {
+				w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+				return w
+			},
+		}, nil
+	}
+	return nil, nil
+}
+
+// newEncodingsFromFlags returns the content codings listed by flag
+// --compress-encodings, in the order of preference. The codings neither
+// registered nor built in are skipped.
+func newEncodingsFromFlags() ([]*Encoding, error) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	es := []*Encoding{}
+	for _, name := range splitFlag(*compressEncodings) {
+		e, ok := encodings[name]
+		if !ok {
+			var err error
+			if e, err = builtinEncoding(name); err != nil {
+				return nil, fmt.Errorf("invalid %s compression: %v", name, err)
+			}
+		}
+		if e == nil {
+			util.Logf(util.ConfigLogger, "Compression %s is not registered, skipped.", name)
+			continue
+		}
+		es = append(es, e)
+	}
+	return es, nil
+}
+
+// negotiateEncoding returns the coding with the highest q-value in the
+// Accept-Encoding header, the earliest one of the codings on a tie, or nil if
+// the client accepts none of them.
+func negotiateEncoding(acceptEncoding string, es []*Encoding) *Encoding {
+	if acceptEncoding == "" {
+		return nil
+	}
+	qs := map[string]float64{}
+	for _, e := range strings.Split(acceptEncoding, ",") {
+		parts := strings.Split(e, ";")
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range parts[1:] {
+			if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+				v, err := strconv.ParseFloat(p[2:], 64)
+				if err != nil {
+					v = 0
+				}
+				q = v
+			}
+		}
+		qs[name] = q
+	}
+
+	var best *Encoding
+	bestQ := 0.0
+	for _, e := range es {
+		q, ok := qs[e.Name]
+		if !ok {
+			q = qs["*"]
+		}
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+// compressResponseWriter compresses the response on the fly. It buffers the
+// first MinSize bytes of the body to decide whether to compress, and streams
+// the rest.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding *Encoding
+	head     bool
+
+	code        int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         Encoder
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	// Informational responses are sent as is.
+	if code >= 100 && code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.code = code
+	w.wroteHeader = true
+	if code == http.StatusNoContent || code == http.StatusNotModified || w.head {
+		w.decide(false)
+	}
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.encoding.MinSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends the buffered data to the client. If compression has not been
+// decided yet, it is decided with the data so far.
+func (w *compressResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.decide(len(w.buf) >= w.encoding.MinSize)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// close finishes the response.
+func (w *compressResponseWriter) close() error {
+	if !w.wroteHeader {
+		// Nothing was written, let the server send its default response.
+		return nil
+	}
+	if !w.decided {
+		if err := w.decide(len(w.buf) >= w.encoding.MinSize); err != nil {
+			return err
+		}
+	}
+	if w.enc == nil {
+		return nil
+	}
+	err := w.enc.Close()
+	w.encoding.put(w.enc)
+	w.enc = nil
+	return err
+}
+
+// decide writes the header, compressing the response if wanted and possible,
+// and then the buffered data.
+func (w *compressResponseWriter) decide(compress bool) error {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 && h.Get("Transfer-Encoding") == "" {
+		// Detect the type from the plain data, instead of letting the
+		// server detect it from the compressed data.
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if compress && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", w.encoding.Name)
+		h.Del("Content-Length")
+		w.enc = w.encoding.get(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// 压缩处理中间件. It compresses the responses with the content coding
+// negotiated by the Accept-Encoding header, without buffering the whole body.
+type CompressMiddleware struct {
+	Handler http.Handler
+	// Encodings are the available codings in the order of preference.
+	Encodings []*Encoding
+}
+
+func (m *CompressMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The frames of a WebSocket connection and the messages of a gRPC call
+	// are not compressed by the content coding.
+	if runtime.IsWebSocketRequest(r) || runtime.IsGRPCRequest(r) {
+		m.Handler.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	e := negotiateEncoding(r.Header.Get("Accept-Encoding"), m.Encodings)
+	if e == nil {
+		m.Handler.ServeHTTP(w, r)
+		return
+	}
+
+	cw := &compressResponseWriter{
+		ResponseWriter: w,
+		encoding:       e,
+		head:           r.Method == http.MethodHead,
+	}
+	m.Handler.ServeHTTP(cw, r)
+	cw.close()
+}
@@ -0,0 +1,380 @@
+package integrate
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	es := []*Encoding{{Name: "br"}, {Name: "zstd"}, {Name: "gzip"}}
+	for _, spec := range []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"gzip, zstd", "zstd"},
+		{"br;q=0.5, gzip;q=0.8", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"gzip;q=0", ""},
+		{"GZIP", "gzip"},
+		{"*", "br"},
+		{"*;q=0.1, gzip;q=0.5", "gzip"},
+		{"*, br;q=0", "zstd"},
+		{"gzip;q=invalid, zstd;q=0.1", "zstd"},
+	} {
+		got := ""
+		if e := negotiateEncoding(spec.acceptEncoding, es); e != nil {
+			got = e.Name
+		}
+		if got != spec.want {
+			t.Errorf("negotiateEncoding(%q) = %q; want %q", spec.acceptEncoding, got, spec.want)
+		}
+	}
+}
+
+func TestCompressMiddlewareBuiltin(t *testing.T) {
+	for _, spec := range []struct {
+		name   string
+		decode func(r io.Reader) (io.ReadCloser, error)
+	}{
+		{
+			name: "br",
+			decode: func(r io.Reader) (io.ReadCloser, error) {
+				return ioutil.NopCloser(brotli.NewReader(r)), nil
+			},
+		},
+		{
+			name: "zstd",
+			decode: func(r io.Reader) (io.ReadCloser, error) {
+				d, err := zstd.NewReader(r)
+				if err != nil {
+					return nil, err
+				}
+				return d.IOReadCloser(), nil
+			},
+		},
+	} {
+		e, err := builtinEncoding(spec.name)
+		if err != nil {
+			t.Fatalf("builtinEncoding(%s) failed with %v", spec.name, err)
+		}
+		e.MinSize = 1024
+		body := strings.Repeat("in order to test "+spec.name+" compress.", 100)
+		m := &CompressMiddleware{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body))
+			}),
+			Encodings: []*Encoding{e},
+		}
+
+		// Twice for the reused encoder.
+		for i := 0; i < 2; i++ {
+			r := httptest.NewRequest("GET", "/v1/echo", nil)
+			r.Header.Set("Accept-Encoding", "gzip, "+spec.name)
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != spec.name {
+				t.Fatalf("Content-Encoding = %q; want %s", got, spec.name)
+			}
+			d, err := spec.decode(bytes.NewReader(w.Body.Bytes()))
+			if err != nil {
+				t.Fatalf("%s: creating the decoder failed with %v", spec.name, err)
+			}
+			got, err := ioutil.ReadAll(d)
+			d.Close()
+			if err != nil {
+				t.Fatalf("reading %s data failed with %v", spec.name, err)
+			}
+			if string(got) != body {
+				t.Errorf("%s: body = %q; want %q", spec.name, got, body)
+			}
+		}
+	}
+}
+
+func TestBuiltinEncodingLevel(t *testing.T) {
+	defer func(v int) { *brLevel = v }(*brLevel)
+	*brLevel = 12
+	if _, err := builtinEncoding("br"); err == nil {
+		t.Errorf("builtinEncoding(br) with --br-level=12 succeeded; want error")
+	}
+}
+
+// upperEncoder is a fake coding which upper-cases the data.
+type upperEncoder struct {
+	w io.Writer
+}
+
+func (e *upperEncoder) Write(b []byte) (int, error) {
+	return e.w.Write(bytes.ToUpper(b))
+}
+
+func (e *upperEncoder) Flush() error      { return nil }
+func (e *upperEncoder) Close() error      { return nil }
+func (e *upperEncoder) Reset(w io.Writer) { e.w = w }
+
+func TestRegisterEncoding(t *testing.T) {
+	defer func(v string) { *compressEncodings = v }(*compressEncodings)
+	*compressEncodings = "upper, lz4, gzip"
+	RegisterEncoding(&Encoding{
+		Name: "upper",
+		New:  func() Encoder { return &upperEncoder{} },
+	})
+	defer func() {
+		encodingsMu.Lock()
+		delete(encodings, "upper")
+		encodingsMu.Unlock()
+	}()
+
+	es, err := newEncodingsFromFlags()
+	if err != nil {
+		t.Fatalf("newEncodingsFromFlags() failed with %v", err)
+	}
+	names := []string{}
+	for _, e := range es {
+		names = append(names, e.Name)
+	}
+	if got, want := strings.Join(names, ","), "upper,gzip"; got != want {
+		t.Fatalf("encodings = %s; want %s", got, want)
+	}
+
+	m := &CompressMiddleware{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		}),
+		Encodings: es,
+	}
+	r := httptest.NewRequest("GET", "/v1/echo", nil)
+	r.Header.Set("Accept-Encoding", "gzip, upper")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if got := w.Header().Get("Content-Encoding"); got != "upper" {
+		t.Errorf("Content-Encoding = %q; want upper", got)
+	}
+	if got := w.Body.String(); got != "HELLO" {
+		t.Errorf("body = %q; want HELLO", got)
+	}
+}
+
+// newGzipMiddleware returns a CompressMiddleware with only the gzip coding,
+// which compresses the responses from 1024 bytes.
+func newGzipMiddleware(t *testing.T, h http.Handler) *CompressMiddleware {
+	e, err := builtinEncoding("gzip")
+	if err != nil {
+		t.Fatalf("builtinEncoding(gzip) failed with %v", err)
+	}
+	e.MinSize = 1024
+	return &CompressMiddleware{Handler: h, Encodings: []*Encoding{e}}
+}
+
+func TestCompressMiddlewareGzip(t *testing.T) {
+	large := strings.Repeat("in order to test gzip compress.", 100)
+	for _, spec := range []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		header         map[string]string
+		code           int
+		body           string
+
+		wantEncoding string
+		wantType     string
+	}{
+		{
+			name:           "large body",
+			acceptEncoding: "gzip, deflate",
+			header:         map[string]string{"Content-Type": "application/json", "Content-Length": strconv.Itoa(len(large))},
+			body:           large,
+			wantEncoding:   "gzip",
+			wantType:       "application/json",
+		},
+		{
+			name:           "small body",
+			acceptEncoding: "gzip",
+			body:           "small",
+			wantType:       "text/plain; charset=utf-8",
+		},
+		{
+			name:     "gzip not accepted",
+			body:     large,
+			wantType: "text/plain; charset=utf-8",
+		},
+		{
+			name:           "gzip refused by q-value",
+			acceptEncoding: "gzip;q=0, br",
+			body:           large,
+			wantType:       "text/plain; charset=utf-8",
+		},
+		{
+			name:           "detected type of the plain body",
+			acceptEncoding: "gzip",
+			body:           large,
+			wantEncoding:   "gzip",
+			wantType:       "text/plain; charset=utf-8",
+		},
+		{
+			name:           "already encoded",
+			acceptEncoding: "gzip",
+			header:         map[string]string{"Content-Encoding": "br"},
+			body:           large,
+			wantEncoding:   "br",
+			wantType:       "text/plain; charset=utf-8",
+		},
+		{
+			name:           "incompressible type",
+			acceptEncoding: "gzip",
+			header:         map[string]string{"Content-Type": "image/png"},
+			body:           large,
+			wantType:       "image/png",
+		},
+		{
+			name:           "error code",
+			acceptEncoding: "gzip",
+			header:         map[string]string{"Content-Type": "application/json"},
+			code:           http.StatusBadRequest,
+			body:           large,
+			wantEncoding:   "gzip",
+			wantType:       "application/json",
+		},
+		{
+			name:           "head",
+			method:         "HEAD",
+			acceptEncoding: "gzip",
+			header:         map[string]string{"Content-Type": "application/json"},
+			wantType:       "application/json",
+		},
+	} {
+		h := newGzipMiddleware(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for k, v := range spec.header {
+				w.Header().Set(k, v)
+			}
+			if spec.code != 0 {
+				w.WriteHeader(spec.code)
+			}
+			// Write in chunks, as the streaming handlers do.
+			for b := []byte(spec.body); len(b) > 0; {
+				n := 100
+				if n > len(b) {
+					n = len(b)
+				}
+				w.Write(b[:n])
+				b = b[n:]
+			}
+		}))
+		method := spec.method
+		if method == "" {
+			method = "GET"
+		}
+		r := httptest.NewRequest(method, "/v1/echo", nil)
+		if spec.acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", spec.acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		wantCode := spec.code
+		if wantCode == 0 {
+			wantCode = http.StatusOK
+		}
+		if w.Code != wantCode {
+			t.Errorf("%s: code = %d; want %d", spec.name, w.Code, wantCode)
+		}
+		if got := w.Header().Get("Content-Encoding"); got != spec.wantEncoding {
+			t.Errorf("%s: Content-Encoding = %q; want %q", spec.name, got, spec.wantEncoding)
+		}
+		if got := w.Header().Get("Content-Type"); got != spec.wantType {
+			t.Errorf("%s: Content-Type = %q; want %q", spec.name, got, spec.wantType)
+		}
+		if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("%s: Vary = %q; want %q", spec.name, got, "Accept-Encoding")
+		}
+		body := w.Body.String()
+		if spec.wantEncoding == "gzip" {
+			if got := w.Header().Get("Content-Length"); got != "" {
+				t.Errorf("%s: Content-Length = %q; want none", spec.name, got)
+			}
+			body = gunzip(t, w.Body.Bytes())
+		}
+		if body != spec.body {
+			t.Errorf("%s: body = %q; want %q", spec.name, body, spec.body)
+		}
+	}
+}
+
+func TestCompressMiddlewareGzipFlush(t *testing.T) {
+	large := strings.Repeat("in order to test gzip compress.", 100)
+	w := httptest.NewRecorder()
+	h := newGzipMiddleware(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		f, ok := rw.(http.Flusher)
+		if !ok {
+			t.Fatalf("%T is not a http.Flusher", rw)
+		}
+
+		// The first message decides the compression.
+		rw.Write([]byte(large))
+		f.Flush()
+		if !w.Flushed {
+			t.Errorf("Flush() was not passed through")
+		}
+		if got := gunzipPrefix(t, w.Body.Bytes()); got != large {
+			t.Errorf("body after Flush() = %q; want %q", got, large)
+		}
+
+		rw.Write([]byte("next"))
+		f.Flush()
+	}))
+	r := httptest.NewRequest("GET", "/v1/stream", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Content-Encoding = %q; want gzip", got)
+	}
+	if got, want := gunzip(t, w.Body.Bytes()), large+"next"; got != want {
+		t.Errorf("body = %q; want %q", got, want)
+	}
+}
+
+func TestCompressMiddlewareGzipFlushSmall(t *testing.T) {
+	w := httptest.NewRecorder()
+	h := newGzipMiddleware(t, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("small"))
+		rw.(http.Flusher).Flush()
+		if got := w.Body.String(); got != "small" {
+			t.Errorf("body after Flush() = %q; want %q", got, "small")
+		}
+		rw.Write([]byte(strings.Repeat("x", 2048)))
+	}))
+	r := httptest.NewRequest("GET", "/v1/stream", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q; want none", got)
+	}
+}
+
+// gunzipPrefix returns the data decompressed from a gzip stream which is not
+// closed yet.
+func gunzipPrefix(t *testing.T, b []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() failed with %v", err)
+	}
+	d, _ := ioutil.ReadAll(r)
+	return string(d)
+}
@@ -0,0 +1,178 @@
+package concurrency
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrLimitExceeded is returned when the in-flight cap is reached and the
+	// wait queue is full.
+	ErrLimitExceeded = errors.New("concurrency limit exceeded")
+	// ErrQueueTimeout is returned when a queued request is not admitted
+	// within the queue timeout.
+	ErrQueueTimeout = errors.New("concurrency queue timeout")
+)
+
+// Options configures a Limiter.
+type Options struct {
+	// Limit is the maximum number of in-flight requests. In adaptive mode it
+	// is the initial limit.
+	Limit int `yaml:"limit"`
+	// MaxQueue is the maximum number of requests waiting for a slot. Zero
+	// means requests are rejected as soon as the limit is reached.
+	MaxQueue int `yaml:"max_queue"`
+	// QueueTimeout is the maximum time a request waits in the queue.
+	QueueTimeout time.Duration `yaml:"queue_timeout"`
+
+	// Adaptive enables AIMD adjustment of the limit: the limit increases
+	// additively while requests complete within LatencyThreshold, and
+	// decreases multiplicatively by BackoffRatio when they do not.
+	Adaptive         bool          `yaml:"adaptive"`
+	MinLimit         int           `yaml:"min_limit"`
+	MaxLimit         int           `yaml:"max_limit"`
+	LatencyThreshold time.Duration `yaml:"latency_threshold"`
+	BackoffRatio     float64       `yaml:"backoff_ratio"`
+}
+
+// Limiter caps the number of in-flight requests.
+type Limiter struct {
+	opts Options
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	queue    []chan struct{}
+}
+
+// NewLimiter returns a new Limiter with the given options.
+func NewLimiter(opts Options) *Limiter {
+	if opts.Limit <= 0 {
+		opts.Limit = 1
+	}
+	if opts.Adaptive {
+		if opts.MinLimit <= 0 {
+			opts.MinLimit = 1
+		}
+		if opts.MaxLimit < opts.Limit {
+			opts.MaxLimit = opts.Limit * 10
+		}
+		if opts.BackoffRatio <= 0 || opts.BackoffRatio >= 1 {
+			opts.BackoffRatio = 0.9
+		}
+	}
+	return &Limiter{
+		opts:  opts,
+		limit: float64(opts.Limit),
+	}
+}
+
+// Acquire takes a slot for a request, waiting in the queue if needed. On
+// success, the returned function must be called with the latency and error of
+// the request when it completes.
+func (l *Limiter) Acquire(ctx context.Context) (func(time.Duration, error), error) {
+	l.mu.Lock()
+	if l.inflight < l.cap() {
+		l.inflight++
+		l.mu.Unlock()
+		return l.release, nil
+	}
+	if len(l.queue) >= l.opts.MaxQueue {
+		l.mu.Unlock()
+		return nil, ErrLimitExceeded
+	}
+	ch := make(chan struct{})
+	l.queue = append(l.queue, ch)
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if l.opts.QueueTimeout > 0 {
+		t := time.NewTimer(l.opts.QueueTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	var err error
+	select {
+	case <-ch:
+		return l.release, nil
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, c := range l.queue {
+		if c == ch {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return nil, err
+		}
+	}
+	// The slot was granted while giving up, keep it.
+	return l.release, nil
+}
+
+// TryAcquire takes a slot for a request if one is free, without waiting in
+// the queue. On success, the returned function must be called like the one
+// returned by Acquire.
+func (l *Limiter) TryAcquire() (func(time.Duration, error), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inflight >= l.cap() {
+		return nil, false
+	}
+	l.inflight++
+	return l.release, true
+}
+
+// release frees a slot and adjusts the limit in adaptive mode.
+func (l *Limiter) release(latency time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+	if l.opts.Adaptive {
+		l.adjust(latency, err)
+	}
+	for len(l.queue) > 0 && l.inflight < l.cap() {
+		ch := l.queue[0]
+		l.queue = l.queue[1:]
+		l.inflight++
+		close(ch)
+	}
+}
+
+// adjust applies AIMD to the limit. Timeouts and overload errors of the
+// backend count as slow requests.
+func (l *Limiter) adjust(latency time.Duration, err error) {
+	slow := l.opts.LatencyThreshold > 0 && latency > l.opts.LatencyThreshold
+	if c := status.Code(err); err == context.DeadlineExceeded || c == codes.DeadlineExceeded || c == codes.ResourceExhausted {
+		slow = true
+	}
+	if slow {
+		l.limit = math.Max(float64(l.opts.MinLimit), l.limit*l.opts.BackoffRatio)
+		return
+	}
+	l.limit = math.Min(float64(l.opts.MaxLimit), l.limit+1/l.limit)
+}
+
+// cap returns the current limit as the number of slots.
+func (l *Limiter) cap() int {
+	return int(l.limit)
+}
+
+// Stats returns the current limit, the number of in-flight requests and the
+// number of queued requests.
+func (l *Limiter) Stats() (limit, inflight, queued int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cap(), l.inflight, len(l.queue)
+}
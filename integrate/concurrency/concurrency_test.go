@@ -0,0 +1,131 @@
+package concurrency
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimiterRejectsOverLimit(t *testing.T) {
+	l := NewLimiter(Options{Limit: 2})
+	ctx := context.Background()
+
+	r1, err := l.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() #1 failed with %v", err)
+	}
+	if _, err := l.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire() #2 failed with %v", err)
+	}
+	if _, err := l.Acquire(ctx); err != ErrLimitExceeded {
+		t.Errorf("Acquire() #3 = %v; want %v", err, ErrLimitExceeded)
+	}
+	r1(time.Millisecond, nil)
+	if _, err := l.Acquire(ctx); err != nil {
+		t.Errorf("Acquire() after release failed with %v", err)
+	}
+	if limit, inflight, queued := l.Stats(); limit != 2 || inflight != 2 || queued != 0 {
+		t.Errorf("Stats() = %d, %d, %d; want 2, 2, 0", limit, inflight, queued)
+	}
+}
+
+func TestLimiterQueue(t *testing.T) {
+	l := NewLimiter(Options{Limit: 1, MaxQueue: 1, QueueTimeout: time.Second})
+	ctx := context.Background()
+
+	release, err := l.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() failed with %v", err)
+	}
+
+	admitted := make(chan error)
+	go func() {
+		_, err := l.Acquire(ctx)
+		admitted <- err
+	}()
+	for {
+		if _, _, queued := l.Stats(); queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := l.Acquire(ctx); err != ErrLimitExceeded {
+		t.Errorf("Acquire() with full queue = %v; want %v", err, ErrLimitExceeded)
+	}
+
+	release(time.Millisecond, nil)
+	if err := <-admitted; err != nil {
+		t.Errorf("queued Acquire() failed with %v", err)
+	}
+	if _, inflight, queued := l.Stats(); inflight != 1 || queued != 0 {
+		t.Errorf("Stats() = _, %d, %d; want _, 1, 0", inflight, queued)
+	}
+}
+
+func TestLimiterTryAcquire(t *testing.T) {
+	l := NewLimiter(Options{Limit: 1, MaxQueue: 1, QueueTimeout: time.Second})
+	release, ok := l.TryAcquire()
+	if !ok {
+		t.Fatal("TryAcquire() = false; want true")
+	}
+	if _, ok := l.TryAcquire(); ok {
+		t.Error("TryAcquire() over limit = true; want false")
+	}
+	if _, _, queued := l.Stats(); queued != 0 {
+		t.Errorf("queued = %d; want 0", queued)
+	}
+	release(time.Millisecond, nil)
+	if _, ok := l.TryAcquire(); !ok {
+		t.Error("TryAcquire() after release = false; want true")
+	}
+}
+
+func TestLimiterQueueTimeout(t *testing.T) {
+	l := NewLimiter(Options{Limit: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond})
+	ctx := context.Background()
+	if _, err := l.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire() failed with %v", err)
+	}
+	if _, err := l.Acquire(ctx); err != ErrQueueTimeout {
+		t.Errorf("Acquire() = %v; want %v", err, ErrQueueTimeout)
+	}
+	if _, _, queued := l.Stats(); queued != 0 {
+		t.Errorf("queued = %d; want 0", queued)
+	}
+}
+
+func TestLimiterAdaptive(t *testing.T) {
+	l := NewLimiter(Options{
+		Limit:            10,
+		Adaptive:         true,
+		MinLimit:         2,
+		MaxLimit:         11,
+		LatencyThreshold: 100 * time.Millisecond,
+		BackoffRatio:     0.5,
+	})
+	ctx := context.Background()
+
+	release, _ := l.Acquire(ctx)
+	release(time.Second, nil)
+	if limit, _, _ := l.Stats(); limit != 5 {
+		t.Errorf("limit after slow request = %d; want 5", limit)
+	}
+	release, _ = l.Acquire(ctx)
+	release(time.Millisecond, status.Error(codes.DeadlineExceeded, "timeout"))
+	release, _ = l.Acquire(ctx)
+	release(time.Millisecond, nil)
+	if limit, _, _ := l.Stats(); limit != 2 {
+		t.Errorf("limit after deadline exceeded = %d; want 2", limit)
+	}
+
+	for i := 0; i < 200; i++ {
+		release, _ = l.Acquire(ctx)
+		release(time.Millisecond, nil)
+	}
+	if limit, _, _ := l.Stats(); limit != 11 {
+		t.Errorf("limit after fast requests = %d; want 11", limit)
+	}
+}
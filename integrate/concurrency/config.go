@@ -0,0 +1,41 @@
+package concurrency
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the concurrency limit configuration of the service groups.
+type Config struct {
+	// Default applies to the service groups not listed in Groups. A zero
+	// limit disables concurrency limiting for them.
+	Default Options `yaml:"default"`
+	// Groups maps service names to their options.
+	Groups map[string]Options `yaml:"groups"`
+}
+
+// LoadConfig reads a YAML concurrency limit configuration from the given
+// file.
+func LoadConfig(file string) (*Config, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	conf := Config{}
+	if err := yaml.UnmarshalStrict(b, &conf); err != nil {
+		return nil, fmt.Errorf("parsing concurrency config %s: %v", file, err)
+	}
+	return &conf, nil
+}
+
+// Options returns the options of the given service, and false if concurrency
+// limiting is disabled for it.
+func (c *Config) Options(serviceName string) (Options, bool) {
+	opts, ok := c.Groups[serviceName]
+	if !ok {
+		opts = c.Default
+	}
+	return opts, opts.Limit > 0
+}
@@ -29,6 +29,8 @@ const (
 	RequestReceivedTime = "request-received-time"
 	// http header:x-forwarded-for
 	XForwardedFor = "x-forwarded-for"
+	// http header:x-real-ip, 网关解析出的客户端IP
+	XRealIp = "x-real-ip"
 )
 
 // IsKnownResource returns if the given resource is known or not.
@@ -1,86 +1,287 @@
 package integrate
 
 import (
+	"flag"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+
+	"gopkg.in/yaml.v2"
 )
 
 var (
-	allowHosts         = []string{"*.xxx.com", "localhost", "localhost:8080", "192.168.*"}
-	allowMethods       = []string{"GET", "HEAD", "POST", "PUT", "DELETE"}
-	allowExposeHeaders = []string{XRequestId}
+	corsConfig = flag.String("cors-config", "", "The YAML file of CORS rules, required when --enable-cors is set unless the allowed hosts are set by SetAllowHostsRegexp.")
 
-	allowHostsRegexp = regexp.MustCompile(getHostRegStr(allowHosts))
+	// allowHosts are the allowed hosts of the default CORS rule, none by
+	// default.
+	allowHosts         []string
+	allowMethods       = []string{"GET", "HEAD", "POST", "PUT", "DELETE"}
+	allowHeaders       = []string{"Content-Type", "Authorization", XSource, XClient, XRequestId, XTs, XSign, "X-Grpc-Web", "X-User-Agent", "Grpc-Timeout"}
+	allowExposeHeaders = []string{XRequestId, "Grpc-Status", "Grpc-Message"}
 
 	allowCredentials = false
 )
 
-// SetAllowHostsRegexp sets the allowed hosts for CORS.
+// SetAllowHostsRegexp sets the allowed hosts of the default CORS rule, which
+// is used when --cors-config is not set.
 // Note: this should only be used by custom gateway and ldap gateway.
 func SetAllowHostsRegexp(hosts []string) {
-	allowHostsRegexp = regexp.MustCompile(getHostRegStr(hosts))
+	allowHosts = hosts
 }
 
-// SetAllowCredentials sets to allow CORS credentials.
+// SetAllowCredentials sets to allow CORS credentials in the default CORS rule.
 func SetAllowCredentials(allow bool) {
 	allowCredentials = allow
 }
 
-// http请求处理中间件
-type CorsMiddleware struct {
-	Handler http.Handler
+// CorsRule is the CORS rule of the requests with the path prefix.
+type CorsRule struct {
+	PathPrefix string `yaml:"path_prefix"`
+	// AllowOrigins are the allowed origin hosts, "*" matches any characters
+	// of a host. A host without port allows any port. A single "*" allows
+	// any origin.
+	AllowOrigins     []string `yaml:"allow_origins"`
+	AllowMethods     []string `yaml:"allow_methods"`
+	AllowHeaders     []string `yaml:"allow_headers"`
+	ExposeHeaders    []string `yaml:"expose_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"`
+	// MaxAge is how long in seconds the preflight response may be cached.
+	MaxAge int `yaml:"max_age"`
 }
 
-func (m *CorsMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if origin := r.Header.Get("Origin"); origin != "" {
-		// 请求域非法.
-		if !isOriginOk(origin) {
-			// util.Logf(util.DefaultLogger, "The request source is illegal. %s", origin)
-			return
-		}
+// CorsConfig is the CORS configuration.
+type CorsConfig struct {
+	Rules []*CorsRule `yaml:"rules"`
+}
+
+// corsRule is a compiled CorsRule.
+type corsRule struct {
+	*CorsRule
+	anyOrigin bool
+	origins   *regexp.Regexp
+	methods   map[string]bool
+	headers   map[string]bool
+}
+
+// CorsPolicy decides the CORS responses by the longest matched path prefix.
+type CorsPolicy struct {
+	rules []*corsRule
+}
 
-		if allowCredentials {
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
+// NewCorsPolicy returns a CORS policy with the given rules.
+func NewCorsPolicy(rules []*CorsRule) (*CorsPolicy, error) {
+	p := &CorsPolicy{}
+	for _, r := range rules {
+		if !strings.HasPrefix(r.PathPrefix, "/") {
+			return nil, fmt.Errorf("CORS rule path prefix %q must start with /", r.PathPrefix)
+		}
+		if len(r.AllowOrigins) == 0 {
+			return nil, fmt.Errorf("CORS rule %s has no allowed origins", r.PathPrefix)
+		}
+		cr := &corsRule{
+			CorsRule: r,
+			methods:  map[string]bool{},
+			headers:  map[string]bool{},
+		}
+		hosts := []string{}
+		for _, o := range r.AllowOrigins {
+			if o == "*" {
+				cr.anyOrigin = true
+				continue
+			}
+			hosts = append(hosts, o)
 		}
+		if cr.anyOrigin && r.AllowCredentials {
+			return nil, fmt.Errorf("CORS rule %s can not allow credentials for any origin", r.PathPrefix)
+		}
+		if len(hosts) > 0 {
+			re, err := regexp.Compile(getHostRegStr(hosts))
+			if err != nil {
+				return nil, fmt.Errorf("CORS rule %s: %v", r.PathPrefix, err)
+			}
+			cr.origins = re
+		}
+		for _, m := range r.AllowMethods {
+			cr.methods[strings.ToUpper(m)] = true
+		}
+		for _, h := range r.AllowHeaders {
+			cr.headers[strings.ToLower(h)] = true
+		}
+		p.rules = append(p.rules, cr)
+	}
+	sort.SliceStable(p.rules, func(i, j int) bool {
+		return len(p.rules[i].PathPrefix) > len(p.rules[j].PathPrefix)
+	})
+	return p, nil
+}
 
-		w.Header().Set("Access-Control-Allow-Origin", origin)
-		// 预检请求.
-		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
-			return
+// LoadCorsPolicy reads a YAML CORS configuration from the given file.
+func LoadCorsPolicy(file string) (*CorsPolicy, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	conf := CorsConfig{}
+	if err := yaml.UnmarshalStrict(b, &conf); err != nil {
+		return nil, fmt.Errorf("parsing CORS config %s: %v", file, err)
+	}
+	return NewCorsPolicy(conf.Rules)
+}
+
+// defaultCorsPolicy returns the CORS policy of a single rule for all paths,
+// built from the allowed hosts and credentials set by SetAllowHostsRegexp and
+// SetAllowCredentials.
+func defaultCorsPolicy() (*CorsPolicy, error) {
+	return NewCorsPolicy([]*CorsRule{{
+		PathPrefix:       "/",
+		AllowOrigins:     allowHosts,
+		AllowMethods:     allowMethods,
+		AllowHeaders:     allowHeaders,
+		ExposeHeaders:    allowExposeHeaders,
+		AllowCredentials: allowCredentials,
+		MaxAge:           600,
+	}})
+}
+
+// newCorsPolicyFromFlags returns the CORS policy configured by flag
+// --cors-config, or the default policy if the flag is not set. Without both,
+// no origin would be allowed, which is an error.
+func newCorsPolicyFromFlags() (*CorsPolicy, error) {
+	if *corsConfig == "" {
+		if len(allowHosts) == 0 {
+			return nil, fmt.Errorf("--enable-cors requires --cors-config, or the allowed hosts set by SetAllowHostsRegexp")
 		}
+		return defaultCorsPolicy()
+	}
+	return LoadCorsPolicy(*corsConfig)
+}
 
-		exHeaders := allowExposeHeaders
-		w.Header().Set("Access-Control-Expose-Headers", strings.Join(exHeaders, ","))
+// match returns the rule of the path, or nil if none matches.
+func (p *CorsPolicy) match(path string) *corsRule {
+	if p == nil {
+		return nil
 	}
-	m.Handler.ServeHTTP(w, r)
+	for _, r := range p.rules {
+		if strings.HasPrefix(path, r.PathPrefix) {
+			return r
+		}
+	}
+	return nil
 }
 
-func isOriginOk(origin string) bool {
-	// if *debugMode {
-	// 	// util.Logf(util.ErrorLogger, "CORS: in debug mode, return true regardless.")
-	// 	return true
-	// }
+func (r *corsRule) allowOrigin(origin string) bool {
+	return r.anyOrigin || r.listsOrigin(origin)
+}
 
+// listsOrigin reports whether the host of the origin, with or without its
+// port, is one of the allowed origin hosts.
+func (r *corsRule) listsOrigin(origin string) bool {
 	u, err := url.Parse(origin)
-	if err != nil {
+	if err != nil || u.Host == "" || r.origins == nil {
 		return false
 	}
+	return r.origins.MatchString(u.Hostname()) || u.Port() != "" && r.origins.MatchString(u.Host)
+}
 
-	return hostMatch(u.Hostname())
+// AllowWebSocketOrigin reports whether the rule of the request path lists
+// the Origin of the WebSocket handshake, which CORS does not apply to. The
+// rules allowing any origin do not allow the handshakes from other origins,
+// since they carry the cookies of the gateway.
+func (p *CorsPolicy) AllowWebSocketOrigin(r *http.Request) bool {
+	rule := p.match(r.URL.Path)
+	return rule != nil && rule.listsOrigin(r.Header.Get("Origin"))
 }
 
-// hostMatch 判断host是否与正则表达式匹配.
-func hostMatch(host string) bool {
-	return allowHostsRegexp.Match([]byte(host))
+// allowHeaders returns whether all the headers requested by a preflight
+// request are allowed.
+func (r *corsRule) allowHeaders(requested string) bool {
+	if r.headers["*"] {
+		return true
+	}
+	for _, h := range strings.Split(requested, ",") {
+		if h = strings.TrimSpace(h); h != "" && !r.headers[strings.ToLower(h)] {
+			return false
+		}
+	}
+	return true
+}
+
+// http请求处理中间件
+type CorsMiddleware struct {
+	Handler http.Handler
+	// Policy decides the CORS responses. The requests pass through if nil.
+	Policy *CorsPolicy
+}
+
+func (m *CorsMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rule := m.Policy.match(r.URL.Path)
+	if rule == nil {
+		m.Handler.ServeHTTP(w, r)
+		return
+	}
+	h := w.Header()
+	h.Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		m.Handler.ServeHTTP(w, r)
+		return
+	}
+	// 请求域非法.
+	if !rule.allowOrigin(origin) {
+		http.Error(w, "CORS origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	if rule.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if rule.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	// 预检请求.
+	if reqMethod := r.Header.Get("Access-Control-Request-Method"); r.Method == http.MethodOptions && reqMethod != "" {
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		reqHeaders := r.Header.Get("Access-Control-Request-Headers")
+		if !rule.methods[strings.ToUpper(reqMethod)] || !rule.allowHeaders(reqHeaders) {
+			http.Error(w, "CORS request not allowed", http.StatusForbidden)
+			return
+		}
+		h.Set("Access-Control-Allow-Methods", strings.Join(rule.AllowMethods, ","))
+		if reqHeaders != "" {
+			if rule.headers["*"] {
+				h.Set("Access-Control-Allow-Headers", reqHeaders)
+			} else {
+				h.Set("Access-Control-Allow-Headers", strings.Join(rule.AllowHeaders, ","))
+			}
+		}
+		if rule.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(rule.MaxAge))
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if len(rule.ExposeHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(rule.ExposeHeaders, ","))
+	}
+	m.Handler.ServeHTTP(w, r)
 }
 
 // getHostRegStr 获取host模板列表的正则匹配表达式.
 func getHostRegStr(temps []string) string {
 	ts := []string{}
 	for i := range temps {
-		ts = append(ts, "^"+strings.Replace(temps[i], "*", "[\\w\\.]+", -1)+"$")
+		ts = append(ts, "^"+strings.Replace(regexp.QuoteMeta(temps[i]), "\\*", "[\\w\\.\\-]+", -1)+"$")
 	}
 
 	return strings.Join(ts, "|")
@@ -0,0 +1,275 @@
+package integrate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestCorsMiddleware(t *testing.T) *CorsMiddleware {
+	policy, err := NewCorsPolicy([]*CorsRule{
+		{
+			PathPrefix:    "/",
+			AllowOrigins:  []string{"*.example.com"},
+			AllowMethods:  []string{"GET", "POST"},
+			AllowHeaders:  []string{"Content-Type", "X-Uid"},
+			ExposeHeaders: []string{"X-Request-Id"},
+			MaxAge:        600,
+		},
+		{
+			PathPrefix:       "/v1/private/",
+			AllowOrigins:     []string{"admin.example.com"},
+			AllowMethods:     []string{"GET", "PUT"},
+			AllowCredentials: true,
+		},
+		{
+			PathPrefix:   "/v1/public/",
+			AllowOrigins: []string{"*"},
+			AllowMethods: []string{"GET"},
+			AllowHeaders: []string{"*"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewCorsPolicy() failed with %v", err)
+	}
+	return &CorsMiddleware{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}),
+		Policy: policy,
+	}
+}
+
+func TestCorsMiddleware(t *testing.T) {
+	m := newTestCorsMiddleware(t)
+	for _, spec := range []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+
+		wantCode    int
+		wantBody    string
+		wantHeaders map[string]string
+	}{
+		{
+			name:     "no origin",
+			method:   "GET",
+			path:     "/v1/echo",
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+			wantHeaders: map[string]string{
+				"Vary":                        "Origin",
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:     "allowed origin",
+			method:   "POST",
+			path:     "/v1/echo",
+			headers:  map[string]string{"Origin": "https://www.example.com"},
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+			wantHeaders: map[string]string{
+				"Vary":                             "Origin",
+				"Access-Control-Allow-Origin":      "https://www.example.com",
+				"Access-Control-Expose-Headers":    "X-Request-Id",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			name:     "rejected origin",
+			method:   "GET",
+			path:     "/v1/echo",
+			headers:  map[string]string{"Origin": "https://www.example.org"},
+			wantCode: http.StatusForbidden,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:     "origin not matching the whole host",
+			method:   "GET",
+			path:     "/v1/echo",
+			headers:  map[string]string{"Origin": "https://www.example.com.evil.org"},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:   "preflight",
+			method: "OPTIONS",
+			path:   "/v1/echo",
+			headers: map[string]string{
+				"Origin":                         "https://www.example.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "content-type, x-uid",
+			},
+			wantCode: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://www.example.com",
+				"Access-Control-Allow-Methods": "GET,POST",
+				"Access-Control-Allow-Headers": "Content-Type,X-Uid",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			name:   "preflight with method not allowed",
+			method: "OPTIONS",
+			path:   "/v1/echo",
+			headers: map[string]string{
+				"Origin":                        "https://www.example.com",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			wantCode: http.StatusForbidden,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		{
+			name:   "preflight with header not allowed",
+			method: "OPTIONS",
+			path:   "/v1/echo",
+			headers: map[string]string{
+				"Origin":                         "https://www.example.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "x-cid",
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "options without request method is not a preflight",
+			method:   "OPTIONS",
+			path:     "/v1/echo",
+			headers:  map[string]string{"Origin": "https://www.example.com"},
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+		},
+		{
+			name:     "longest prefix with credentials",
+			method:   "GET",
+			path:     "/v1/private/users",
+			headers:  map[string]string{"Origin": "https://admin.example.com"},
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://admin.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "",
+			},
+		},
+		{
+			name:     "longest prefix rejects origin of shorter prefix",
+			method:   "GET",
+			path:     "/v1/private/users",
+			headers:  map[string]string{"Origin": "https://www.example.com"},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:   "any origin and header",
+			method: "OPTIONS",
+			path:   "/v1/public/docs",
+			headers: map[string]string{
+				"Origin":                         "https://anyone.org",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "x-anything",
+			},
+			wantCode: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "x-anything",
+				"Access-Control-Max-Age":       "",
+			},
+		},
+	} {
+		r := httptest.NewRequest(spec.method, spec.path, nil)
+		for k, v := range spec.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+
+		if w.Code != spec.wantCode {
+			t.Errorf("%s: code = %d; want %d", spec.name, w.Code, spec.wantCode)
+		}
+		if spec.wantBody != "" && w.Body.String() != spec.wantBody {
+			t.Errorf("%s: body = %q; want %q", spec.name, w.Body.String(), spec.wantBody)
+		}
+		for k, want := range spec.wantHeaders {
+			if got := w.Header().Get(k); got != want {
+				t.Errorf("%s: header %s = %q; want %q", spec.name, k, got, want)
+			}
+		}
+	}
+}
+
+func TestNewCorsPolicyErrors(t *testing.T) {
+	for _, spec := range []struct {
+		name string
+		rule *CorsRule
+	}{
+		{"relative prefix", &CorsRule{PathPrefix: "v1", AllowOrigins: []string{"*"}}},
+		{"no origins", &CorsRule{PathPrefix: "/"}},
+		{"credentials for any origin", &CorsRule{PathPrefix: "/", AllowOrigins: []string{"*"}, AllowCredentials: true}},
+	} {
+		if _, err := NewCorsPolicy([]*CorsRule{spec.rule}); err == nil {
+			t.Errorf("%s: NewCorsPolicy() succeeded; want error", spec.name)
+		}
+	}
+}
+
+func TestDefaultCorsPolicy(t *testing.T) {
+	defer func(v string) { *corsConfig = v }(*corsConfig)
+	defer SetAllowHostsRegexp(allowHosts)
+	*corsConfig = ""
+
+	// No origin is allowed without --cors-config or the allowed hosts.
+	SetAllowHostsRegexp(nil)
+	if _, err := newCorsPolicyFromFlags(); err == nil {
+		t.Error("newCorsPolicyFromFlags() without hosts succeeded; want error")
+	}
+
+	SetAllowHostsRegexp([]string{"*.example.com", "localhost", "127.0.0.1:8080"})
+	p, err := newCorsPolicyFromFlags()
+	if err != nil {
+		t.Fatalf("newCorsPolicyFromFlags() failed with %v", err)
+	}
+	for _, spec := range []struct {
+		origin string
+		want   bool
+	}{
+		{"https://www.example.com", true},
+		{"https://www.example.com:8443", true},
+		{"http://localhost:8080", true},
+		{"http://127.0.0.1:8080", true},
+		{"http://127.0.0.1", false},
+		{"http://127.0.0.1:9090", false},
+		{"https://www.example.com.cn", false},
+		{"https://www-example.com", false},
+		{"null", false},
+	} {
+		if got := p.match("/v1/echo").allowOrigin(spec.origin); got != spec.want {
+			t.Errorf("allowOrigin(%q) = %t; want %t", spec.origin, got, spec.want)
+		}
+	}
+}
+
+func TestCorsPolicyAllowWebSocketOrigin(t *testing.T) {
+	p := newTestCorsMiddleware(t).Policy
+	for _, spec := range []struct {
+		path, origin string
+		want         bool
+	}{
+		{"/v1/chat", "https://www.example.com", true},
+		{"/v1/chat", "https://www.example.org", false},
+		{"/v1/chat", "", false},
+		{"/v1/private/chat", "https://admin.example.com", true},
+		{"/v1/private/chat", "https://www.example.com", false},
+		// Any origin is not enough for WebSocket.
+		{"/v1/public/chat", "https://anyone.org", false},
+	} {
+		r := httptest.NewRequest("GET", spec.path, nil)
+		r.Header.Set("Origin", spec.origin)
+		if got := p.AllowWebSocketOrigin(r); got != spec.want {
+			t.Errorf("AllowWebSocketOrigin(%s, %q) = %v; want %v", spec.path, spec.origin, got, spec.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package integrate
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/codes"
+
+	fpb "github.com/binchencoder/gateway-proto/frontend"
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/util"
+)
+
+var (
+	decompressRequest = flag.Bool("decompress-request", true, "Whether to decompress the request bodies with Content-Encoding.")
+	maxDecompressSize = flag.Int64("max-decompressed-size", 16<<20, "The max size in bytes of a decompressed request body.")
+)
+
+// Decoder returns a reader of the data decompressed from r. maxSize is the
+// max size of the decompressed data, which the decoder may use to bound its
+// memory.
+type Decoder func(r io.Reader, maxSize int64) (io.ReadCloser, error)
+
+var (
+	decodersMu sync.Mutex
+	decoders   = map[string]Decoder{
+		"br": func(r io.Reader, maxSize int64) (io.ReadCloser, error) {
+			return ioutil.NopCloser(brotli.NewReader(r)), nil
+		},
+		"gzip": func(r io.Reader, maxSize int64) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		"zstd": func(r io.Reader, maxSize int64) (io.ReadCloser, error) {
+			// A frame may declare a window much larger than its data, which
+			// would be allocated before the size limit is reached.
+			window := uint64(maxSize)
+			if window < zstd.MinWindowSize {
+				window = zstd.MinWindowSize
+			}
+			d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1),
+				zstd.WithDecoderMaxMemory(window), zstd.WithDecoderMaxWindow(window))
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		},
+	}
+)
+
+// RegisterDecoding registers the decoder of a request content coding. It
+// replaces the built-in br, gzip or zstd decoder of the same name.
+// It should be called before NewHttpMux.
+func RegisterDecoding(name string, d Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[strings.ToLower(name)] = d
+}
+
+func getDecoder(name string) Decoder {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	return decoders[name]
+}
+
+// 请求解压中间件. It decompresses the request bodies with Content-Encoding
+// before they reach the marshalers, and rejects the unsupported codings with
+// 415 and the bodies decompressed larger than MaxSize with 413.
+type DecompressMiddleware struct {
+	Handler http.Handler
+	// MaxSize is the max size in bytes of a decompressed body.
+	MaxSize int64
+	// Mux writes the error responses with its error handler.
+	Mux *runtime.ServeMux
+}
+
+func (m *DecompressMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ce := strings.TrimSpace(r.Header.Get("Content-Encoding"))
+	if ce == "" || strings.EqualFold(ce, "identity") || r.Body == nil || r.Body == http.NoBody {
+		m.Handler.ServeHTTP(w, r)
+		return
+	}
+
+	// The codings are listed in the order they were applied.
+	codings := strings.Split(ce, ",")
+	body := io.Reader(r.Body)
+	for i := len(codings) - 1; i >= 0; i-- {
+		name := strings.ToLower(strings.TrimSpace(codings[i]))
+		if name == "identity" {
+			continue
+		}
+		d := getDecoder(name)
+		if d == nil {
+			w.Header().Set("Accept-Encoding", strings.Join(decodingNames(), ", "))
+			m.error(w, r, http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported Content-Encoding %q.", name))
+			return
+		}
+		rc, err := d(body, m.MaxSize)
+		if err != nil {
+			m.error(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid %s request body.", name))
+			return
+		}
+		defer rc.Close()
+		body = rc
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(body, m.MaxSize+1))
+	if err != nil {
+		// The compressed body is larger than the limit of LimitsMiddleware.
+		var se *runtime.HTTPStatusError
+		if errors.As(err, &se) {
+			_, outboundMarshaler := runtime.MarshalerForRequest(m.Mux, r)
+			runtime.HTTPError(r.Context(), m.Mux, outboundMarshaler, w, r, err)
+			return
+		}
+		m.error(w, r, http.StatusBadRequest, "Invalid compressed request body.")
+		return
+	}
+	if int64(len(data)) > m.MaxSize {
+		util.Logf(util.LimitLogger, util.LimitFormat, r.Header.Get(XRequestId), "decompress", "", r.Method, r.URL.Path,
+			fmt.Sprintf("encoding:%s,max:%d", ce, m.MaxSize))
+		m.error(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Decompressed request body larger than %d bytes.", m.MaxSize))
+		return
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(data))
+	r.ContentLength = int64(len(data))
+	r.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	r.Header.Del("Content-Encoding")
+	m.Handler.ServeHTTP(w, r)
+}
+
+// error writes the error response of the HTTP status, with the same body as
+// the errors of the hooks.
+func (m *DecompressMiddleware) error(w http.ResponseWriter, r *http.Request, code int, msg string) {
+	err := &runtime.HTTPStatusError{
+		HTTPStatus: code,
+		Err:        grpcError(codes.InvalidArgument, fpb.ErrorCode_BAD_REQUEST, []string{msg}),
+	}
+	_, outboundMarshaler := runtime.MarshalerForRequest(m.Mux, r)
+	runtime.HTTPError(r.Context(), m.Mux, outboundMarshaler, w, r, err)
+}
+
+// decodingNames returns the names of the supported request content codings.
+func decodingNames() []string {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	names := []string{}
+	for n := range decoders {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
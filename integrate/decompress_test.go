@@ -0,0 +1,164 @@
+package integrate
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+)
+
+func gzipData(t *testing.T, s string) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write([]byte(s))
+	if err := w.Close(); err != nil {
+		t.Fatalf("gzip failed with %v", err)
+	}
+	return b.Bytes()
+}
+
+func zstdData(t *testing.T, s string) []byte {
+	w, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("zstd.NewWriter() failed with %v", err)
+	}
+	return w.EncodeAll([]byte(s), nil)
+}
+
+func brData(t *testing.T, s string) []byte {
+	var b bytes.Buffer
+	w := brotli.NewWriter(&b)
+	w.Write([]byte(s))
+	if err := w.Close(); err != nil {
+		t.Fatalf("brotli failed with %v", err)
+	}
+	return b.Bytes()
+}
+
+// zstdWindowData returns a zstd frame of a raw block, which declares the
+// window size of 1<<(10+exp) bytes instead of the content size.
+func zstdWindowData(s string, exp byte) []byte {
+	b := []byte{0x28, 0xb5, 0x2f, 0xfd, 0, exp << 3}
+	h := uint32(len(s))<<3 | 1 // the last raw block
+	b = append(b, byte(h), byte(h>>8), byte(h>>16))
+	return append(b, s...)
+}
+
+func TestDecompressMiddleware(t *testing.T) {
+	body := `{"id":"` + strings.Repeat("a", 100) + `"}`
+	for _, spec := range []struct {
+		name     string
+		encoding string
+		data     []byte
+
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "plain",
+			data:     []byte(body),
+			wantCode: http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name:     "gzip",
+			encoding: "gzip",
+			data:     gzipData(t, body),
+			wantCode: http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name:     "zstd",
+			encoding: "ZSTD",
+			data:     zstdData(t, body),
+			wantCode: http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name:     "br",
+			encoding: "br",
+			data:     brData(t, body),
+			wantCode: http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name:     "gzip then zstd",
+			encoding: "gzip, zstd",
+			data:     zstdData(t, string(gzipData(t, body))),
+			wantCode: http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name:     "unsupported",
+			encoding: "compress",
+			data:     []byte(body),
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "zstd window",
+			encoding: "zstd",
+			data:     zstdWindowData(body, 0),
+			wantCode: http.StatusOK,
+			wantBody: body,
+		},
+		{
+			name:     "zstd window larger than max size",
+			encoding: "zstd",
+			data:     zstdWindowData(body, 13),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "corrupt",
+			encoding: "gzip",
+			data:     []byte(body),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "too large",
+			encoding: "gzip",
+			data:     gzipData(t, strings.Repeat("a", 1025)),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	} {
+		var got string
+		m := &DecompressMiddleware{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if ce := r.Header.Get("Content-Encoding"); ce != "" {
+					t.Errorf("%s: Content-Encoding = %q; want none", spec.name, ce)
+				}
+				b, _ := ioutil.ReadAll(r.Body)
+				if r.ContentLength != int64(len(b)) {
+					t.Errorf("%s: ContentLength = %d; want %d", spec.name, r.ContentLength, len(b))
+				}
+				got = string(b)
+			}),
+			MaxSize: 1024,
+			Mux:     runtime.NewServeMux(),
+		}
+		r := httptest.NewRequest("POST", "/v1/echo", bytes.NewReader(spec.data))
+		if spec.encoding != "" {
+			r.Header.Set("Content-Encoding", spec.encoding)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+
+		if w.Code != spec.wantCode {
+			t.Errorf("%s: code = %d; want %d", spec.name, w.Code, spec.wantCode)
+		}
+		if got != spec.wantBody {
+			t.Errorf("%s: body = %q; want %q", spec.name, got, spec.wantBody)
+		}
+		// The errors have the body of the hook errors.
+		if w.Code != http.StatusOK && !strings.Contains(w.Body.String(), `"code":100008`) {
+			t.Errorf("%s: error body = %q; want containing %q", spec.name, w.Body.String(), `"code":100008`)
+		}
+	}
+}
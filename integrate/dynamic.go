@@ -0,0 +1,75 @@
+package integrate
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	gr "google.golang.org/grpc"
+
+	"github.com/binchencoder/janus-gateway/integrate/dynamic"
+	"github.com/binchencoder/janus-gateway/util"
+)
+
+var (
+	descriptorSets     = flag.String("descriptor-sets", "", "Comma separated FileDescriptorSet files of the gRPC services proxied by their janus.api annotations, without generated handlers.")
+	reflectionEndpoint = flag.String("reflection-endpoint", "", "The address of a gRPC server, such as 127.0.0.1:9090, whose services are proxied by the descriptors read with server reflection. The calls are still routed by SkyLB, see --debug-svc-endpoint.")
+	reflectionTimeout  = flag.Duration("reflection-timeout", 10*time.Second, "The timeout to read the descriptors from --reflection-endpoint.")
+)
+
+// AddDynamicServices adds the services of flags --descriptor-sets and
+// --reflection-endpoint to the runtime registry. It must be called before
+// runtime.SetGatewayServiceHook, which registers and enables the services. A
+// service which has generated handlers keeps them.
+func AddDynamicServices() error {
+	var svcs []*dynamic.Service
+	if *descriptorSets != "" {
+		files, err := dynamic.LoadDescriptorSets(strings.Split(*descriptorSets, ",")...)
+		if err != nil {
+			return fmt.Errorf("loading --descriptor-sets: %v", err)
+		}
+		ss, err := dynamic.NewServices(files)
+		if err != nil {
+			return fmt.Errorf("loading --descriptor-sets: %v", err)
+		}
+		svcs = append(svcs, ss...)
+	}
+	if *reflectionEndpoint != "" {
+		ss, err := reflectServices(*reflectionEndpoint)
+		if err != nil {
+			return fmt.Errorf("reading --reflection-endpoint %s: %v", *reflectionEndpoint, err)
+		}
+		svcs = append(svcs, ss...)
+	}
+
+	added := map[*dynamic.Service]bool{}
+	for _, s := range dynamic.AddServices(svcs) {
+		added[s] = true
+	}
+	for _, s := range svcs {
+		if added[s] {
+			util.Logf(util.ConfigLogger, "[Dynamic service] %s", s.Name())
+		} else {
+			util.Logf(util.ConfigLogger, "[Dynamic service] %s is skipped for its generated handlers", s.Name())
+		}
+	}
+	return nil
+}
+
+func reflectServices(endpoint string) ([]*dynamic.Service, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), *reflectionTimeout)
+	defer cancel()
+	conn, err := gr.DialContext(ctx, endpoint, gr.WithInsecure(), gr.WithBlock())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	files, err := dynamic.LoadReflection(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewServices(files)
+}
@@ -0,0 +1,146 @@
+// Package dynamic proxies gRPC services by their descriptors instead of the
+// handlers generated by protoc-gen-grpc-gateway. The routes are read from the
+// janus.api.http and janus.api.method options of the methods, and the
+// requests and responses are transcoded with dynamicpb messages, so a new
+// backend API needs neither code generation nor a new gateway build.
+package dynamic
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	// Registers the janus.api options, so that they are parsed with the
+	// descriptors.
+	_ "github.com/binchencoder/janus-gateway/httpoptions"
+)
+
+const reflectionService = "grpc.reflection.v1alpha.ServerReflection"
+
+// LoadDescriptorSets reads FileDescriptorSet files, e.g. the output of
+// protoc --descriptor_set_out --include_imports. A file in several sets is
+// taken from the first one. The imports missing from the sets are resolved
+// from the files linked into the gateway.
+func LoadDescriptorSets(paths ...string) (*protoregistry.Files, error) {
+	fds := &descriptorpb.FileDescriptorSet{}
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(b, &set); err != nil {
+			return nil, fmt.Errorf("parsing descriptor set %s: %v", path, err)
+		}
+		fds.File = append(fds.File, set.File...)
+	}
+	return newFiles(fds)
+}
+
+// LoadReflection reads the files of the services listed by the gRPC server
+// reflection service on conn.
+func LoadReflection(ctx context.Context, conn *grpc.ClientConn) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := reflect(stream, &rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{ListServices: "*"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	fds := &descriptorpb.FileDescriptorSet{}
+	for _, svc := range resp.GetListServicesResponse().GetService() {
+		if svc.Name == reflectionService {
+			continue
+		}
+		resp, err := reflect(stream, &rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: svc.Name},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// The file comes with the imports not sent on the stream before.
+		for _, b := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(b, fd); err != nil {
+				return nil, fmt.Errorf("parsing descriptor of %s: %v", svc.Name, err)
+			}
+			fds.File = append(fds.File, fd)
+		}
+	}
+	return newFiles(fds)
+}
+
+// reflect sends a request on the reflection stream and returns its response.
+func reflect(stream rpb.ServerReflection_ServerReflectionInfoClient, req *rpb.ServerReflectionRequest) (*rpb.ServerReflectionResponse, error) {
+	if err := stream.Send(req); err != nil {
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if e := resp.GetErrorResponse(); e != nil {
+		return nil, fmt.Errorf("server reflection error %d: %s", e.ErrorCode, e.ErrorMessage)
+	}
+	return resp, nil
+}
+
+// newFiles builds the files of the set, each after its imports.
+func newFiles(fds *descriptorpb.FileDescriptorSet) (*protoregistry.Files, error) {
+	protos := map[string]*descriptorpb.FileDescriptorProto{}
+	var names []string
+	for _, fd := range fds.File {
+		if _, ok := protos[fd.GetName()]; !ok {
+			protos[fd.GetName()] = fd
+			names = append(names, fd.GetName())
+		}
+	}
+
+	files := &protoregistry.Files{}
+	var add func(name string) error
+	add = func(name string) error {
+		if _, err := files.FindFileByPath(name); err == nil {
+			return nil
+		}
+		fd, ok := protos[name]
+		if !ok {
+			f, err := protoregistry.GlobalFiles.FindFileByPath(name)
+			if err != nil {
+				return fmt.Errorf("file %s is not found", name)
+			}
+			return files.RegisterFile(f)
+		}
+		// Removed before its imports are added, so an import cycle ends up
+		// in a file not found.
+		delete(protos, name)
+		for _, dep := range fd.Dependency {
+			if err := add(dep); err != nil {
+				return fmt.Errorf("importing %s: %v", name, err)
+			}
+		}
+		f, err := protodesc.NewFile(fd, files)
+		if err != nil {
+			return err
+		}
+		return files.RegisterFile(f)
+	}
+	for _, name := range names {
+		if err := add(name); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
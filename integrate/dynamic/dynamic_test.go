@@ -0,0 +1,310 @@
+package dynamic
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	vexpb "github.com/binchencoder/gateway-proto/data"
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	options "github.com/binchencoder/janus-gateway/httpoptions"
+)
+
+// testFile returns the descriptor of an echo service annotated for the
+// gateway.
+func testFile() *descriptorpb.FileDescriptorProto {
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	method := func(name string, rule *options.HttpRule, mopts *options.ApiMethod) *descriptorpb.MethodDescriptorProto {
+		opts := &descriptorpb.MethodOptions{}
+		proto.SetExtension(opts, options.E_Http, rule)
+		if mopts != nil {
+			proto.SetExtension(opts, options.E_Method, mopts)
+		}
+		return &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".dynamic.test.SimpleMessage"),
+			OutputType: proto.String(".dynamic.test.SimpleMessage"),
+			Options:    opts,
+		}
+	}
+	sopts := &descriptorpb.ServiceOptions{}
+	proto.SetExtension(sopts, options.E_ServiceSpec, &options.ServiceSpec{
+		ServiceId: vexpb.ServiceId_SHARED_TEST_SERVER_SERVICE,
+	})
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("dynamic/test/echo.proto"),
+		Package: proto.String("dynamic.test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:  proto.String("Note"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("text", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")},
+			},
+			{
+				Name: proto.String("SimpleMessage"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("num", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+					field("note", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".dynamic.test.Note"),
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name:    proto.String("EchoService"),
+				Options: sopts,
+				Method: []*descriptorpb.MethodDescriptorProto{
+					method("Echo", &options.HttpRule{
+						Pattern: &options.HttpRule_Get{Get: "/v1/dynamic/echo/{id}"},
+						AdditionalBindings: []*options.HttpRule{{
+							Pattern: &options.HttpRule_Post{Post: "/v1/dynamic/echo/{id}:note"},
+							Body:    "note",
+						}},
+					}, &options.ApiMethod{LoginNotRequired: true, Timeout: "3s"}),
+					method("EchoBody", &options.HttpRule{
+						Pattern:      &options.HttpRule_Post{Post: "/v1/dynamic/echo_body"},
+						Body:         "*",
+						ResponseBody: "note",
+					}, nil),
+				},
+			},
+		},
+	}
+}
+
+// echoServer serves the calls of any method with the request, and records
+// the method names.
+func echoServer(t *testing.T, msg protoreflect.MessageDescriptor, methods *[]string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer(grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		name, _ := grpc.MethodFromServerStream(stream)
+		*methods = append(*methods, name)
+		in := dynamicpb.NewMessage(msg)
+		if err := stream.RecvMsg(in); err != nil {
+			return err
+		}
+		return stream.SendMsg(in)
+	}))
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	return l.Addr().String()
+}
+
+func TestDynamicService(t *testing.T) {
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{testFile()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "echo.pb")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := LoadDescriptorSets(path)
+	if err != nil {
+		t.Fatalf("LoadDescriptorSets() failed with %v", err)
+	}
+	svcs, err := NewServices(files)
+	if err != nil {
+		t.Fatalf("NewServices() failed with %v", err)
+	}
+	if len(svcs) != 1 || svcs[0].Name() != "dynamic.test.EchoService" {
+		t.Fatalf("NewServices() = %v; want dynamic.test.EchoService", svcs)
+	}
+	if added := AddServices(svcs); len(added) != 1 {
+		t.Fatalf("AddServices() = %v; want the service added", added)
+	}
+	if added := AddServices(svcs); len(added) != 0 {
+		t.Errorf("AddServices() = %v again; want the service skipped", added)
+	}
+
+	svc := svcs[0].Runtime()
+	mux := runtime.NewServeMux()
+	if err := svc.Register(mux); err != nil {
+		t.Fatalf("svc.Register() failed with %v", err)
+	}
+	if len(svc.Methods) != 3 {
+		t.Fatalf("svc.Methods = %v; want 3 bindings", svc.Methods)
+	}
+	if m := svc.Methods[0]; m.Name != "Echo" || m.LoginRequired || m.Timeout != "3s" {
+		t.Errorf("svc.Methods[0] = %+v; want Echo with its janus.api.method options", m)
+	}
+
+	req := httptest.NewRequest("GET", "/v1/dynamic/echo/foo", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("disabled service: status = %d; want %d", w.Code, http.StatusInternalServerError)
+	}
+
+	var methods []string
+	md, _ := files.FindDescriptorByName("dynamic.test.SimpleMessage")
+	conn, err := grpc.Dial(echoServer(t, md.(protoreflect.MessageDescriptor), &methods), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	svc.Enable(svc.Spec, conn)
+
+	for _, spec := range []struct {
+		method, target, body string
+		want                 map[string]interface{}
+	}{
+		{
+			method: "GET", target: "/v1/dynamic/echo/foo?num=3&id=bar",
+			want: map[string]interface{}{"id": "foo", "num": "3", "note": nil},
+		},
+		{
+			method: "POST", target: "/v1/dynamic/echo/foo:note?num=3", body: `{"text":"hello"}`,
+			want: map[string]interface{}{"id": "foo", "num": "3", "note": map[string]interface{}{"text": "hello"}},
+		},
+		{
+			method: "POST", target: "/v1/dynamic/echo_body?id=bar", body: `{"id":"foo","note":{"text":"hello"}}`,
+			want: map[string]interface{}{"text": "hello"},
+		},
+	} {
+		req := httptest.NewRequest(spec.method, spec.target, strings.NewReader(spec.body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s %s: status = %d, body %s; want 200", spec.method, spec.target, w.Code, w.Body)
+			continue
+		}
+		got := map[string]interface{}{}
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Errorf("%s %s: body %s is not JSON: %v", spec.method, spec.target, w.Body, err)
+			continue
+		}
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(spec.want)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("%s %s: body = %s; want %s", spec.method, spec.target, gotJSON, wantJSON)
+		}
+	}
+	want := "/dynamic.test.EchoService/Echo,/dynamic.test.EchoService/Echo,/dynamic.test.EchoService/EchoBody"
+	if got := strings.Join(methods, ","); got != want {
+		t.Errorf("called methods = %s; want %s", got, want)
+	}
+}
+
+func TestNewServicesInvalidBody(t *testing.T) {
+	fd := testFile()
+	rule := proto.GetExtension(fd.Service[0].Method[1].Options, options.E_Http).(*options.HttpRule)
+	rule.Body = "id"
+	files, err := newFiles(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{fd}})
+	if err != nil {
+		t.Fatalf("newFiles() failed with %v", err)
+	}
+	if _, err := NewServices(files); err == nil {
+		t.Errorf("NewServices() with a string body field succeeded; want an error")
+	}
+}
+
+func TestAddServicesGeneratedGroup(t *testing.T) {
+	fd := testFile()
+	proto.SetExtension(fd.Service[0].Options, options.E_ServiceSpec, &options.ServiceSpec{
+		ServiceId: vexpb.ServiceId_SHARED_TEST_CLIENT_SERVICE,
+	})
+	files, err := newFiles(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{fd}})
+	if err != nil {
+		t.Fatalf("newFiles() failed with %v", err)
+	}
+	svcs, err := NewServices(files)
+	if err != nil {
+		t.Fatalf("NewServices() failed with %v", err)
+	}
+
+	// The group is added by the generated handlers of another service.
+	spec := svcs[0].Runtime().Spec
+	enables := 0
+	runtime.AddService(&runtime.Service{Spec: spec, Name: "GeneratedService"}, func() { enables++ }, func() {})
+
+	if added := AddServices(svcs); len(added) != 1 {
+		t.Fatalf("AddServices() = %v; want the service added", added)
+	}
+	sg := runtime.GetServiceGroup(spec)
+	if sg.Services["GeneratedService"] == nil || sg.Services[svcs[0].Runtime().Name] != svcs[0].Runtime() {
+		t.Errorf("service group services = %v; want both services", sg.Services)
+	}
+	sg.Enable()
+	if enables != 1 {
+		t.Errorf("enables of the generated group = %d; want 1", enables)
+	}
+}
+
+func TestLoadReflection(t *testing.T) {
+	fd := testFile()
+	if _, err := protoregistry.GlobalFiles.FindFileByPath(fd.GetName()); err != nil {
+		f, err := protodesc.NewFile(fd, protoregistry.GlobalFiles)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := protoregistry.GlobalFiles.RegisterFile(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "dynamic.test.EchoService",
+		HandlerType: (*interface{})(nil),
+		Metadata:    fd.GetName(),
+	}, struct{}{})
+	reflection.Register(s)
+	go s.Serve(l)
+	defer s.Stop()
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	files, err := LoadReflection(context.Background(), conn)
+	if err != nil {
+		t.Fatalf("LoadReflection() failed with %v", err)
+	}
+	if _, err := files.FindDescriptorByName(reflectionService); err == nil {
+		t.Errorf("LoadReflection() loaded %s; want it skipped", reflectionService)
+	}
+	svcs, err := NewServices(files)
+	if err != nil {
+		t.Fatalf("NewServices() failed with %v", err)
+	}
+	if len(svcs) != 1 || svcs[0].Name() != "dynamic.test.EchoService" || len(svcs[0].bindings) != 3 {
+		t.Errorf("NewServices() = %v; want dynamic.test.EchoService with 3 bindings", svcs)
+	}
+}
@@ -0,0 +1,398 @@
+package dynamic
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	options "github.com/binchencoder/janus-gateway/httpoptions"
+	"github.com/binchencoder/letsgo/service/naming"
+	"github.com/binchencoder/skylb-api/client"
+	skypb "github.com/binchencoder/skylb-api/proto"
+)
+
+// Service is a gRPC service proxied by its descriptor.
+type Service struct {
+	desc     protoreflect.ServiceDescriptor
+	opts     *options.ServiceSpec
+	svc      *runtime.Service
+	bindings []*binding
+
+	// client holds the *grpc.ClientConn of the service while it's enabled.
+	client runtime.ServiceClient
+}
+
+// binding is an HTTP binding of a method: its HttpRule or one of the
+// additional bindings.
+type binding struct {
+	method     protoreflect.MethodDescriptor
+	opts       *options.ApiMethod
+	httpMethod string
+	path       string
+	pattern    runtime.Pattern
+	// body is the request field the body is decoded into; it's nil for the
+	// whole request.
+	body    protoreflect.FieldDescriptor
+	hasBody bool
+	// responseBody is the response field written as the body, or nil for the
+	// whole response.
+	responseBody protoreflect.FieldDescriptor
+	// filter excludes the fields bound by the path and the body from the
+	// query parameters; it's nil if the whole request is the body.
+	filter *utilities.DoubleArray
+}
+
+// NewServices returns the services of the files which have the
+// janus.api.service_spec option. Only the unary methods with the
+// janus.api.http option are proxied.
+func NewServices(files *protoregistry.Files) ([]*Service, error) {
+	var svcs []*Service
+	var err error
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		sds := fd.Services()
+		for i := 0; i < sds.Len(); i++ {
+			var s *Service
+			if s, err = newService(sds.Get(i)); err != nil {
+				return false
+			}
+			if s != nil {
+				svcs = append(svcs, s)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return svcs, nil
+}
+
+// newService returns the service of the descriptor, or nil if the service
+// has no janus.api.service_spec option.
+func newService(sd protoreflect.ServiceDescriptor) (*Service, error) {
+	sopts, ok := proto.GetExtension(sd.Options(), options.E_ServiceSpec).(*options.ServiceSpec)
+	if !ok || sopts == nil {
+		return nil, nil
+	}
+	if _, err := naming.ServiceIdToName(sopts.GetServiceId()); err != nil {
+		return nil, fmt.Errorf("service %s: %v", sd.FullName(), err)
+	}
+
+	s := &Service{desc: sd, opts: sopts}
+	methods := sd.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+		rule, ok := proto.GetExtension(md.Options(), options.E_Http).(*options.HttpRule)
+		if !ok || rule == nil || md.IsStreamingClient() || md.IsStreamingServer() {
+			continue
+		}
+		mopts, _ := proto.GetExtension(md.Options(), options.E_Method).(*options.ApiMethod)
+		if mopts == nil {
+			mopts = &options.ApiMethod{}
+		}
+		for _, r := range append([]*options.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+			b, err := newBinding(md, mopts, r)
+			if err != nil {
+				return nil, fmt.Errorf("method %s: %v", md.FullName(), err)
+			}
+			s.bindings = append(s.bindings, b)
+		}
+	}
+
+	spec := client.NewServiceSpec(sopts.GetNamespace(), sopts.GetServiceId(), sopts.GetPortName())
+	s.svc = &runtime.Service{
+		Spec:     spec,
+		Name:     string(sd.Name()),
+		FullName: string(sd.FullName()),
+		Balancer: sopts.GetBalancer().String(),
+		Register: s.register,
+		Enable: func(spec *skypb.ServiceSpec, conn *grpc.ClientConn) {
+			s.client.Store(conn)
+		},
+		Disable: func() {
+			s.client.Store(nil)
+		},
+	}
+	return s, nil
+}
+
+func newBinding(md protoreflect.MethodDescriptor, mopts *options.ApiMethod, rule *options.HttpRule) (*binding, error) {
+	b := &binding{method: md, opts: mopts}
+	switch {
+	case rule.GetGet() != "":
+		b.httpMethod, b.path = "GET", rule.GetGet()
+	case rule.GetPut() != "":
+		b.httpMethod, b.path = "PUT", rule.GetPut()
+	case rule.GetPost() != "":
+		b.httpMethod, b.path = "POST", rule.GetPost()
+	case rule.GetDelete() != "":
+		b.httpMethod, b.path = "DELETE", rule.GetDelete()
+	case rule.GetPatch() != "":
+		b.httpMethod, b.path = "PATCH", rule.GetPatch()
+	case rule.GetCustom() != nil:
+		b.httpMethod, b.path = rule.GetCustom().Kind, rule.GetCustom().Path
+	default:
+		return nil, fmt.Errorf("no pattern specified in janus.api.http")
+	}
+	if b.httpMethod == "GET" && rule.Body != "" {
+		return nil, fmt.Errorf("must not set request body when http method is GET")
+	}
+
+	var err error
+	if b.pattern, err = runtime.ParsePattern(b.path); err != nil {
+		return nil, err
+	}
+	var bound [][]string
+	for _, v := range b.pattern.Vars() {
+		bound = append(bound, strings.Split(v, "."))
+	}
+
+	fields := md.Input().Fields()
+	switch rule.Body {
+	case "":
+	case "*":
+		b.hasBody = true
+	default:
+		b.hasBody = true
+		b.body = fields.ByName(protoreflect.Name(rule.Body))
+		if b.body == nil || b.body.Message() == nil || b.body.Cardinality() == protoreflect.Repeated {
+			return nil, fmt.Errorf("body %q is not a message field of %s", rule.Body, md.Input().FullName())
+		}
+		bound = append(bound, []string{rule.Body})
+	}
+	if rule.Body != "*" {
+		b.filter = utilities.NewDoubleArray(bound)
+	}
+
+	if rule.ResponseBody != "" {
+		b.responseBody = md.Output().Fields().ByName(protoreflect.Name(rule.ResponseBody))
+		if b.responseBody == nil || b.responseBody.IsList() || b.responseBody.IsMap() {
+			return nil, fmt.Errorf("response body %q is not a singular field of %s", rule.ResponseBody, md.Output().FullName())
+		}
+	}
+	return b, nil
+}
+
+// Name returns the full name of the service.
+func (s *Service) Name() string {
+	return string(s.desc.FullName())
+}
+
+// Runtime returns the service added to the runtime registry by AddServices.
+func (s *Service) Runtime() *runtime.Service {
+	return s.svc
+}
+
+// AddServices adds the services to the default registry of runtime, so that
+// they are registered to the mux and enabled with the generated ones. The
+// service groups no generated handler is added for resolve the services with
+// SkyLB as the generated service groups do. A service already in the registry,
+// e.g. by its generated handlers, is left to them; AddServices returns the
+// services which are added.
+func AddServices(svcs []*Service) []*Service {
+	groups := map[string]*group{}
+	var added []*Service
+	for _, s := range svcs {
+		spec := s.svc.Spec
+		sg := runtime.GetServiceGroup(spec)
+		if sg != nil && sg.Services[s.svc.Name] != nil {
+			continue
+		}
+		if sg != nil {
+			runtime.AddService(s.svc, nil, nil)
+		} else {
+			g := groups[spec.String()]
+			if g == nil {
+				g = &group{spec: spec}
+				groups[spec.String()] = g
+			}
+			runtime.AddService(s.svc, g.enable, g.disable)
+		}
+		added = append(added, s)
+	}
+	return added
+}
+
+// register adds the methods of the service to the registry and their
+// bindings to the mux.
+func (s *Service) register(mux *runtime.ServeMux) error {
+	spec := s.svc.Spec
+	for _, b := range s.bindings {
+		o := b.opts
+		meth := runtime.AddMethod(spec, s.svc.Name, string(b.method.Name()), b.path, b.httpMethod, &runtime.MethodOptions{
+			LoginRequired:      !o.LoginNotRequired,
+			ClientSignRequired: o.ClientSignRequired,
+			IsThirdParty:       o.IsThirdParty,
+			SpecSourceType:     o.SpecSourceType.String(),
+			ApiSource:          o.ApiSource.String(),
+			TokenType:          o.TokenType.String(),
+			Timeout:            o.Timeout,
+			RateLimit:          o.RateLimit,
+			RateLimitBurst:     o.RateLimitBurst,
+			MaxAttempts:        o.MaxAttempts,
+			Hedge:              o.Hedge,
+			HedgeDelay:         o.HedgeDelay,
+			MaxBodyBytes:       o.MaxBodyBytes,
+		})
+		mux.HandleMethod(b.httpMethod, b.pattern, s.opts.GetServiceId(), meth, s.handler(mux, b, meth))
+	}
+	return nil
+}
+
+func (s *Service) handler(mux *runtime.ServeMux, b *binding, meth *runtime.Method) runtime.HandlerFunc {
+	rpcMethodName := fmt.Sprintf("/%s/%s", s.desc.FullName(), b.method.Name())
+	return func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		conn, _ := s.client.Load().(*grpc.ClientConn)
+		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		if conn == nil {
+			err := status.Error(codes.Internal, "service disabled")
+			runtime.HTTPError(inctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		ctx, err := runtime.RequestAcceptedFor(inctx, meth, w, req)
+		if err != nil {
+			grpclog.Errorf("runtime.RequestAccepted returns error: %v", err)
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		ctx, err = runtime.AnnotateContext(ctx, mux, req, rpcMethodName, runtime.WithHTTPPathPattern(b.path))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		resp, md, err := s.request(ctx, inboundMarshaler, conn, req, pathParams, b, meth, rpcMethodName)
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		runtime.ForwardResponseMessage(ctx, mux, outboundMarshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+	}
+}
+
+// request builds the request message of the binding from the HTTP request
+// and calls the method on conn.
+func (s *Service) request(ctx context.Context, marshaler runtime.Marshaler, conn *grpc.ClientConn, req *http.Request, pathParams map[string]string, b *binding, meth *runtime.Method, rpcMethodName string) (proto.Message, runtime.ServerMetadata, error) {
+	protoReq := dynamicpb.NewMessage(b.method.Input())
+	var metadata runtime.ServerMetadata
+
+	if b.hasBody {
+		var into proto.Message = protoReq
+		if b.body != nil {
+			into = protoReq.Mutable(b.body).Message().Interface()
+		}
+		newReader, berr := utilities.IOReaderFactory(req.Body)
+		if berr != nil {
+			return nil, metadata, runtime.RequestBodyError(berr)
+		}
+		if err := marshaler.NewDecoder(newReader()).Decode(into); err != nil && err != io.EOF {
+			runtime.RequestHandledFor(ctx, meth, nil, &metadata, err)
+			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+
+	for _, v := range b.pattern.Vars() {
+		val, ok := pathParams[v]
+		if !ok {
+			return nil, metadata, status.Errorf(codes.InvalidArgument, "missing parameter %s", v)
+		}
+		if err := runtime.PopulateFieldFromPath(protoReq, v, val); err != nil {
+			return nil, metadata, status.Errorf(codes.InvalidArgument, "type mismatch, parameter: %s, error: %v", v, err)
+		}
+	}
+
+	if b.filter != nil {
+		if err := req.ParseForm(); err != nil {
+			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		if err := runtime.PopulateQueryParameters(protoReq, req.Form, b.filter); err != nil {
+			return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+
+	runtime.RequestParsedFor(ctx, meth, protoReq, &metadata)
+	ctx = runtime.PreLoadBalance(ctx, s.svc.Balancer, b.opts.HashKey, protoReq)
+	ctx = runtime.WithServiceMethod(ctx, meth)
+	var msg proto.Message = dynamicpb.NewMessage(b.method.Output())
+	err := conn.Invoke(ctx, rpcMethodName, protoReq, msg, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	if err != nil {
+		msg = nil
+	}
+	runtime.RequestHandledFor(ctx, meth, msg, &metadata, err)
+	if err == nil && b.responseBody != nil {
+		msg = responseBody{Message: msg, field: b.responseBody}
+	}
+	return msg, metadata, err
+}
+
+// responseBody writes the response_body field of the response as the body.
+type responseBody struct {
+	proto.Message
+	field protoreflect.FieldDescriptor
+}
+
+func (r responseBody) XXX_ResponseBody() interface{} {
+	v := r.ProtoReflect().Get(r.field)
+	if r.field.Message() != nil {
+		return v.Message().Interface()
+	}
+	return v.Interface()
+}
+
+// group enables and disables a service group of the dynamic services only,
+// as the generated Enable and Disable functions of a service group do.
+type group struct {
+	spec *skypb.ServiceSpec
+
+	mu     sync.Mutex
+	skycli client.ServiceCli
+}
+
+func (g *group) enable() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.skycli = client.NewServiceCli(runtime.CallerServiceId)
+	g.skycli.AddUnaryInterceptor(runtime.ClientInterceptor(g.spec))
+	g.skycli.Resolve(g.spec)
+	g.skycli.Start(func(spec *skypb.ServiceSpec, conn *grpc.ClientConn) {
+		sg := runtime.GetServiceGroup(spec)
+		for _, svc := range sg.Services {
+			svc.SetConn(conn)
+			svc.Enable(spec, conn)
+		}
+	})
+}
+
+func (g *group) disable() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.skycli != nil {
+		sg := runtime.GetServiceGroup(g.spec)
+		for _, svc := range sg.Services {
+			svc.Disable()
+			svc.SetConn(nil)
+		}
+
+		g.skycli.Shutdown()
+		g.skycli = nil
+	}
+}
@@ -0,0 +1,48 @@
+package integrate
+
+import (
+	"flag"
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+)
+
+var (
+	enableGRPCProxy = flag.Bool("enable-grpc-proxy", false, "Whether to serve the native gRPC calls over HTTP/2 of the methods with HTTP bindings on the gateway port, by their gRPC method names. HTTP/2 without TLS is accepted as well.")
+)
+
+func newGRPCMiddleware(mux *runtime.ServeMux) (Middleware, error) {
+	if !*enableGRPCProxy {
+		return nil, nil
+	}
+	return func(next http.Handler) http.Handler {
+		return &GRPCMiddleware{Handler: next, Mux: mux}
+	}, nil
+}
+
+// GRPCMiddleware proxies the native gRPC calls to the services of the mux,
+// and passes the other requests to the handler.
+type GRPCMiddleware struct {
+	Handler http.Handler
+	Mux     *runtime.ServeMux
+}
+
+func (m *GRPCMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if runtime.IsGRPCRequest(r) {
+		m.Mux.ServeGRPC(w, r)
+		return
+	}
+	m.Handler.ServeHTTP(w, r)
+}
+
+// withH2C serves HTTP/2 without TLS on the handler, for the gRPC clients of
+// a plaintext gateway port.
+func withH2C(h http.Handler) http.Handler {
+	if !*enableGRPCProxy {
+		return h
+	}
+	return h2c.NewHandler(h, &http2.Server{})
+}
@@ -0,0 +1,37 @@
+package integrate
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+)
+
+var (
+	enableGRPCWeb = flag.Bool("enable-grpc-web", false, "Whether to serve the gRPC-Web calls of the methods with HTTP bindings, by their gRPC method names.")
+)
+
+func newGRPCWebMiddleware(mux *runtime.ServeMux) (Middleware, error) {
+	if !*enableGRPCWeb {
+		return nil, nil
+	}
+	return func(next http.Handler) http.Handler {
+		return &GRPCWebMiddleware{Handler: next, Mux: mux}
+	}, nil
+}
+
+// GRPCWebMiddleware proxies the gRPC-Web calls, in the binary or the text
+// format, to the services of the mux, and passes the other requests to the
+// handler.
+type GRPCWebMiddleware struct {
+	Handler http.Handler
+	Mux     *runtime.ServeMux
+}
+
+func (m *GRPCWebMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if runtime.IsGRPCWebRequest(r) {
+		m.Mux.ServeGRPCWeb(w, r)
+		return
+	}
+	m.Handler.ServeHTTP(w, r)
+}
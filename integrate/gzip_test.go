@@ -2,24 +2,46 @@ package integrate
 
 import (
 	"bytes"
+	"io/ioutil"
 	"testing"
+
+	"github.com/klauspost/compress/gzip"
 )
 
+func gunzip(t *testing.T, b []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() failed with %v", err)
+	}
+	d, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading gzip data failed with %v", err)
+	}
+	return string(d)
+}
+
 func TestCompress(t *testing.T) {
 	s := bytes.NewBufferString(`"in order to test gzip compress.in order to test gzip compress.
 	                             in order to test gzip compress.in order to test gzip compress.
 	                             in order to test gzip compress.in order to test gzip compress.
 	                             in order to test gzip compress.in order to test gzip compress."`)
-	var b bytes.Buffer
-	t.Log(b.Len())
-	size, err := Compress(&b, s)
+	e, err := builtinEncoding("gzip")
 	if err != nil {
-		t.Error(err)
+		t.Fatalf("builtinEncoding(gzip) failed with %v", err)
 	}
-	if size > 0 {
-		t.Log(size, ",", s.Len(), ",", b.Len())
-	} else {
-		t.Error("compress error")
+	var b bytes.Buffer
+	enc := e.get(&b)
+	defer e.put(enc)
+	if _, err := enc.Write(s.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() == 0 || b.Len() >= s.Len() {
+		t.Errorf("compressed %d bytes to %d", s.Len(), b.Len())
+	}
+	if got := gunzip(t, b.Bytes()); got != s.String() {
+		t.Errorf("gunzip() = %q; want %q", got, s.String())
 	}
-
 }
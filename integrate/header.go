@@ -0,0 +1,113 @@
+package integrate
+
+import (
+	"flag"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+)
+
+var (
+	trustedHeaders = flag.String("trusted-headers", strings.Join([]string{XUid, XCid, XAid, XRealIp}, ","),
+		"Comma separated headers which are always stripped from inbound requests. Only the gateway itself may set them.")
+	forwardHeaders = flag.String("forward-headers", strings.Join(defaultForwardHeaders, ","),
+		"Comma separated headers which are forwarded to backend services as gRPC metadata.")
+
+	// defaultForwardHeaders 默认转发给后端服务的http header. x-forwarded-for
+	// is added by runtime.AnnotateContext from the resolved client IP.
+	defaultForwardHeaders = []string{
+		XSource, XClient, XUid, XCid, XAid, XSid, XDid, XAppVersion, XTs, XSign,
+		XRequestId, XLocale, XClientId, XCorpCode, XRealIp,
+	}
+)
+
+// headerPolicy decides which inbound http headers reach the backend services.
+type headerPolicy struct {
+	// trusted headers are stripped from inbound requests, so that only the
+	// values set by the gateway's own auth step are forwarded.
+	trusted map[string]bool
+	// forward is the allow-list of headers forwarded as gRPC metadata.
+	forward map[string]bool
+}
+
+// newHeaderPolicy returns a headerPolicy with the given trusted and forwarded
+// header names.
+func newHeaderPolicy(trusted, forward []string) *headerPolicy {
+	return &headerPolicy{
+		trusted: headerSet(trusted),
+		forward: headerSet(forward),
+	}
+}
+
+// newHeaderPolicyFromFlags returns a headerPolicy configured by flags
+// --trusted-headers and --forward-headers.
+func newHeaderPolicyFromFlags() *headerPolicy {
+	return newHeaderPolicy(splitFlag(*trustedHeaders), splitFlag(*forwardHeaders))
+}
+
+// IncomingHeaderMatcher returns the header matcher of the mux, with which
+// only the headers of flag --forward-headers reach the backend services as
+// gRPC metadata.
+func IncomingHeaderMatcher() runtime.HeaderMatcherFunc {
+	return newHeaderPolicyFromFlags().match
+}
+
+// strip removes all trusted headers supplied by the client, also those sent
+// as gRPC metadata with runtime.MetadataHeaderPrefix.
+func (p *headerPolicy) strip(h http.Header) {
+	for k := range h {
+		if p.trusted[metadataKey(k)] {
+			delete(h, k)
+		}
+	}
+}
+
+// match forwards the allowed headers, also those sent with
+// runtime.MetadataHeaderPrefix, as gRPC metadata.
+func (p *headerPolicy) match(key string) (string, bool) {
+	key = metadataKey(key)
+	return key, p.forward[key]
+}
+
+// metadataKey returns the lower-cased header name without
+// runtime.MetadataHeaderPrefix.
+func metadataKey(name string) string {
+	name = strings.TrimPrefix(textproto.CanonicalMIMEHeaderKey(name), runtime.MetadataHeaderPrefix)
+	return strings.ToLower(name)
+}
+
+// outgoingMD returns the gRPC metadata of the allowed headers.
+func (p *headerPolicy) outgoingMD(h http.Header) metadata.MD {
+	md := metadata.MD{}
+	for k, vs := range h {
+		key := strings.ToLower(k)
+		if p.forward[key] && len(vs) > 0 {
+			md[key] = append(md[key], vs...)
+		}
+	}
+	return md
+}
+
+// headerSet returns the lower-cased set of the given header names.
+func headerSet(names []string) map[string]bool {
+	s := make(map[string]bool, len(names))
+	for _, n := range names {
+		s[strings.ToLower(n)] = true
+	}
+	return s
+}
+
+// splitFlag splits a comma separated flag value, skipping empty items.
+func splitFlag(v string) []string {
+	items := []string{}
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			items = append(items, s)
+		}
+	}
+	return items
+}
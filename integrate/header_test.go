@@ -0,0 +1,178 @@
+package integrate
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+)
+
+func TestHeaderPolicyStrip(t *testing.T) {
+	p := newHeaderPolicy([]string{XUid, XCid, XAid}, defaultForwardHeaders)
+	h := http.Header{}
+	h.Set(XUid, "1")
+	h.Set(XCid, "2")
+	h.Set(XAid, "3")
+	h.Set(runtime.MetadataHeaderPrefix+XUid, "4")
+	h.Set(XSource, ResourceWeb)
+
+	p.strip(h)
+	for _, k := range []string{XUid, XCid, XAid, runtime.MetadataHeaderPrefix + XUid} {
+		if v := h.Get(k); v != "" {
+			t.Errorf("h.Get(%q) = %q; want empty", k, v)
+		}
+	}
+	if v := h.Get(XSource); v != ResourceWeb {
+		t.Errorf("h.Get(%q) = %q; want %q", XSource, v, ResourceWeb)
+	}
+}
+
+func TestHeaderPolicyOutgoingMD(t *testing.T) {
+	for _, spec := range []struct {
+		forward []string
+		header  http.Header
+		want    metadata.MD
+	}{
+		{
+			forward: []string{XSource, XUid},
+			header: http.Header{
+				"X-Source": []string{"web"},
+				"X-Uid":    []string{"10"},
+				"Cookie":   []string{"sid=1"},
+			},
+			want: metadata.MD{"x-source": []string{"web"}, "x-uid": []string{"10"}},
+		},
+		{
+			forward: []string{"X-Locale"},
+			header: http.Header{
+				"X-Locale": []string{"zh_CN", "en_US"},
+			},
+			want: metadata.MD{"x-locale": []string{"zh_CN", "en_US"}},
+		},
+		{
+			forward: nil,
+			header: http.Header{
+				"X-Source": []string{"web"},
+			},
+			want: metadata.MD{},
+		},
+	} {
+		p := newHeaderPolicy(nil, spec.forward)
+		if got := p.outgoingMD(spec.header); !reflect.DeepEqual(got, spec.want) {
+			t.Errorf("outgoingMD(%v) = %v; want %v", spec.header, got, spec.want)
+		}
+	}
+}
+
+func TestHeaderPolicyMatch(t *testing.T) {
+	p := newHeaderPolicy(nil, []string{XSource, XUid})
+	for _, spec := range []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{key: "X-Source", want: "x-source", ok: true},
+		{key: "x-uid", want: "x-uid", ok: true},
+		{key: "Grpc-Metadata-X-Source", want: "x-source", ok: true},
+		{key: "Cookie", want: "cookie", ok: false},
+		{key: "Grpc-Metadata-X-Other", want: "x-other", ok: false},
+	} {
+		if got, ok := p.match(spec.key); got != spec.want || ok != spec.ok {
+			t.Errorf("match(%q) = %q, %t; want %q, %t", spec.key, got, ok, spec.want, spec.ok)
+		}
+	}
+}
+
+// TestForwardedMetadata checks the metadata a backend service receives from
+// a handler calling the hook and runtime.AnnotateContext as the generated
+// handlers do.
+func TestForwardedMetadata(t *testing.T) {
+	defer func(v bool) { *debugMode = v }(*debugMode)
+	*debugMode = true
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan metadata.MD, 1)
+	s := grpc.NewServer(grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		received <- md
+		in := new(wrapperspb.StringValue)
+		if err := stream.RecvMsg(in); err != nil {
+			return err
+		}
+		return stream.SendMsg(in)
+	}))
+	go s.Serve(l)
+	defer s.Stop()
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	gh := newTestIPHook(t)
+	gh.headers = newHeaderPolicy(splitFlag(*trustedHeaders), defaultForwardHeaders)
+	mux := runtime.NewServeMux(runtime.WithIncomingHeaderMatcher(gh.headers.match))
+	svc := &runtime.Service{Name: "EchoService"}
+	m := &runtime.Method{Name: "Echo", HttpMethod: "GET", Path: "/v1/echo"}
+	m.SetEnabled(true)
+	err = mux.HandlePath("GET", "/v1/echo", 1, func(inctx context.Context, w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		if _, err := gh.RequestAccepted(req.Context(), svc, m, w, req); err != nil {
+			t.Errorf("RequestAccepted() failed with %v", err)
+			return
+		}
+		ctx, err := runtime.AnnotateContext(req.Context(), mux, req, "/test.EchoService/Echo")
+		if err != nil {
+			t.Errorf("AnnotateContext() failed with %v", err)
+			return
+		}
+		if err := conn.Invoke(ctx, "/test.EchoService/Echo", wrapperspb.String("hi"), new(wrapperspb.StringValue)); err != nil {
+			t.Errorf("Invoke() failed with %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("mux.HandlePath() failed with %v", err)
+	}
+
+	r := httptest.NewRequest("GET", "/v1/echo", nil)
+	r.RemoteAddr = "203.0.113.1:1234"
+	r.Header.Set(XUid, "1")
+	r.Header.Set(runtime.MetadataHeaderPrefix+XCid, "2")
+	r.Header.Set(XSource, ResourceWeb)
+	r.Header.Set(XForwardedFor, "192.168.0.1")
+	r.Header.Set("Cookie", "sid=1")
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+
+	var md metadata.MD
+	select {
+	case md = <-received:
+	default:
+		t.Fatal("the backend was not called")
+	}
+	for k, want := range map[string]string{XUid: *debugUid, XCid: *debugCid, XSource: ResourceWeb, XRealIp: "203.0.113.1", XForwardedFor: "203.0.113.1"} {
+		if got := md.Get(k); len(got) != 1 || got[0] != want {
+			t.Errorf("metadata %s = %q; want [%s]", k, got, want)
+		}
+	}
+	if got := md.Get("cookie"); len(got) != 0 {
+		t.Errorf("metadata cookie = %q; want none", got)
+	}
+}
+
+func TestSplitFlag(t *testing.T) {
+	got := splitFlag(" x-uid, ,x-cid,")
+	want := []string{"x-uid", "x-cid"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitFlag() = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+// Package hedge implements hedged requests: a second request is sent if the
+// first one has not answered within a delay, and whichever answers first is
+// used.
+package hedge
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/binchencoder/janus-gateway/integrate/retry"
+)
+
+// Call is one attempt of a hedged request. The attempts run concurrently, so
+// each of them must write its result to its own storage.
+type Call func(ctx context.Context) error
+
+// Outcome describes how a hedged request went.
+type Outcome struct {
+	// Hedged is true if the hedged attempt was issued.
+	Hedged bool
+	// HedgeWon is true if the result of the hedged attempt was used.
+	HedgeWon bool
+}
+
+type result struct {
+	hedge bool
+	err   error
+}
+
+// Do calls primary, and if it has not returned after delay and the budget
+// allows it, calls hedge concurrently. It returns the first successful
+// result, or the error of primary if both attempts fail. The losing attempt
+// is canceled.
+func Do(ctx context.Context, delay time.Duration, budget *retry.Budget, primary, hedge Call) (Outcome, error) {
+	if budget != nil {
+		budget.Deposit()
+	}
+
+	pctx, pcancel := context.WithCancel(ctx)
+	defer pcancel()
+	hctx, hcancel := context.WithCancel(ctx)
+	defer hcancel()
+
+	results := make(chan result, 2)
+	go func() {
+		results <- result{false, primary(pctx)}
+	}()
+
+	out := Outcome{}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case r := <-results:
+		return out, r.err
+	case <-t.C:
+	}
+
+	if budget != nil && !budget.Withdraw() {
+		r := <-results
+		return out, r.err
+	}
+	out.Hedged = true
+	go func() {
+		results <- result{true, hedge(hctx)}
+	}()
+
+	var perr error
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.err == nil {
+			out.HedgeWon = r.hedge
+			return out, nil
+		}
+		if !r.hedge {
+			perr = r.err
+		}
+	}
+	return out, perr
+}
@@ -0,0 +1,103 @@
+package hedge
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/binchencoder/janus-gateway/integrate/retry"
+)
+
+var errFailed = errors.New("failed")
+
+// sleep returns a Call which returns err after d, or the context error if
+// canceled before.
+func sleep(d time.Duration, err error) Call {
+	return func(ctx context.Context) error {
+		select {
+		case <-time.After(d):
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	for _, spec := range []struct {
+		name           string
+		primary, hedge Call
+		want           Outcome
+		wantErr        error
+	}{
+		{
+			name:    "fast primary",
+			primary: sleep(0, nil),
+			hedge:   sleep(0, nil),
+			want:    Outcome{},
+		},
+		{
+			name:    "fast primary failure",
+			primary: sleep(0, errFailed),
+			hedge:   sleep(0, nil),
+			want:    Outcome{},
+			wantErr: errFailed,
+		},
+		{
+			name:    "hedge wins",
+			primary: sleep(time.Second, nil),
+			hedge:   sleep(0, nil),
+			want:    Outcome{Hedged: true, HedgeWon: true},
+		},
+		{
+			name:    "primary wins",
+			primary: sleep(20*time.Millisecond, nil),
+			hedge:   sleep(time.Second, nil),
+			want:    Outcome{Hedged: true},
+		},
+		{
+			name:    "hedge fails",
+			primary: sleep(20*time.Millisecond, nil),
+			hedge:   sleep(0, errFailed),
+			want:    Outcome{Hedged: true},
+		},
+		{
+			name:    "both fail",
+			primary: sleep(20*time.Millisecond, errFailed),
+			hedge:   sleep(0, errors.New("hedge failed")),
+			want:    Outcome{Hedged: true},
+			wantErr: errFailed,
+		},
+	} {
+		got, err := Do(context.Background(), 10*time.Millisecond, nil, spec.primary, spec.hedge)
+		if got != spec.want || err != spec.wantErr {
+			t.Errorf("%s: Do() = %+v, %v; want %+v, %v", spec.name, got, err, spec.want, spec.wantErr)
+		}
+	}
+}
+
+func TestDoBudget(t *testing.T) {
+	budget := retry.NewBudget(0, 0)
+	got, err := Do(context.Background(), time.Millisecond, budget, sleep(20*time.Millisecond, nil), sleep(0, nil))
+	if got.Hedged || err != nil {
+		t.Errorf("Do() without budget = %+v, %v; want not hedged", got, err)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker()
+	for i := 1; i < trackerMinSamples; i++ {
+		tr.Observe(time.Duration(i) * time.Millisecond)
+	}
+	if _, ok := tr.P95(); ok {
+		t.Errorf("P95() with %d samples is ok; want not ok", trackerMinSamples-1)
+	}
+	for i := trackerMinSamples; i <= trackerSamples; i++ {
+		tr.Observe(time.Duration(i) * time.Millisecond)
+	}
+	if got, ok := tr.P95(); !ok || got != 121*time.Millisecond {
+		t.Errorf("P95() = %v, %t; want %v, true", got, ok, 121*time.Millisecond)
+	}
+}
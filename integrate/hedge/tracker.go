@@ -0,0 +1,61 @@
+package hedge
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// trackerSamples is the number of the latest latencies kept by a Tracker.
+	trackerSamples = 128
+	// trackerMinSamples is the number of latencies needed for a percentile.
+	trackerMinSamples = 32
+	// trackerRefresh is the number of latencies between two computations of
+	// the percentile.
+	trackerRefresh = 16
+)
+
+// Tracker tracks the p95 latency of a method over its latest requests.
+type Tracker struct {
+	mu      sync.Mutex
+	samples [trackerSamples]time.Duration
+	n       int
+	p95     time.Duration
+}
+
+// NewTracker returns a new Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Observe records the latency of a request.
+func (t *Tracker) Observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.samples[t.n%trackerSamples] = d
+	t.n++
+	if t.n >= trackerMinSamples && t.n%trackerRefresh == 0 {
+		t.p95 = t.percentile(0.95)
+	}
+}
+
+// P95 returns the p95 latency, and false if too few requests have been
+// observed.
+func (t *Tracker) P95() (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.p95, t.p95 > 0
+}
+
+func (t *Tracker) percentile(q float64) time.Duration {
+	n := t.n
+	if n > trackerSamples {
+		n = trackerSamples
+	}
+	s := make([]time.Duration, n)
+	copy(s, t.samples[:n])
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	return s[int(q*float64(n-1))]
+}
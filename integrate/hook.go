@@ -15,7 +15,9 @@ import (
 
 	"github.com/binchencoder/janus-gateway/gateway/runtime"
 	options "github.com/binchencoder/janus-gateway/httpoptions"
+	"github.com/binchencoder/janus-gateway/integrate/ipfilter"
 	"github.com/binchencoder/janus-gateway/integrate/metrics"
+	"github.com/binchencoder/janus-gateway/integrate/ratelimit"
 	"github.com/binchencoder/janus-gateway/util"
 	"github.com/binchencoder/letsgo/grpc"
 	"github.com/binchencoder/letsgo/trace"
@@ -43,8 +45,13 @@ var (
 // gatewayHook implements interface GatewayServiceHook in package
 // github.com/binchencoder/janus-gateway/gateway/runtime.
 type gatewayHook struct {
-	mux  *runtime.ServeMux
-	host string
+	mux      *runtime.ServeMux
+	host     string
+	headers  *headerPolicy
+	limiter  *ratelimit.Limiter
+	ips      *ipfilter.Resolver
+	ipFilter *ipfilter.Filter
+	apis     ApiConfigStore
 }
 
 // Bootstrap starts the gateway and sets up the housekeeping goroutine.
@@ -55,6 +62,44 @@ func (gh *gatewayHook) Bootstrap(sgs map[string]*runtime.ServiceGroup) error {
 		panic("No program service was found.")
 	}
 
+	limiter, err := newLimiter()
+	if err != nil {
+		return err
+	}
+	gh.limiter = limiter
+
+	if gh.ips, err = newClientIPResolver(); err != nil {
+		return err
+	}
+	if gh.ipFilter, err = newIPFilter(); err != nil {
+		return err
+	}
+	if gh.apis, err = newApiConfigStore(); err != nil {
+		return err
+	}
+
+	ci, err := newConcurrencyInterceptor()
+	if err != nil {
+		return err
+	}
+	if ci != nil {
+		runtime.AddClientInterceptor(ci)
+	}
+	// The circuit breakers run after the concurrency limiter, so that they
+	// only observe the results of the backends.
+	bi, err := newBreakerInterceptor()
+	if err != nil {
+		return err
+	}
+	if bi != nil {
+		runtime.AddClientInterceptor(bi)
+	}
+	// The retries and the hedges run last, so that a request takes one
+	// concurrency slot and one circuit breaker outcome regardless of its
+	// attempts.
+	runtime.AddClientInterceptor(newRetryInterceptor())
+	runtime.AddClientInterceptor(newHedgeInterceptor())
+
 	return gh.bootstrap(sgs)
 }
 
@@ -84,6 +129,9 @@ func (gh *gatewayHook) RequestReceived(w http.ResponseWriter, r *http.Request) (
 
 func (gh *gatewayHook) RequestAccepted(ctx context.Context, svc *runtime.Service, m *runtime.Method, w http.ResponseWriter,
 	r *http.Request) (context.Context, error) {
+	// 移除客户端伪造的可信header, 只允许网关自己设置.
+	gh.headers.strip(r.Header)
+	gh.resolveClientIP(r)
 	if m.IsThirdParty {
 		r.Header.Set(XSource, ResourceThird)
 	}
@@ -100,12 +148,9 @@ func (gh *gatewayHook) RequestAccepted(ctx context.Context, svc *runtime.Service
 
 	ctx, err := gh.requestAccepted(ctx, svc, m, w, r)
 
-	// 转发全部http header
-	h := make(map[string]string)
-	for k, v := range r.Header {
-		h[k] = v[0]
-	}
-	ctxret := metadata.NewOutgoingContext(ctx, metadata.New(h))
+	// 白名单内的http header, 用于记录日志. 后端服务收到的metadata由
+	// IncomingHeaderMatcher在runtime.AnnotateContext中生成.
+	ctxret := metadata.NewOutgoingContext(ctx, gh.headers.outgoingMD(r.Header))
 	return ctxret, err
 }
 
@@ -144,8 +189,9 @@ func (gh *gatewayHook) RequestHandled(ctx context.Context, svc *runtime.Service,
 // NewGatewayHook returns a new gatewayHook.
 func NewGatewayHook(mux *runtime.ServeMux, host string) runtime.GatewayServiceHook {
 	return &gatewayHook{
-		mux:  mux,
-		host: host,
+		mux:     mux,
+		host:    host,
+		headers: newHeaderPolicyFromFlags(),
 	}
 }
 
@@ -38,14 +38,17 @@ func (gh *gatewayHook) bootstrap(sgs map[string]*runtime.ServiceGroup) error {
 				// 判断是否为此网关的注册api, 如果不是则从slice中移除
 				if !isGatewayApi(m) {
 					svc.Methods = append(svc.Methods[:i], svc.Methods[i+1:]...)
-					util.Logf(util.DefaultLogger, "  [Remove]%s,%s,%s,%t,%t,%t.", m.Name, m.Path, m.HttpMethod, m.Enabled, m.LoginRequired, m.ClientSignRequired)
+					util.Logf(util.DefaultLogger, "  [Remove]%s,%s,%s,%t,%t,%t.", m.Name, m.Path, m.HttpMethod, m.IsEnabled(), m.LoginRequired, m.ClientSignRequired)
 				} else {
 					i++
-					util.Logf(util.DefaultLogger, "  ======>%s,%s,%s,%t,%t,%t.", m.Name, m.Path, m.HttpMethod, m.Enabled, m.LoginRequired, m.ClientSignRequired)
+					util.Logf(util.DefaultLogger, "  ======>%s,%s,%s,%t,%t,%t.", m.Name, m.Path, m.HttpMethod, m.IsEnabled(), m.LoginRequired, m.ClientSignRequired)
 				}
 			}
 		}
 	}
+	if err := checkRouteConflicts(gh.mux); err != nil {
+		return err
+	}
 	if *debugMode { // debug模式,直接启动,不使用etcd配置
 		if *debugService == "" {
 			panic("The flag debug-service is null.")
@@ -54,15 +57,15 @@ func (gh *gatewayHook) bootstrap(sgs map[string]*runtime.ServiceGroup) error {
 			spec := sg.Spec
 			if strings.Contains(*debugService, spec.ServiceName) {
 				util.Logf(util.DefaultLogger, "&&&Start service %s.", spec.ServiceName)
-				go sg.Enable()
+				sg.Switch(true)
 			} else {
 				util.Logf(util.DefaultLogger, "&&&Do not service %s.", spec.ServiceName)
 			}
 		}
 	} else {
-		// TODO(chenbin): 由于没有后台管理来控制APIs的开启和关闭, 默认全部开启
+		// 默认全部开启, 之后可通过admin接口(--admin-addr)开启和关闭.
 		for _, sg := range sgs {
-			go sg.Enable()
+			sg.Switch(true)
 		}
 
 		// initEtcd()
@@ -90,6 +93,11 @@ func (gh *gatewayHook) bootstrap(sgs map[string]*runtime.ServiceGroup) error {
 
 func (gh *gatewayHook) requestAccepted(ctx context.Context, svc *runtime.Service, m *runtime.Method, w http.ResponseWriter,
 	r *http.Request) (context.Context, error) {
+	// api关闭状态, 由admin接口控制.
+	if !m.IsEnabled() {
+		xt, _ := ctx.Value(RequestReceivedTime).(time.Time)
+		return apiDisabled(ctx, svc, m, getClientFromHeader(r.Header), trace.GetTraceIdOrEmpty(ctx), xt)
+	}
 	// 新增debug模式,默认uid和cid
 	if *debugMode {
 		if r.Header.Get(XUid) == "" {
@@ -102,51 +110,29 @@ func (gh *gatewayHook) requestAccepted(ctx context.Context, svc *runtime.Service
 			r.Header.Set(XAid, *debugAid)
 		}
 	} else {
-		// // client.
-		// clt := getClientFromHeader(r.Header)
-		// // traceid.
-		// tid := trace.GetTraceIdOrEmpty(ctx)
-		// xt, _ := ctx.Value(RequestReceivedTime).(time.Time)
-
 		// 校验请求的http header.
 		if err := verifyHeader(ctx, r.Header, svc, m); err != nil {
 			return ctx, err
 		}
-		// // 获取api存储配置信息.
-		// api, ok := cache.GetApi(m.HttpMethod, m.Path)
-		// if !ok {
-		// 	return apiNil(ctx, svc, m, clt, tid, xt)
-		// }
-		// // api开启状态.
-		// if !api.Enabled {
-		// 	return apiDisabled(ctx, svc, m, clt, tid, xt)
-		// }
+		// api存储配置的访问策略.
+		if err := gh.apiPolicy(ctx, r, svc, m); err != nil {
+			return ctx, err
+		}
+	}
 
-		// // 判断SourceAllow.
-		// if !api.IsThirdParty && api.SourceAllow.String != "all" && r.Header.Get(XSource) != api.SourceAllow.String {
-		// 	return apiForbidden(ctx, svc, m, clt, tid, xt)
-		// }
-		// // api验签校验.
-		// if api.SignRequired && !api.IsThirdParty {
-		// 	if ok, err := checkSign(r); !ok {
-		// 		return apiSignErr(ctx, svc, m, clt, tid, xt, err)
-		// 	}
-		// }
+	// 客户端IP访问控制.
+	if err := gh.ipAccess(ctx, r, svc, m); err != nil {
+		return ctx, err
+	}
 
-		// // api登录校验.
-		// if api.LoginRequired && !api.IsThirdParty {
-		// 	authS := NewExternalAuthServer(util.AuthClient, util.AuthNClient, authRedis, mgwRedis)
-		// 	if err := authS.Authenticate(r, m); err != nil {
-		// 		return apiLoginErr(ctx, svc, m, clt, tid, xt, err)
-		// 	}
-		// }
+	// api限流.
+	if err := gh.apiLimit(ctx, w, r, svc, m); err != nil {
+		return ctx, err
+	}
 
-		// // api限流.
-		// if cof, ok := cache.GetApiLimit(m.HttpMethod, m.Path); ok {
-		// 	if err := apiLimit(ctx, r.Header, svc, m, xt, cof); err != nil {
-		// 		return ctx, err
-		// 	}
-		// }
+	// 请求body大小限制.
+	if err := limitBody(ctx, w, r, svc, m); err != nil {
+		return ctx, err
 	}
 
 	return ctx, nil
@@ -154,7 +140,7 @@ func (gh *gatewayHook) requestAccepted(ctx context.Context, svc *runtime.Service
 
 func getReqInfo(r *http.Request) string {
 	// remote address.
-	remoteAddr := r.Header.Get(XForwardedFor)
+	remoteAddr := clientIP(r)
 	if remoteAddr == "" {
 		remoteAddr = r.RemoteAddr
 	}
@@ -0,0 +1,141 @@
+package ipfilter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Rule restricts the client IPs of the API methods.
+type Rule struct {
+	// Name identifies the rule in logs.
+	Name string `yaml:"name"`
+	// HttpMethod and Path select the API methods the rule applies to. Empty
+	// values match all methods.
+	HttpMethod string `yaml:"http_method"`
+	Path       string `yaml:"path"`
+	// Allow lists the CIDRs allowed to call the methods. Empty allows all
+	// IPs not denied.
+	Allow []string `yaml:"allow"`
+	// Deny lists the CIDRs not allowed to call the methods, which take
+	// precedence over Allow.
+	Deny []string `yaml:"deny"`
+
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// Config is the IP access configuration.
+type Config struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// LoadConfig reads a YAML IP access configuration from the given file.
+func LoadConfig(file string) (*Config, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	conf := Config{}
+	if err := yaml.UnmarshalStrict(b, &conf); err != nil {
+		return nil, fmt.Errorf("parsing IP access config %s: %v", file, err)
+	}
+	return &conf, nil
+}
+
+// Filter decides whether a client IP may call an API method.
+type Filter struct {
+	rules []*Rule
+}
+
+// NewFilter returns a Filter with the given rules.
+func NewFilter(rules []*Rule) (*Filter, error) {
+	for i, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("IP access rule #%d has no name", i)
+		}
+		if len(r.Allow) == 0 && len(r.Deny) == 0 {
+			return nil, fmt.Errorf("IP access rule %s has neither allow nor deny list", r.Name)
+		}
+		var err error
+		if r.allow, err = parseCIDRs(r.Allow); err != nil {
+			return nil, fmt.Errorf("IP access rule %s: %v", r.Name, err)
+		}
+		if r.deny, err = parseCIDRs(r.Deny); err != nil {
+			return nil, fmt.Errorf("IP access rule %s: %v", r.Name, err)
+		}
+	}
+	return &Filter{rules: rules}, nil
+}
+
+// Allow returns whether the IP may call the API method, and the rule which
+// rejects it if not. An unknown IP is rejected by the rules with an allow
+// list.
+func (f *Filter) Allow(httpMethod, path string, ip net.IP) (bool, *Rule) {
+	if f == nil {
+		return true, nil
+	}
+	for _, r := range f.rules {
+		if !r.matches(httpMethod, path) {
+			continue
+		}
+		if ip != nil && contains(r.deny, ip) {
+			return false, r
+		}
+		if len(r.allow) > 0 && (ip == nil || !contains(r.allow, ip)) {
+			return false, r
+		}
+	}
+	return true, nil
+}
+
+// Len returns the number of the rules.
+func (f *Filter) Len() int {
+	if f == nil {
+		return 0
+	}
+	return len(f.rules)
+}
+
+func (r *Rule) matches(httpMethod, path string) bool {
+	return (r.HttpMethod == "" || strings.EqualFold(r.HttpMethod, httpMethod)) &&
+		(r.Path == "" || r.Path == path)
+}
+
+// parseCIDRs parses the CIDRs, taking a single IP as the CIDR of the host.
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", c)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package ipfilter
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolverClientIP(t *testing.T) {
+	r, err := NewResolver([]string{"10.0.0.0/8", "2001:db8::1"})
+	if err != nil {
+		t.Fatalf("NewResolver() failed with %v", err)
+	}
+	for _, spec := range []struct {
+		name       string
+		remoteAddr string
+		headers    map[string][]string
+		want       string
+	}{
+		{
+			name:       "direct",
+			remoteAddr: "203.0.113.1:1234",
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "spoofed from untrusted peer",
+			remoteAddr: "203.0.113.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.7"}},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "spoofed behind trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4, 203.0.113.7, 10.0.0.2"}},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "multiple headers",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.7", "10.0.0.2"}},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "all trusted",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "malformed hop",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.7, garbage, 10.0.0.2"}},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "forwarded",
+			remoteAddr: "[2001:db8::1]:443",
+			headers: map[string][]string{
+				"Forwarded":       {`for=1.2.3.4, for="[2001:db8:cafe::17]:4711";proto=https`},
+				"X-Forwarded-For": {"5.6.7.8"},
+			},
+			want: "2001:db8:cafe::17",
+		},
+		{
+			name:       "forwarded obfuscated",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"Forwarded": {"for=_hidden"}},
+			want:       "10.0.0.1",
+		},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = spec.remoteAddr
+		for k, vs := range spec.headers {
+			req.Header[k] = vs
+		}
+		if got := r.ClientIP(req); got.String() != spec.want {
+			t.Errorf("%s: ClientIP() = %v; want %s", spec.name, got, spec.want)
+		}
+	}
+}
+
+func TestNilResolverTrustsNoProxy(t *testing.T) {
+	var r *Resolver
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	if got := r.ClientIP(req); got.String() != "10.0.0.1" {
+		t.Errorf("ClientIP() = %v; want 10.0.0.1", got)
+	}
+}
+
+func TestFilterAllow(t *testing.T) {
+	f, err := NewFilter([]*Rule{
+		{Name: "admin", Path: "/v1/admin", Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.1.0.0/16"}},
+		{Name: "callback", HttpMethod: "post", Path: "/v1/callback", Allow: []string{"203.0.113.7", "2001:db8::/32"}},
+		{Name: "blocked", Deny: []string{"198.51.100.0/24"}},
+	})
+	if err != nil {
+		t.Fatalf("NewFilter() failed with %v", err)
+	}
+	for _, spec := range []struct {
+		httpMethod, path, ip string
+		wantRule             string
+	}{
+		{"GET", "/v1/admin", "10.0.0.1", ""},
+		{"GET", "/v1/admin", "10.1.0.1", "admin"},
+		{"GET", "/v1/admin", "203.0.113.7", "admin"},
+		{"GET", "/v1/admin", "", "admin"},
+		{"POST", "/v1/callback", "203.0.113.7", ""},
+		{"POST", "/v1/callback", "2001:db8::2", ""},
+		{"POST", "/v1/callback", "203.0.113.8", "callback"},
+		{"GET", "/v1/callback", "203.0.113.8", ""},
+		{"GET", "/v1/echo", "198.51.100.1", "blocked"},
+		{"GET", "/v1/echo", "", ""},
+	} {
+		ok, rule := f.Allow(spec.httpMethod, spec.path, net.ParseIP(spec.ip))
+		got := ""
+		if rule != nil {
+			got = rule.Name
+		}
+		if ok != (spec.wantRule == "") || got != spec.wantRule {
+			t.Errorf("Allow(%s, %s, %s) = %t, %q; want rule %q", spec.httpMethod, spec.path, spec.ip, ok, got, spec.wantRule)
+		}
+	}
+}
+
+func TestNewFilterErrors(t *testing.T) {
+	for _, spec := range []struct {
+		name string
+		rule *Rule
+	}{
+		{"no name", &Rule{Allow: []string{"10.0.0.0/8"}}},
+		{"no lists", &Rule{Name: "empty"}},
+		{"invalid cidr", &Rule{Name: "bad", Allow: []string{"10.0.0.0/33"}}},
+		{"invalid ip", &Rule{Name: "bad", Deny: []string{"10.0.0"}}},
+	} {
+		if _, err := NewFilter([]*Rule{spec.rule}); err == nil {
+			t.Errorf("%s: NewFilter() succeeded; want error", spec.name)
+		}
+	}
+}
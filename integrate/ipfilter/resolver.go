@@ -0,0 +1,97 @@
+package ipfilter
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolver resolves the real client IP of requests which may pass through
+// trusted proxies.
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// NewResolver returns a Resolver which trusts the X-Forwarded-For and
+// Forwarded headers set by the proxies in the given CIDRs. A single IP is
+// taken as a CIDR of the host.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	nets, err := parseCIDRs(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{trusted: nets}, nil
+}
+
+// ClientIP returns the IP of the client which sent the request.
+//
+// The headers are only trusted if the peer is a trusted proxy, and are walked
+// from the nearest hop back until a hop which is not a trusted proxy. The
+// Forwarded header takes precedence over X-Forwarded-For.
+func (r *Resolver) ClientIP(req *http.Request) net.IP {
+	peer := parseIP(req.RemoteAddr)
+	if peer == nil || !r.trust(peer) {
+		return peer
+	}
+	hops := forwardedFor(req.Header)
+	if hops == nil {
+		hops = xForwardedFor(req.Header)
+	}
+	ip := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseIP(hops[i])
+		if hop == nil {
+			// Obfuscated or malformed, nothing beyond it can be trusted.
+			break
+		}
+		ip = hop
+		if !r.trust(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func (r *Resolver) trust(ip net.IP) bool {
+	return r != nil && contains(r.trusted, ip)
+}
+
+// xForwardedFor returns the hops of the X-Forwarded-For headers, the nearest
+// last.
+func xForwardedFor(h http.Header) []string {
+	var hops []string
+	for _, v := range h.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(v, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// forwardedFor returns the "for" parameters of the Forwarded headers
+// (RFC 7239), the nearest last, or nil if there is no Forwarded header.
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	for _, v := range h.Values("Forwarded") {
+		for _, elem := range strings.Split(v, ",") {
+			hop := ""
+			for _, pair := range strings.Split(elem, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+					hop = strings.Trim(kv[1], `"`)
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseIP parses an IP address optionally with a port, such as
+// "192.0.2.1:80" and "[2001:db8::1]:80".
+func parseIP(s string) net.IP {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+}
@@ -0,0 +1,88 @@
+package integrate
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+	gr "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	fpb "github.com/binchencoder/gateway-proto/frontend"
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/integrate/ratelimit"
+	"github.com/binchencoder/janus-gateway/util"
+	"github.com/binchencoder/letsgo/trace"
+)
+
+var (
+	rateLimitConfig = flag.String("rate-limit-config", "", "The YAML file of rate limit rules.")
+)
+
+// newLimiter returns the rate limiter with the rules configured by flag
+// --rate-limit-config.
+func newLimiter() (*ratelimit.Limiter, error) {
+	rules := []*ratelimit.Rule{}
+	if *rateLimitConfig != "" {
+		conf, err := ratelimit.LoadConfig(*rateLimitConfig)
+		if err != nil {
+			return nil, err
+		}
+		rules = conf.Rules
+	}
+	util.Logf(util.ConfigLogger, "Loaded %d rate limit rules.", len(rules))
+	return ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rules), nil
+}
+
+// apiLimit checks the request against the configured rate limits and the
+// rate limit annotated on the API method.
+func (gh *gatewayHook) apiLimit(ctx context.Context, w http.ResponseWriter, r *http.Request, svc *runtime.Service, m *runtime.Method) error {
+	if gh.limiter == nil {
+		return nil
+	}
+
+	req := &ratelimit.Request{
+		HttpMethod: m.HttpMethod,
+		Path:       m.Path,
+		Client:     r.Header.Get(XClient),
+		Uid:        r.Header.Get(XUid),
+		Cid:        r.Header.Get(XCid),
+		IP:         clientIP(r),
+	}
+	extra := []*ratelimit.Rule{}
+	rate, burst := gh.rateLimit(m)
+	if mr := ratelimit.MethodRule(m.HttpMethod, m.Path, rate, burst); mr != nil {
+		extra = append(extra, mr)
+	}
+	ok, rule, wait := gh.limiter.Allow(req, extra...)
+	if ok {
+		return nil
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	xt, _ := ctx.Value(RequestReceivedTime).(time.Time)
+	_, err := apiLimited(ctx, svc, m, getClientFromHeader(r.Header), trace.GetTraceIdOrEmpty(ctx), xt, rule, wait)
+	return err
+}
+
+// apiLimited处理api被限流情况.
+func apiLimited(ctx context.Context, svc *runtime.Service, m *runtime.Method, clt, tid string, xt time.Time, rule *ratelimit.Rule, wait time.Duration) (context.Context, error) {
+	// prometheus metrics.
+	ms := addMetrics(ctx, svc, m, codes.ResourceExhausted, xt, clt)
+
+	// record limit logs.
+	util.Logf(util.LimitLogger, util.LimitFormat, tid, rule.Name, svc.Spec.GetServiceName(), m.HttpMethod, m.Path,
+		fmt.Sprintf("client:%s,rate:%g,burst:%d,retry:%v", clt, rule.Rate, rule.Burst, wait))
+	// record stat logs.
+	util.Logf(util.StatLogger, util.StatFormat, tid, svc.Spec.GetServiceName(), m.HttpMethod, m.Path, clt, "N", codes.ResourceExhausted, ms)
+
+	ger := grpcError(codes.ResourceExhausted, fpb.ErrorCode_BAD_REQUEST, []string{"Too many requests."})
+	// record rest logs.
+	util.Logf(util.RestLogger, util.ResponseRestFormat, tid, codes.ResourceExhausted, gr.ErrorDesc(ger))
+
+	return ctx, ger
+}
@@ -33,6 +33,62 @@ var (
 		},
 		[]string{"tag"},
 	)
+
+	// Create a gauge for record in-flight upstream requests of each service group.
+	gatewayInflightGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Subsystem: "upstream",
+			Name:      "inflight",
+			Help:      "Gateway in-flight upstream requests.",
+		},
+		[]string{"service_name"},
+	)
+
+	// Create a gauge for record the concurrency limit of each service group.
+	gatewayConcurrencyLimitGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Subsystem: "upstream",
+			Name:      "concurrency_limit",
+			Help:      "Gateway concurrency limit of upstream requests.",
+		},
+		[]string{"service_name"},
+	)
+
+	// Create a counter for record requests shed by the concurrency limiter.
+	gatewayShedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "upstream",
+			Name:      "shed",
+			Help:      "Gateway shed upstream request count.",
+		},
+		[]string{"service_name", "reason"},
+	)
+
+	// Create a gauge for record the state of each circuit breaker, 0 for
+	// closed, 1 for open and 2 for half-open.
+	gatewayBreakerStateGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Subsystem: "upstream",
+			Name:      "breaker_state",
+			Help:      "Gateway circuit breaker state.",
+		},
+		[]string{"breaker"},
+	)
+
+	// Create a counter for record hedged requests, issued and won.
+	gatewayHedgeCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "upstream",
+			Name:      "hedge",
+			Help:      "Gateway hedged upstream request count.",
+		},
+		[]string{"service_name", "result"},
+	)
 )
 
 func init() {
@@ -40,6 +96,12 @@ func init() {
 	prometheus.MustRegister(gatewayHandledHistogram)
 	// Register the counter with Prometheus's default registry.
 	prometheus.MustRegister(gatewayErrCounter)
+	// Register the upstream concurrency metrics.
+	prometheus.MustRegister(gatewayInflightGauge)
+	prometheus.MustRegister(gatewayConcurrencyLimitGauge)
+	prometheus.MustRegister(gatewayShedCounter)
+	prometheus.MustRegister(gatewayBreakerStateGauge)
+	prometheus.MustRegister(gatewayHedgeCounter)
 }
 
 // ReporterParam contains prometheus label value and other extra attribute.
@@ -70,3 +132,25 @@ func (reporter *ReporterParam) RequestComplete() float64 {
 func ErrCount(tag string) {
 	gatewayErrCounter.WithLabelValues(tag).Inc()
 }
+
+// Concurrency records the concurrency limit and in-flight requests of a
+// service group.
+func Concurrency(serviceName string, limit, inflight int) {
+	gatewayConcurrencyLimitGauge.WithLabelValues(serviceName).Set(float64(limit))
+	gatewayInflightGauge.WithLabelValues(serviceName).Set(float64(inflight))
+}
+
+// ShedCount may be invoked when a request of a service group is shed.
+func ShedCount(serviceName, reason string) {
+	gatewayShedCounter.WithLabelValues(serviceName, reason).Inc()
+}
+
+// BreakerState records the state of a circuit breaker.
+func BreakerState(breaker string, state int) {
+	gatewayBreakerStateGauge.WithLabelValues(breaker).Set(float64(state))
+}
+
+// HedgeCount may be invoked when a hedged request is issued or won.
+func HedgeCount(serviceName, result string) {
+	gatewayHedgeCounter.WithLabelValues(serviceName, result).Inc()
+}
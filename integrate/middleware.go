@@ -1,26 +1,269 @@
 package integrate
 
 import (
+	"bufio"
 	"flag"
+	"fmt"
+	"net"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/integrate/ipfilter"
+	"github.com/binchencoder/janus-gateway/util"
+	tm "github.com/binchencoder/letsgo/time"
+	"github.com/binchencoder/letsgo/trace"
 )
 
 var (
-	enableGzip = flag.Bool("gzip", true, "Whether to enable gzip.")
-	enableCors = flag.Bool("enable-cors", false, "Whether to enable HTTP access control.")
+	enableGzip  = flag.Bool("gzip", true, "Whether to enable response compression.")
+	enableCors  = flag.Bool("enable-cors", false, "Whether to enable HTTP access control.")
+	middlewares = flag.String("middlewares", "recovery,request-id,cors,limits,compress,decompress,grpc-web,grpc", "The HTTP middlewares from the outermost to the innermost. Available: "+strings.Join(MiddlewareNames(), ","))
 )
 
-// HttpMux 返回所需的封装Handler.
-func HttpMux(mux *runtime.ServeMux) http.Handler {
-	var sm http.Handler = mux
-	if *enableGzip {
-		sm = &GzipMiddleware{sm}
+// Middleware wraps a handler with a layer of processing.
+type Middleware func(next http.Handler) http.Handler
+
+// MiddlewareFactory returns the middleware for the mux, configured by the
+// flags. It returns a nil Middleware if the middleware is disabled by its
+// flags.
+type MiddlewareFactory func(mux *runtime.ServeMux) (Middleware, error)
+
+var (
+	middlewaresMu       sync.Mutex
+	middlewareFactories = map[string]MiddlewareFactory{
+		"recovery":   newRecoveryMiddleware,
+		"request-id": newRequestIdMiddleware,
+		"access-log": newAccessLogMiddleware,
+		"cors":       newCorsMiddleware,
+		"limits":     newLimitsMiddleware,
+		"compress":   newCompressMiddleware,
+		"decompress": newDecompressMiddleware,
+		"grpc-web":   newGRPCWebMiddleware,
+		"grpc":       newGRPCMiddleware,
+	}
+)
+
+// RegisterMiddleware registers a named middleware, which can then be listed
+// in flag --middlewares or NewChain. It replaces the middleware of the same
+// name.
+func RegisterMiddleware(name string, f MiddlewareFactory) {
+	middlewaresMu.Lock()
+	defer middlewaresMu.Unlock()
+	middlewareFactories[name] = f
+}
+
+// MiddlewareNames returns the names of the registered middlewares.
+func MiddlewareNames() []string {
+	middlewaresMu.Lock()
+	defer middlewaresMu.Unlock()
+	names := []string{}
+	for n := range middlewareFactories {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Chain is an ordered list of middlewares. The first one is the outermost.
+type Chain struct {
+	names       []string
+	middlewares []Middleware
+}
+
+// NewChain returns the chain of the named middlewares for the mux. The
+// middlewares disabled by their flags are left out.
+func NewChain(mux *runtime.ServeMux, names ...string) (*Chain, error) {
+	c := &Chain{}
+	for _, name := range names {
+		middlewaresMu.Lock()
+		f, ok := middlewareFactories[name]
+		middlewaresMu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown middleware %q", name)
+		}
+		m, err := f(mux)
+		if err != nil {
+			return nil, fmt.Errorf("middleware %s: %v", name, err)
+		}
+		if m != nil {
+			c.Append(name, m)
+		}
+	}
+	return c, nil
+}
+
+// Append adds a middleware as the innermost one of the chain.
+func (c *Chain) Append(name string, m Middleware) *Chain {
+	c.names = append(c.names, name)
+	c.middlewares = append(c.middlewares, m)
+	return c
+}
+
+// Names returns the names of the middlewares in the chain.
+func (c *Chain) Names() []string {
+	return append([]string(nil), c.names...)
+}
+
+// Then returns the handler wrapped by the middlewares of the chain.
+func (c *Chain) Then(h http.Handler) http.Handler {
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+	return h
+}
+
+// NewHttpMux 返回所需的封装Handler, with the middlewares listed by flag
+// --middlewares. It returns an error if a middleware is unknown or its flags
+// are invalid.
+func NewHttpMux(mux *runtime.ServeMux) (http.Handler, error) {
+	c, err := NewChain(mux, splitFlag(*middlewares)...)
+	if err != nil {
+		return nil, fmt.Errorf("setting up middlewares: %v", err)
+	}
+	util.Logf(util.ConfigLogger, "HTTP middlewares: %s.", strings.Join(c.Names(), ","))
+	return withH2C(c.Then(mux)), nil
+}
+
+func newCorsMiddleware(mux *runtime.ServeMux) (Middleware, error) {
+	if !*enableCors {
+		return nil, nil
+	}
+	policy, err := newCorsPolicyFromFlags()
+	if err != nil {
+		return nil, err
+	}
+	// The WebSocket handshakes from other origins are allowed by the policy
+	// too.
+	runtime.WithWebSocketOriginChecker(policy.AllowWebSocketOrigin)(mux)
+	return func(next http.Handler) http.Handler {
+		return &CorsMiddleware{Handler: next, Policy: policy}
+	}, nil
+}
+
+func newLimitsMiddleware(mux *runtime.ServeMux) (Middleware, error) {
+	if *maxRequestBodyBytes <= 0 {
+		return nil, nil
 	}
+	return func(next http.Handler) http.Handler {
+		return &LimitsMiddleware{Handler: next, MaxBodyBytes: *maxRequestBodyBytes, Mux: mux}
+	}, nil
+}
 
-	// if *enableCors {
-	// 	sm = &CorsMiddleware{sm}
-	// }
-	return sm
+func newCompressMiddleware(mux *runtime.ServeMux) (Middleware, error) {
+	if !*enableGzip {
+		return nil, nil
+	}
+	es, err := newEncodingsFromFlags()
+	if err != nil {
+		return nil, err
+	}
+	return func(next http.Handler) http.Handler {
+		return &CompressMiddleware{Handler: next, Encodings: es}
+	}, nil
+}
+
+func newDecompressMiddleware(mux *runtime.ServeMux) (Middleware, error) {
+	if !*decompressRequest {
+		return nil, nil
+	}
+	return func(next http.Handler) http.Handler {
+		return &DecompressMiddleware{Handler: next, MaxSize: *maxDecompressSize, Mux: mux}
+	}, nil
+}
+
+func newRequestIdMiddleware(mux *runtime.ServeMux) (Middleware, error) {
+	return RequestIdMiddleware, nil
+}
+
+// RequestIdMiddleware makes sure every request has the X-Request-Id header,
+// generating one if the client has not sent it, and echoes it in the
+// response.
+func RequestIdMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(XRequestId)
+		if id == "" {
+			id = trace.GenerateTraceId()
+			r.Header.Set(XRequestId, id)
+		}
+		w.Header().Set(XRequestId, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newAccessLogMiddleware(mux *runtime.ServeMux) (Middleware, error) {
+	ips, err := newClientIPResolver()
+	if err != nil {
+		return nil, err
+	}
+	return accessLogMiddleware(ips), nil
+}
+
+// AccessLogMiddleware records every request to util.AccessLogger, with the
+// peer address as the client address.
+func AccessLogMiddleware(next http.Handler) http.Handler {
+	return accessLogMiddleware(nil)(next)
+}
+
+// accessLogMiddleware returns the access log middleware which records the
+// client IP resolved by ips.
+func accessLogMiddleware(ips *ipfilter.Resolver) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			if sw.code == 0 {
+				sw.code = http.StatusOK
+			}
+			util.Logf(util.AccessLogger, util.AccessFormat, r.Header.Get(XRequestId), ips.ClientIP(r), r.Method, r.RequestURI,
+				sw.code, sw.size, tm.MillisecondSince(start))
+		})
+	}
+}
+
+// statusResponseWriter records the status code and the body size of a
+// response.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	code int
+	size int64
+}
+
+func (w *statusResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *statusResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection of a WebSocket upgrade, which is logged
+// as 101.
+func (w *statusResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T is not a http.Hijacker", w.ResponseWriter)
+	}
+	if w.code == 0 {
+		w.code = http.StatusSwitchingProtocols
+	}
+	return hj.Hijack()
 }
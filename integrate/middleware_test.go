@@ -0,0 +1,141 @@
+package integrate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+)
+
+// tagMiddleware returns a middleware which appends the tag to the X-Tags
+// header of the request.
+func tagMiddleware(tag string) MiddlewareFactory {
+	return func(mux *runtime.ServeMux) (Middleware, error) {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.Header.Add("X-Tags", tag)
+				next.ServeHTTP(w, r)
+			})
+		}, nil
+	}
+}
+
+func TestChain(t *testing.T) {
+	RegisterMiddleware("test-a", tagMiddleware("a"))
+	RegisterMiddleware("test-b", tagMiddleware("b"))
+	RegisterMiddleware("test-disabled", func(mux *runtime.ServeMux) (Middleware, error) {
+		return nil, nil
+	})
+	defer func() {
+		middlewaresMu.Lock()
+		delete(middlewareFactories, "test-a")
+		delete(middlewareFactories, "test-b")
+		delete(middlewareFactories, "test-disabled")
+		middlewaresMu.Unlock()
+	}()
+
+	c, err := NewChain(nil, "test-b", "test-disabled", "test-a")
+	if err != nil {
+		t.Fatalf("NewChain() failed with %v", err)
+	}
+	if got, want := strings.Join(c.Names(), ","), "test-b,test-a"; got != want {
+		t.Errorf("Names() = %s; want %s", got, want)
+	}
+
+	var tags []string
+	h := c.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tags = r.Header["X-Tags"]
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got, want := strings.Join(tags, ","), "b,a"; got != want {
+		t.Errorf("middlewares ran in order %s; want %s", got, want)
+	}
+
+	if _, err := NewChain(nil, "test-a", "unknown"); err == nil {
+		t.Errorf("NewChain() with unknown middleware succeeded; want error")
+	}
+}
+
+func TestChainDisabledByFlags(t *testing.T) {
+	defer func(v bool) { *enableCors = v }(*enableCors)
+	*enableCors = false
+
+	c, err := NewChain(nil, "request-id", "cors")
+	if err != nil {
+		t.Fatalf("NewChain() failed with %v", err)
+	}
+	if got, want := strings.Join(c.Names(), ","), "request-id"; got != want {
+		t.Errorf("Names() = %s; want %s", got, want)
+	}
+}
+
+func TestNewHttpMux(t *testing.T) {
+	defer func(v string) { *middlewares = v }(*middlewares)
+	*middlewares = "request-id,unknown"
+	if _, err := NewHttpMux(runtime.NewServeMux()); err == nil {
+		t.Errorf("NewHttpMux() with unknown middleware succeeded; want error")
+	}
+
+	*middlewares = "request-id"
+	if h, err := NewHttpMux(runtime.NewServeMux()); err != nil || h == nil {
+		t.Errorf("NewHttpMux() = %v, %v; want a handler", h, err)
+	}
+}
+
+func TestRequestIdMiddleware(t *testing.T) {
+	var got string
+	h := RequestIdMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(XRequestId)
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(XRequestId, "tid")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got != "tid" || w.Header().Get(XRequestId) != "tid" {
+		t.Errorf("request id = %q, response id = %q; want tid", got, w.Header().Get(XRequestId))
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if got == "" || w.Header().Get(XRequestId) != got {
+		t.Errorf("generated request id = %q, response id = %q; want the same non-empty id", got, w.Header().Get(XRequestId))
+	}
+}
+
+func TestStatusResponseWriter(t *testing.T) {
+	for _, spec := range []struct {
+		handler  http.HandlerFunc
+		wantCode int
+		wantSize int64
+	}{
+		{
+			handler:  func(w http.ResponseWriter, r *http.Request) {},
+			wantCode: 0,
+		},
+		{
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("hello"))
+			},
+			wantCode: http.StatusOK,
+			wantSize: 5,
+		},
+		{
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("not found"))
+			},
+			wantCode: http.StatusNotFound,
+			wantSize: 9,
+		},
+	} {
+		sw := &statusResponseWriter{ResponseWriter: httptest.NewRecorder()}
+		spec.handler(sw, httptest.NewRequest("GET", "/", nil))
+		if sw.code != spec.wantCode || sw.size != spec.wantSize {
+			t.Errorf("code, size = %d, %d; want %d, %d", sw.code, sw.size, spec.wantCode, spec.wantSize)
+		}
+	}
+}
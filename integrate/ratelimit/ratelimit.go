@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// KeyType is a dimension of the request a limit is counted by.
+type KeyType string
+
+const (
+	// KeyMethod counts by the API method, i.e. http method and path.
+	KeyMethod KeyType = "method"
+	// KeyClient counts by the x-client header.
+	KeyClient KeyType = "client"
+	// KeyUid counts by the user id.
+	KeyUid KeyType = "uid"
+	// KeyCid counts by the company id.
+	KeyCid KeyType = "cid"
+	// KeyIP counts by the remote IP.
+	KeyIP KeyType = "ip"
+)
+
+// Rule is a rate limit rule.
+type Rule struct {
+	// Name identifies the rule in logs and bucket keys.
+	Name string `yaml:"name"`
+	// HttpMethod and Path select the API methods the rule applies to. Empty
+	// values match all methods.
+	HttpMethod string `yaml:"http_method"`
+	Path       string `yaml:"path"`
+	// Keys is the combination of request dimensions the tokens are counted
+	// by. An empty list means one bucket shared by all matched requests.
+	Keys []KeyType `yaml:"keys"`
+	// Rate is the number of requests allowed per second.
+	Rate float64 `yaml:"rate"`
+	// Burst is the maximum number of requests allowed at once.
+	Burst int `yaml:"burst"`
+}
+
+// Config is the rate limit configuration.
+type Config struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// LoadConfig reads a YAML rate limit configuration from the given file.
+func LoadConfig(file string) (*Config, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	conf := Config{}
+	if err := yaml.UnmarshalStrict(b, &conf); err != nil {
+		return nil, fmt.Errorf("parsing rate limit config %s: %v", file, err)
+	}
+	for i, r := range conf.Rules {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("rate limit rule #%d: %v", i, err)
+		}
+	}
+	return &conf, nil
+}
+
+func (r *Rule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is missing")
+	}
+	if r.Rate <= 0 {
+		return fmt.Errorf("rule %s: rate must be positive", r.Name)
+	}
+	for _, k := range r.Keys {
+		switch k {
+		case KeyMethod, KeyClient, KeyUid, KeyCid, KeyIP:
+		default:
+			return fmt.Errorf("rule %s: unknown key %q", r.Name, k)
+		}
+	}
+	return nil
+}
+
+// matches returns if the rule applies to the given request.
+func (r *Rule) matches(req *Request) bool {
+	return (r.HttpMethod == "" || strings.EqualFold(r.HttpMethod, req.HttpMethod)) &&
+		(r.Path == "" || r.Path == req.Path)
+}
+
+// bucketKey returns the key of the token bucket for the given request.
+func (r *Rule) bucketKey(req *Request) string {
+	parts := make([]string, 0, len(r.Keys)+1)
+	parts = append(parts, r.Name)
+	for _, k := range r.Keys {
+		parts = append(parts, req.value(k))
+	}
+	return strings.Join(parts, "|")
+}
+
+// Request holds the request dimensions the limits are counted by.
+type Request struct {
+	HttpMethod string
+	Path       string
+	Client     string
+	Uid        string
+	Cid        string
+	IP         string
+}
+
+func (req *Request) value(k KeyType) string {
+	switch k {
+	case KeyMethod:
+		return req.HttpMethod + " " + req.Path
+	case KeyClient:
+		return req.Client
+	case KeyUid:
+		return req.Uid
+	case KeyCid:
+		return req.Cid
+	case KeyIP:
+		return req.IP
+	}
+	return ""
+}
+
+// Limiter checks requests against the rate limit rules.
+type Limiter struct {
+	store Store
+	rules []*Rule
+}
+
+// NewLimiter returns a Limiter which keeps its buckets in the given store.
+func NewLimiter(store Store, rules []*Rule) *Limiter {
+	return &Limiter{
+		store: store,
+		rules: rules,
+	}
+}
+
+// Allow checks the request against all matched rules plus the extra ones,
+// typically derived from the API method annotation. The request is allowed
+// only when all rules allow it; otherwise the first rule which rejects it is
+// returned along with the time to wait before retrying, and the tokens taken
+// by the other rules are returned.
+func (l *Limiter) Allow(req *Request, extra ...*Rule) (bool, *Rule, time.Duration) {
+	type taken struct {
+		key string
+		lim Limit
+	}
+	var takens []taken
+	now := time.Now()
+	for _, rules := range [][]*Rule{l.rules, extra} {
+		for _, r := range rules {
+			if !r.matches(req) {
+				continue
+			}
+			key, lim := r.bucketKey(req), Limit{Rate: r.Rate, Burst: r.Burst}
+			if ok, wait := l.store.Take(key, lim, now); !ok {
+				for _, t := range takens {
+					l.store.Put(t.key, t.lim, now)
+				}
+				return false, r, wait
+			}
+			takens = append(takens, taken{key, lim})
+		}
+	}
+	return true, nil, 0
+}
+
+// MethodRule returns the rule of the rate limit annotated on an API method,
+// shared by all callers of the method. It returns nil if the method has no
+// limit.
+func MethodRule(httpMethod, path string, rate float64, burst int32) *Rule {
+	if rate <= 0 {
+		return nil
+	}
+	return &Rule{
+		Name:       "api",
+		HttpMethod: httpMethod,
+		Path:       path,
+		Keys:       []KeyType{KeyMethod},
+		Rate:       rate,
+		Burst:      int(burst),
+	}
+}
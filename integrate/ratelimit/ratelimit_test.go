@@ -0,0 +1,203 @@
+package ratelimit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	s := NewMemoryStore()
+	lim := Limit{Rate: 2, Burst: 3}
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := s.Take("k", lim, now); !ok {
+			t.Fatalf("Take #%d was rejected; want allowed within burst", i)
+		}
+	}
+	ok, wait := s.Take("k", lim, now)
+	if ok {
+		t.Fatalf("Take beyond burst was allowed; want rejected")
+	}
+	if want := 500 * time.Millisecond; wait != want {
+		t.Errorf("wait = %v; want %v", wait, want)
+	}
+	if ok, _ := s.Take("other", lim, now); !ok {
+		t.Errorf("Take of another key was rejected; want allowed")
+	}
+	if ok, _ := s.Take("k", lim, now.Add(500*time.Millisecond)); !ok {
+		t.Errorf("Take after refill was rejected; want allowed")
+	}
+}
+
+func TestMemoryStoreDefaultBurst(t *testing.T) {
+	s := NewMemoryStore()
+	lim := Limit{Rate: 0.5}
+	now := time.Unix(1000, 0)
+	if ok, _ := s.Take("k", lim, now); !ok {
+		t.Fatalf("first Take was rejected; want allowed")
+	}
+	ok, wait := s.Take("k", lim, now)
+	if ok {
+		t.Fatalf("second Take was allowed; want rejected")
+	}
+	if want := 2 * time.Second; wait != want {
+		t.Errorf("wait = %v; want %v", wait, want)
+	}
+}
+
+func TestMemoryStorePut(t *testing.T) {
+	s := NewMemoryStore()
+	lim := Limit{Rate: 1, Burst: 1}
+	now := time.Now()
+	if ok, _ := s.Take("k", lim, now); !ok {
+		t.Fatalf("first Take was rejected; want allowed")
+	}
+	s.Put("k", lim, now)
+	if ok, _ := s.Take("k", lim, now); !ok {
+		t.Errorf("Take after Put was rejected; want allowed")
+	}
+	// The bucket is not filled over its capacity.
+	s.Put("k", lim, now)
+	s.Put("k", lim, now)
+	s.Take("k", lim, now)
+	if ok, _ := s.Take("k", lim, now); ok {
+		t.Errorf("Take beyond burst after Put was allowed; want rejected")
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s := NewMemoryStore().(*memoryStore)
+	lim := Limit{Rate: 1, Burst: 1}
+	now := time.Unix(1000, 0)
+	s.Take("idle", lim, now)
+	s.Take("busy", lim, now.Add(2*sweepInterval))
+	s.Take("busy", lim, now.Add(2*sweepInterval))
+
+	if _, ok := s.buckets["idle"]; ok {
+		t.Errorf("idle bucket was not swept")
+	}
+	if _, ok := s.buckets["busy"]; !ok {
+		t.Errorf("busy bucket was swept")
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	rules := []*Rule{
+		{Name: "uid", Keys: []KeyType{KeyUid}, Rate: 1, Burst: 1},
+		{Name: "post", HttpMethod: "POST", Path: "/v1/foo", Keys: []KeyType{KeyMethod, KeyIP}, Rate: 1, Burst: 2},
+	}
+	l := NewLimiter(NewMemoryStore(), rules)
+
+	get := &Request{HttpMethod: "GET", Path: "/v1/foo", Uid: "1"}
+	if ok, _, _ := l.Allow(get); !ok {
+		t.Fatalf("first request of uid 1 was rejected")
+	}
+	ok, r, wait := l.Allow(get)
+	if ok || r.Name != "uid" || wait <= 0 {
+		t.Errorf("Allow() = %t, %v, %v; want rejected by rule uid", ok, r, wait)
+	}
+
+	for i, uid := range []string{"2", "3"} {
+		post := &Request{HttpMethod: "POST", Path: "/v1/foo", Uid: uid, IP: "10.0.0.1"}
+		if ok, _, _ := l.Allow(post); !ok {
+			t.Fatalf("post #%d was rejected", i)
+		}
+	}
+	post := &Request{HttpMethod: "POST", Path: "/v1/foo", Uid: "4", IP: "10.0.0.1"}
+	if ok, r, _ := l.Allow(post); ok || r.Name != "post" {
+		t.Errorf("Allow() = %t, %v; want rejected by rule post", ok, r)
+	}
+	post = &Request{HttpMethod: "POST", Path: "/v1/foo", Uid: "5", IP: "10.0.0.2"}
+	if ok, _, _ := l.Allow(post); !ok {
+		t.Errorf("post from another IP was rejected")
+	}
+}
+
+func TestLimiterAllowRejectedTakesNone(t *testing.T) {
+	rules := []*Rule{
+		{Name: "ip", Keys: []KeyType{KeyIP}, Rate: 0.001, Burst: 2},
+		{Name: "uid", Keys: []KeyType{KeyUid}, Rate: 0.001, Burst: 1},
+	}
+	l := NewLimiter(NewMemoryStore(), rules)
+
+	req := &Request{HttpMethod: "GET", Path: "/v1/foo", Uid: "1", IP: "10.0.0.1"}
+	if ok, _, _ := l.Allow(req); !ok {
+		t.Fatalf("first request was rejected")
+	}
+	if ok, r, _ := l.Allow(req); ok || r.Name != "uid" {
+		t.Fatalf("Allow() = %t, %v; want rejected by rule uid", ok, r)
+	}
+	// The token of rule ip taken by the rejected request is returned.
+	req = &Request{HttpMethod: "GET", Path: "/v1/foo", Uid: "2", IP: "10.0.0.1"}
+	if ok, r, _ := l.Allow(req); !ok {
+		t.Errorf("request of another uid was rejected by rule %v", r)
+	}
+	req = &Request{HttpMethod: "GET", Path: "/v1/foo", Uid: "3", IP: "10.0.0.1"}
+	if ok, r, _ := l.Allow(req); ok || r.Name != "ip" {
+		t.Errorf("Allow() = %t, %v; want rejected by rule ip", ok, r)
+	}
+}
+
+func TestLimiterAllowMethodRule(t *testing.T) {
+	l := NewLimiter(NewMemoryStore(), nil)
+	if MethodRule("GET", "/v1/foo", 0, 0) != nil {
+		t.Errorf("MethodRule() with zero rate returns a rule; want nil")
+	}
+	mr := MethodRule("GET", "/v1/foo", 1, 0)
+	req := &Request{HttpMethod: "GET", Path: "/v1/foo"}
+	if ok, _, _ := l.Allow(req, mr); !ok {
+		t.Fatalf("first request was rejected")
+	}
+	if ok, _, _ := l.Allow(req, mr); ok {
+		t.Errorf("second request was allowed; want rejected by method rule")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ratelimit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "limit.yaml")
+	conf := `
+rules:
+- name: per-user
+  keys: [method, uid]
+  rate: 10
+  burst: 20
+- name: echo
+  http_method: POST
+  path: /v1/example/echo/{id}
+  keys: [ip]
+  rate: 0.5
+`
+	if err := ioutil.WriteFile(file, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadConfig(file)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed with %v", err)
+	}
+	if len(c.Rules) != 2 {
+		t.Fatalf("len(c.Rules) = %d; want 2", len(c.Rules))
+	}
+	if r := c.Rules[0]; r.Name != "per-user" || len(r.Keys) != 2 || r.Rate != 10 || r.Burst != 20 {
+		t.Errorf("c.Rules[0] = %+v", r)
+	}
+	if r := c.Rules[1]; r.HttpMethod != "POST" || r.Path != "/v1/example/echo/{id}" || r.Rate != 0.5 {
+		t.Errorf("c.Rules[1] = %+v", r)
+	}
+
+	if err := ioutil.WriteFile(file, []byte("rules:\n- name: bad\n  keys: [foo]\n  rate: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(file); err == nil {
+		t.Errorf("LoadConfig() with unknown key succeeded; want error")
+	}
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// sweepInterval is how often the memory store drops idle buckets.
+	sweepInterval = time.Minute
+)
+
+// Limit describes a token bucket.
+type Limit struct {
+	// Rate is the number of tokens added to the bucket per second.
+	Rate float64
+	// Burst is the capacity of the bucket.
+	Burst int
+}
+
+// capacity returns the capacity of the bucket, defaults to the ceiling of
+// the rate.
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.Rate))
+}
+
+// Store holds the token buckets of all limit keys.
+//
+// Implementations must be goroutine-safe.
+type Store interface {
+	// Take takes one token from the bucket of the given key. When the bucket
+	// is empty it returns false and the time to wait for the next token.
+	Take(key string, limit Limit, now time.Time) (bool, time.Duration)
+	// Put returns one token taken by Take to the bucket of the given key.
+	Put(key string, limit Limit, now time.Time)
+}
+
+// bucket is a token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is the time when the bucket is refilled to its capacity.
+	full time.Time
+}
+
+// memoryStore is a Store which keeps the token buckets in memory.
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns a Store which keeps the token buckets in the memory
+// of the current process.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Take implements Store.
+func (s *memoryStore) Take(key string, limit Limit, now time.Time) (bool, time.Duration) {
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+	capacity := limit.capacity()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*limit.Rate)
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false, seconds((1 - b.tokens) / limit.Rate)
+	}
+	b.tokens--
+	b.full = now.Add(seconds((capacity - b.tokens) / limit.Rate))
+	return true, 0
+}
+
+// Put implements Store.
+func (s *memoryStore) Put(key string, limit Limit, now time.Time) {
+	if limit.Rate <= 0 {
+		return
+	}
+	capacity := limit.capacity()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A missing bucket is full.
+	b, ok := s.buckets[key]
+	if !ok {
+		return
+	}
+	b.tokens = math.Min(capacity, b.tokens+1)
+	b.full = now.Add(seconds((capacity - b.tokens) / limit.Rate))
+}
+
+// sweep drops the buckets which are already refilled. A missing bucket is
+// created full, so dropping them changes nothing.
+func (s *memoryStore) sweep(now time.Time) {
+	for k, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, k)
+		}
+	}
+	s.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,93 @@
+package integrate
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"google.golang.org/grpc/codes"
+
+	fpb "github.com/binchencoder/gateway-proto/frontend"
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/integrate/metrics"
+	"github.com/binchencoder/janus-gateway/util"
+)
+
+func newRecoveryMiddleware(mux *runtime.ServeMux) (Middleware, error) {
+	return func(next http.Handler) http.Handler {
+		return &RecoveryMiddleware{Handler: next, Mux: mux}
+	}, nil
+}
+
+// 异常恢复中间件. It recovers the panics of the handler, logs them with the
+// stack to util.ErrorLogger and responds 500 through the error handler of the
+// mux.
+type RecoveryMiddleware struct {
+	Handler http.Handler
+	Mux     *runtime.ServeMux
+}
+
+func (m *RecoveryMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rw := &startedResponseWriter{ResponseWriter: w}
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		if p == http.ErrAbortHandler {
+			panic(p)
+		}
+
+		tid := r.Header.Get(XRequestId)
+		util.Logef(util.ErrorLogger, util.ErrorFormat, tid, fmt.Sprintf("panic serving %s %s: %v\n%s", r.Method, r.URL.Path, p, debug.Stack()))
+		metrics.ErrCount("panic")
+
+		if rw.started {
+			// Too late for an error response, abort the connection.
+			panic(http.ErrAbortHandler)
+		}
+		err := grpcError(codes.Internal, fpb.ErrorCode_SERVICE_INTERNAL_ERROR, []string{"Internal server error."})
+		_, outboundMarshaler := runtime.MarshalerForRequest(m.Mux, r)
+		runtime.HTTPError(r.Context(), m.Mux, outboundMarshaler, w, r, err)
+	}()
+	m.Handler.ServeHTTP(rw, r)
+}
+
+// startedResponseWriter records whether the response has been started.
+type startedResponseWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *startedResponseWriter) WriteHeader(code int) {
+	// Informational responses do not start the final response.
+	if code >= 200 {
+		w.started = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *startedResponseWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *startedResponseWriter) Flush() {
+	w.started = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection, after which no error response can be
+// written.
+func (w *startedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T is not a http.Hijacker", w.ResponseWriter)
+	}
+	w.started = true
+	return hj.Hijack()
+}
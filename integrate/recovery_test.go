@@ -0,0 +1,81 @@
+package integrate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	for _, spec := range []struct {
+		name    string
+		handler http.HandlerFunc
+
+		wantCode  int
+		wantBody  string
+		wantAbort bool
+	}{
+		{
+			name: "no panic",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			},
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+		},
+		{
+			name: "panic before response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			},
+			wantCode: http.StatusInternalServerError,
+			wantBody: `"code":100001`,
+		},
+		{
+			name: "panic after response started",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("partial"))
+				panic("boom")
+			},
+			wantAbort: true,
+		},
+		{
+			name: "abort handler",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic(http.ErrAbortHandler)
+			},
+			wantAbort: true,
+		},
+	} {
+		m := &RecoveryMiddleware{Handler: spec.handler, Mux: runtime.NewServeMux()}
+		r := httptest.NewRequest("GET", "/v1/echo", nil)
+		r.Header.Set(XRequestId, "tid")
+		w := httptest.NewRecorder()
+
+		var aborted interface{}
+		func() {
+			defer func() { aborted = recover() }()
+			m.ServeHTTP(w, r)
+		}()
+
+		if spec.wantAbort {
+			if aborted != http.ErrAbortHandler {
+				t.Errorf("%s: panicked with %v; want %v", spec.name, aborted, http.ErrAbortHandler)
+			}
+			continue
+		}
+		if aborted != nil {
+			t.Errorf("%s: panicked with %v", spec.name, aborted)
+			continue
+		}
+		if w.Code != spec.wantCode {
+			t.Errorf("%s: code = %d; want %d", spec.name, w.Code, spec.wantCode)
+		}
+		if !strings.Contains(w.Body.String(), spec.wantBody) {
+			t.Errorf("%s: body = %q; want containing %q", spec.name, w.Body.String(), spec.wantBody)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// budgetBuckets is the number of one-second buckets of a Budget.
+const budgetBuckets = 10
+
+// Budget limits the retries of the whole gateway relative to the requests,
+// so that retries can not amplify the load of a struggling backend into a
+// retry storm.
+//
+// Within a sliding window of ten seconds, the retries may not exceed Ratio
+// of the requests plus MinPerSecond retries per second.
+type Budget struct {
+	ratio        float64
+	minPerSecond int
+	now          func() time.Time
+
+	mu       sync.Mutex
+	start    int64
+	requests [budgetBuckets]int
+	retries  [budgetBuckets]int
+}
+
+// NewBudget returns a new Budget.
+func NewBudget(ratio float64, minPerSecond int) *Budget {
+	return &Budget{
+		ratio:        ratio,
+		minPerSecond: minPerSecond,
+		now:          time.Now,
+	}
+}
+
+// Deposit records a request.
+func (b *Budget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests[b.advance()]++
+}
+
+// Withdraw records a retry and returns true if the budget allows it.
+func (b *Budget) Withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.advance()
+	requests, retries := 0, 0
+	for j := 0; j < budgetBuckets; j++ {
+		requests += b.requests[j]
+		retries += b.retries[j]
+	}
+	if float64(retries) >= b.ratio*float64(requests)+float64(b.minPerSecond*budgetBuckets) {
+		return false
+	}
+	b.retries[i]++
+	return true
+}
+
+// advance clears the buckets which fell out of the window and returns the
+// index of the current bucket.
+func (b *Budget) advance() int {
+	sec := b.now().Unix()
+	if sec-b.start >= budgetBuckets {
+		b.requests = [budgetBuckets]int{}
+		b.retries = [budgetBuckets]int{}
+	} else {
+		for s := b.start + 1; s <= sec; s++ {
+			b.requests[s%budgetBuckets] = 0
+			b.retries[s%budgetBuckets] = 0
+		}
+	}
+	if sec > b.start {
+		b.start = sec
+	}
+	return int(b.start % budgetBuckets)
+}
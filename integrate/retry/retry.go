@@ -0,0 +1,80 @@
+// Package retry implements retries of upstream gRPC calls with exponential
+// backoff and a retry budget.
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Policy configures the retries of a call.
+type Policy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	MaxAttempts int
+	// InitialBackoff is the backoff before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff.
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the backoff grows after each retry.
+	Multiplier float64
+	// Jitter randomizes the backoff by up to the given ratio in both
+	// directions, between 0 and 1.
+	Jitter float64
+}
+
+// Backoff returns the backoff before the n-th retry, starting from 1.
+func (p *Policy) Backoff(n int) time.Duration {
+	b := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(n-1))
+	if p.MaxBackoff > 0 && b > float64(p.MaxBackoff) {
+		b = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		b *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(b)
+}
+
+// Retryable returns whether the error of an attempt is worth a retry, which
+// is codes.Unavailable only.
+func Retryable(err error) bool {
+	return err != nil && status.Code(err) == codes.Unavailable
+}
+
+// Do calls f until it succeeds, returns a non retryable error or the policy
+// gives up. A retry is only made if the budget allows it and the remaining
+// deadline of ctx outlasts the backoff. onRetry, if not nil, is called before
+// each retry with the attempt number, the error of the previous attempt and
+// the backoff.
+func Do(ctx context.Context, p *Policy, budget *Budget, f func(ctx context.Context) error, onRetry func(attempt int, err error, backoff time.Duration)) error {
+	if budget != nil {
+		budget.Deposit()
+	}
+	err := f(ctx)
+	for attempt := 2; attempt <= p.MaxAttempts && Retryable(err); attempt++ {
+		backoff := p.Backoff(attempt - 1)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= backoff {
+			return err
+		}
+		if budget != nil && !budget.Withdraw() {
+			return err
+		}
+		if onRetry != nil {
+			onRetry(attempt, err, backoff)
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		err = f(ctx)
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errUnavailable = status.Error(codes.Unavailable, "unavailable")
+	errInternal    = status.Error(codes.Internal, "internal")
+)
+
+func TestBackoff(t *testing.T) {
+	p := &Policy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond, Multiplier: 2}
+	for _, spec := range []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 50 * time.Millisecond},
+	} {
+		if got := p.Backoff(spec.n); got != spec.want {
+			t.Errorf("Backoff(%d) = %v; want %v", spec.n, got, spec.want)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.Backoff(1); got < 5*time.Millisecond || got > 15*time.Millisecond {
+			t.Fatalf("Backoff(1) with jitter = %v; want within [5ms, 15ms]", got)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	p := &Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2}
+	for _, spec := range []struct {
+		errs     []error
+		want     error
+		attempts int
+	}{
+		{[]error{nil}, nil, 1},
+		{[]error{errUnavailable, nil}, nil, 2},
+		{[]error{errInternal}, errInternal, 1},
+		{[]error{errUnavailable, errUnavailable, errUnavailable}, errUnavailable, 3},
+	} {
+		attempts, retries := 0, 0
+		err := Do(context.Background(), p, nil, func(ctx context.Context) error {
+			attempts++
+			return spec.errs[attempts-1]
+		}, func(attempt int, err error, backoff time.Duration) {
+			retries++
+		})
+		if err != spec.want {
+			t.Errorf("Do(%v) = %v; want %v", spec.errs, err, spec.want)
+		}
+		if attempts != spec.attempts || retries != spec.attempts-1 {
+			t.Errorf("Do(%v) made %d attempts and %d retries; want %d and %d", spec.errs, attempts, retries, spec.attempts, spec.attempts-1)
+		}
+	}
+}
+
+func TestDoRespectsDeadline(t *testing.T) {
+	p := &Policy{MaxAttempts: 3, InitialBackoff: time.Second, Multiplier: 2}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	err := Do(ctx, p, nil, func(ctx context.Context) error {
+		attempts++
+		return errUnavailable
+	}, nil)
+	if err != errUnavailable || attempts != 1 {
+		t.Errorf("Do() = %v after %d attempts; want %v after 1", err, attempts, errUnavailable)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBudget(0.5, 0)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		b.Deposit()
+	}
+	for i := 0; i < 2; i++ {
+		if !b.Withdraw() {
+			t.Fatalf("Withdraw() #%d = false; want true", i+1)
+		}
+	}
+	if b.Withdraw() {
+		t.Errorf("Withdraw() over budget = true; want false")
+	}
+
+	// The requests and retries expire with the window.
+	now = now.Add(budgetBuckets * time.Second)
+	if b.Withdraw() {
+		t.Errorf("Withdraw() without requests = true; want false")
+	}
+	b.Deposit()
+	b.Deposit()
+	if !b.Withdraw() {
+		t.Errorf("Withdraw() in a new window = false; want true")
+	}
+
+	b = NewBudget(0, 1)
+	b.now = func() time.Time { return now }
+	for i := 0; i < budgetBuckets; i++ {
+		if !b.Withdraw() {
+			t.Fatalf("Withdraw() #%d with minimum = false; want true", i+1)
+		}
+	}
+	if b.Withdraw() {
+		t.Errorf("Withdraw() over minimum = true; want false")
+	}
+}
@@ -0,0 +1,38 @@
+package integrate
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	"github.com/binchencoder/janus-gateway/util"
+)
+
+const (
+	// RouteConflictsWarn logs the route conflicts with both owners.
+	RouteConflictsWarn = "warn"
+	// RouteConflictsStrict fails the bootstrap on route conflicts.
+	RouteConflictsStrict = "strict"
+)
+
+var (
+	routeConflicts = flag.String("route-conflicts", RouteConflictsWarn, "How to handle the routes of different services which are identical or shadow each other: warn logs both owners, strict fails the bootstrap.")
+)
+
+// checkRouteConflicts logs the route conflicts of the mux, and returns an
+// error on any of them if flag --route-conflicts is strict.
+func checkRouteConflicts(mux *runtime.ServeMux) error {
+	mode := *routeConflicts
+	if mode != RouteConflictsWarn && mode != RouteConflictsStrict {
+		return fmt.Errorf("unknown --route-conflicts mode %q", mode)
+	}
+
+	conflicts := mux.RouteConflicts()
+	for _, c := range conflicts {
+		util.Logf(util.ConfigLogger, "[Route conflict] %s", c)
+	}
+	if mode == RouteConflictsStrict && len(conflicts) > 0 {
+		return fmt.Errorf("%d route conflicts, the first: %s", len(conflicts), conflicts[0])
+	}
+	return nil
+}
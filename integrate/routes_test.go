@@ -0,0 +1,40 @@
+package integrate
+
+import (
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+)
+
+func TestCheckRouteConflicts(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, pathParams map[string]string) {}
+	mux := runtime.NewServeMux()
+	if err := mux.HandlePath("GET", "/v1/echo/{id}", 1, noop); err != nil {
+		t.Fatalf("mux.HandlePath() failed with %v", err)
+	}
+	if err := mux.HandlePath("GET", "/v1/echo/{name}", 2, noop); err != nil {
+		t.Fatalf("mux.HandlePath() failed with %v", err)
+	}
+
+	old := *routeConflicts
+	defer func() { *routeConflicts = old }()
+
+	*routeConflicts = RouteConflictsWarn
+	if err := checkRouteConflicts(mux); err != nil {
+		t.Errorf("checkRouteConflicts() in warn mode failed with %v", err)
+	}
+	*routeConflicts = RouteConflictsStrict
+	if err := checkRouteConflicts(mux); err == nil {
+		t.Errorf("checkRouteConflicts() in strict mode succeeded; want error")
+	}
+	if err := checkRouteConflicts(runtime.NewServeMux()); err != nil {
+		t.Errorf("checkRouteConflicts() without conflicts failed with %v", err)
+	}
+	*routeConflicts = "fail"
+	if err := checkRouteConflicts(runtime.NewServeMux()); err == nil {
+		t.Errorf("checkRouteConflicts() in unknown mode succeeded; want error")
+	}
+}
@@ -0,0 +1,408 @@
+package integrate
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	gr "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	fpb "github.com/binchencoder/gateway-proto/frontend"
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	options "github.com/binchencoder/janus-gateway/httpoptions"
+	"github.com/binchencoder/janus-gateway/integrate/breaker"
+	"github.com/binchencoder/janus-gateway/integrate/concurrency"
+	"github.com/binchencoder/janus-gateway/integrate/hedge"
+	"github.com/binchencoder/janus-gateway/integrate/metrics"
+	"github.com/binchencoder/janus-gateway/integrate/retry"
+	"github.com/binchencoder/janus-gateway/util"
+	"github.com/binchencoder/letsgo/trace"
+	skypb "github.com/binchencoder/skylb-api/proto"
+)
+
+var (
+	concurrencyConfig = flag.String("concurrency-config", "", "The YAML file of concurrency limits of the service groups.")
+	breakerConfig     = flag.String("circuit-breaker-config", "", "The YAML file of circuit breakers of the service groups.")
+
+	retryGet            = flag.Bool("retry-get", false, "Whether to retry the GET methods without max_attempts annotated.")
+	retryMaxAttempts    = flag.Int("retry-max-attempts", 3, "The max attempts of the GET methods when --retry-get is set.")
+	retryInitialBackoff = flag.Duration("retry-initial-backoff", 50*time.Millisecond, "The backoff before the first retry.")
+	retryMaxBackoff     = flag.Duration("retry-max-backoff", time.Second, "The max backoff between retries.")
+	retryJitter         = flag.Float64("retry-jitter", 0.2, "The jitter ratio of the retry backoff.")
+	retryBudgetRatio    = flag.Float64("retry-budget-ratio", 0.1, "The max ratio of retries to requests of the gateway.")
+	retryBudgetMin      = flag.Int("retry-budget-min", 10, "The retries per second allowed regardless of --retry-budget-ratio.")
+
+	hedgeBudgetRatio = flag.Float64("hedge-budget-ratio", 0.05, "The max ratio of hedged requests to requests of the gateway.")
+	hedgeBudgetMin   = flag.Int("hedge-budget-min", 5, "The hedged requests per second allowed regardless of --hedge-budget-ratio.")
+)
+
+// newConcurrencyInterceptor returns the client interceptor which limits the
+// concurrent upstream requests of each service group, configured by flag
+// --concurrency-config. It returns nil if the flag is not set.
+func newConcurrencyInterceptor() (runtime.ClientInterceptorFunc, error) {
+	if *concurrencyConfig == "" {
+		return nil, nil
+	}
+	conf, err := concurrency.LoadConfig(*concurrencyConfig)
+	if err != nil {
+		return nil, err
+	}
+	util.Logf(util.ConfigLogger, "Loaded concurrency limits of %d service groups.", len(conf.Groups))
+
+	// The limiter of a service group is kept when it is disabled, so that
+	// enabling it again does not reset the limit.
+	var (
+		mu       sync.Mutex
+		limiters = map[string]*concurrency.Limiter{}
+	)
+	return func(spec *skypb.ServiceSpec) gr.UnaryClientInterceptor {
+		name := spec.GetServiceName()
+		opts, ok := conf.Options(name)
+		if !ok {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		l, ok := limiters[name]
+		if !ok {
+			l = concurrency.NewLimiter(opts)
+			limiters[name] = l
+		}
+		return concurrencyInterceptor(name, l)
+	}, nil
+}
+
+// limiterKey is the context key of the concurrency limiter which admitted a
+// request, so that its hedged attempt takes a slot as well.
+type limiterKey struct{}
+
+// concurrencyInterceptor returns the client interceptor which admits the
+// requests of the given service through the limiter and sheds the rest.
+func concurrencyInterceptor(serviceName string, l *concurrency.Limiter) gr.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *gr.ClientConn, invoker gr.UnaryInvoker, opts ...gr.CallOption) error {
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			reportConcurrency(serviceName, l)
+			if err != concurrency.ErrLimitExceeded && err != concurrency.ErrQueueTimeout {
+				// The caller gave up while queued, which is not shedding.
+				return status.FromContextError(err).Err()
+			}
+			return upstreamShed(ctx, serviceName, method, l, err)
+		}
+		reportConcurrency(serviceName, l)
+
+		start := time.Now()
+		err = invoker(context.WithValue(ctx, limiterKey{}, l), method, req, reply, cc, opts...)
+		release(time.Since(start), err)
+		reportConcurrency(serviceName, l)
+		return err
+	}
+}
+
+func reportConcurrency(serviceName string, l *concurrency.Limiter) {
+	limit, inflight, _ := l.Stats()
+	metrics.Concurrency(serviceName, limit, inflight)
+}
+
+// upstreamShed处理后端并发超限情况.
+func upstreamShed(ctx context.Context, serviceName, method string, l *concurrency.Limiter, err error) error {
+	reason := "limit"
+	if err == concurrency.ErrQueueTimeout {
+		reason = "queue_timeout"
+	}
+	metrics.ShedCount(serviceName, reason)
+
+	limit, inflight, queued := l.Stats()
+	// record limit logs.
+	util.Logf(util.LimitLogger, util.LimitFormat, trace.GetTraceIdOrEmpty(ctx), "concurrency", serviceName, "", method,
+		fmt.Sprintf("reason:%s,limit:%d,inflight:%d,queued:%d", reason, limit, inflight, queued))
+
+	return grpcError(codes.Unavailable, fpb.ErrorCode_SERVICE_DOWN, []string{"The service is overloaded."})
+}
+
+// newBreakerInterceptor returns the client interceptor which short-circuits
+// the upstream requests of the failing service groups, configured by flag
+// --circuit-breaker-config. It returns nil if the flag is not set.
+func newBreakerInterceptor() (runtime.ClientInterceptorFunc, error) {
+	if *breakerConfig == "" {
+		return nil, nil
+	}
+	conf, err := breaker.LoadConfig(*breakerConfig)
+	if err != nil {
+		return nil, err
+	}
+	util.Logf(util.ConfigLogger, "Loaded circuit breakers of %d service groups.", len(conf.Groups))
+
+	// The breakers of a service group are kept when it is disabled, so that
+	// enabling it again does not reset them.
+	var (
+		mu   sync.Mutex
+		sets = map[string]*breakerSet{}
+	)
+	return func(spec *skypb.ServiceSpec) gr.UnaryClientInterceptor {
+		name := spec.GetServiceName()
+		opts, ok := conf.Options(name)
+		if !ok {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		bs, ok := sets[name]
+		if !ok {
+			bs = &breakerSet{serviceName: name, opts: opts, breakers: map[string]*breaker.Breaker{}}
+			sets[name] = bs
+		}
+		return breakerInterceptor(bs)
+	}, nil
+}
+
+// breakerSet holds the circuit breakers of a service group, one for the
+// group or one per method.
+type breakerSet struct {
+	serviceName string
+	opts        breaker.GroupOptions
+
+	mu       sync.Mutex
+	breakers map[string]*breaker.Breaker
+}
+
+// get returns the circuit breaker of the method, created on first use.
+func (bs *breakerSet) get(method string) *breaker.Breaker {
+	name := bs.serviceName
+	if bs.opts.PerMethod {
+		name = bs.serviceName + method
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	b, ok := bs.breakers[name]
+	if !ok {
+		b = breaker.NewBreaker(name, bs.opts.Options, breakerStateChanged)
+		bs.breakers[name] = b
+		metrics.BreakerState(name, int(breaker.StateClosed))
+	}
+	return b
+}
+
+// breakerInterceptor returns the client interceptor which passes the requests
+// of a service group through its circuit breakers.
+func breakerInterceptor(bs *breakerSet) gr.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *gr.ClientConn, invoker gr.UnaryInvoker, opts ...gr.CallOption) error {
+		b := bs.get(method)
+		done, err := b.Allow()
+		if err != nil {
+			return upstreamBroken(ctx, bs.serviceName, method, b)
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(err)
+		return err
+	}
+}
+
+func breakerStateChanged(name string, from, to breaker.State) {
+	util.Logf(util.ConfigLogger, "Circuit breaker %s changed from %v to %v.", name, from, to)
+	metrics.BreakerState(name, int(to))
+}
+
+// upstreamBroken处理后端熔断情况.
+func upstreamBroken(ctx context.Context, serviceName, method string, b *breaker.Breaker) error {
+	// record limit logs.
+	util.Logf(util.LimitLogger, util.LimitFormat, trace.GetTraceIdOrEmpty(ctx), "breaker", serviceName, "", method,
+		fmt.Sprintf("breaker:%s,state:%v", b.Name(), b.State()))
+
+	return grpcError(codes.Unavailable, fpb.ErrorCode_SERVICE_DOWN, []string{"The service is unavailable."})
+}
+
+// newRetryInterceptor returns the client interceptor which retries the
+// upstream requests of the API methods with retries enabled.
+func newRetryInterceptor() runtime.ClientInterceptorFunc {
+	budget := retry.NewBudget(*retryBudgetRatio, *retryBudgetMin)
+	util.Logf(util.ConfigLogger, "Retry GET methods: %t, max attempts: %d, budget ratio: %g, budget min: %d/s.",
+		*retryGet, *retryMaxAttempts, *retryBudgetRatio, *retryBudgetMin)
+
+	return func(spec *skypb.ServiceSpec) gr.UnaryClientInterceptor {
+		return retryInterceptor(spec.GetServiceName(), budget)
+	}
+}
+
+// retryInterceptor returns the client interceptor which retries the requests
+// of the given service within the budget.
+func retryInterceptor(serviceName string, budget *retry.Budget) gr.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *gr.ClientConn, invoker gr.UnaryInvoker, opts ...gr.CallOption) error {
+		_, m, ok := runtime.MethodFromContext(ctx)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		p := retryPolicy(m)
+		if p.MaxAttempts <= 1 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		tid := trace.GetTraceIdOrEmpty(ctx)
+		return retry.Do(ctx, p, budget, func(ctx context.Context) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}, func(attempt int, err error, backoff time.Duration) {
+			// record stat logs.
+			util.Logf(util.StatLogger, util.RetryStatFormat, tid, serviceName, m.HttpMethod, m.Path, attempt, gr.Code(err), backoff)
+		})
+	}
+}
+
+// retryPolicy returns the retry policy of the API method. The attempts
+// annotated on the method take precedence over the default of GET methods.
+func retryPolicy(m *runtime.Method) *retry.Policy {
+	attempts := int(m.MaxAttempts)
+	if attempts == 0 && *retryGet && m.HttpMethod == http.MethodGet {
+		attempts = *retryMaxAttempts
+	}
+	return &retry.Policy{
+		MaxAttempts:    attempts,
+		InitialBackoff: *retryInitialBackoff,
+		MaxBackoff:     *retryMaxBackoff,
+		Multiplier:     2,
+		Jitter:         *retryJitter,
+	}
+}
+
+// newHedgeInterceptor returns the client interceptor which hedges the
+// upstream requests of the API methods marked with hedge.
+func newHedgeInterceptor() runtime.ClientInterceptorFunc {
+	budget := retry.NewBudget(*hedgeBudgetRatio, *hedgeBudgetMin)
+	util.Logf(util.ConfigLogger, "Hedge budget ratio: %g, budget min: %d/s.", *hedgeBudgetRatio, *hedgeBudgetMin)
+
+	return func(spec *skypb.ServiceSpec) gr.UnaryClientInterceptor {
+		return hedgeInterceptor(spec.GetServiceName(), budget)
+	}
+}
+
+// hedgeInterceptor returns the client interceptor which hedges the requests
+// of the given service within the budget. The requests of the services with
+// consistent hashing are not hedged, since the hedged attempt would go to the
+// same backend. A hedged attempt takes a slot of the concurrency limiter of
+// the request if one is free, and fails otherwise.
+func hedgeInterceptor(serviceName string, budget *retry.Budget) gr.UnaryClientInterceptor {
+	var (
+		mu       sync.Mutex
+		trackers = map[*runtime.Method]*hedge.Tracker{}
+	)
+	tracker := func(m *runtime.Method) *hedge.Tracker {
+		mu.Lock()
+		defer mu.Unlock()
+		t, ok := trackers[m]
+		if !ok {
+			t = hedge.NewTracker()
+			trackers[m] = t
+		}
+		return t
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *gr.ClientConn, invoker gr.UnaryInvoker, opts ...gr.CallOption) error {
+		svc, m, ok := runtime.MethodFromContext(ctx)
+		out, isMsg := reply.(proto.Message)
+		if !ok || !m.Hedge || !isMsg || svc.Balancer == options.LoadBalancer_CONSISTENT.String() {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		t := tracker(m)
+		delay, ok := hedgeDelay(m, t)
+		if !ok {
+			start := time.Now()
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil {
+				t.Observe(time.Since(start))
+			}
+			return err
+		}
+
+		primary, secondary := newHedgeAttempt(out, opts), newHedgeAttempt(out, opts)
+		start := time.Now()
+		o, err := hedge.Do(ctx, delay, budget, func(ctx context.Context) error {
+			err := invoker(ctx, method, req, primary.reply, cc, primary.opts...)
+			if err == nil {
+				t.Observe(time.Since(start))
+			}
+			return err
+		}, func(ctx context.Context) error {
+			l, _ := ctx.Value(limiterKey{}).(*concurrency.Limiter)
+			if l == nil {
+				return invoker(ctx, method, req, secondary.reply, cc, secondary.opts...)
+			}
+			release, ok := l.TryAcquire()
+			if !ok {
+				metrics.HedgeCount(serviceName, "shed")
+				return concurrency.ErrLimitExceeded
+			}
+			reportConcurrency(serviceName, l)
+			start := time.Now()
+			err := invoker(ctx, method, req, secondary.reply, cc, secondary.opts...)
+			release(time.Since(start), err)
+			reportConcurrency(serviceName, l)
+			return err
+		})
+		if o.Hedged {
+			metrics.HedgeCount(serviceName, "issued")
+		}
+		winner := primary
+		if o.HedgeWon {
+			metrics.HedgeCount(serviceName, "won")
+			winner = secondary
+		}
+		winner.copyTo(out, opts)
+		return err
+	}
+}
+
+// hedgeDelay returns the delay before the hedged request of the API method,
+// and false if it is unknown yet.
+func hedgeDelay(m *runtime.Method, t *hedge.Tracker) (time.Duration, bool) {
+	if m.HedgeDelay != "" {
+		if d, err := time.ParseDuration(m.HedgeDelay); err == nil {
+			return d, true
+		}
+	}
+	return t.P95()
+}
+
+// hedgeAttempt holds the reply and the metadata of one attempt of a hedged
+// request, as the attempts run concurrently.
+type hedgeAttempt struct {
+	reply   proto.Message
+	header  metadata.MD
+	trailer metadata.MD
+	opts    []gr.CallOption
+}
+
+func newHedgeAttempt(reply proto.Message, opts []gr.CallOption) *hedgeAttempt {
+	a := &hedgeAttempt{reply: proto.Clone(reply)}
+	for _, o := range opts {
+		switch o.(type) {
+		case gr.HeaderCallOption:
+			o = gr.Header(&a.header)
+		case gr.TrailerCallOption:
+			o = gr.Trailer(&a.trailer)
+		}
+		a.opts = append(a.opts, o)
+	}
+	return a
+}
+
+// copyTo copies the reply and the metadata of the attempt to the ones of the
+// request.
+func (a *hedgeAttempt) copyTo(reply proto.Message, opts []gr.CallOption) {
+	proto.Reset(reply)
+	proto.Merge(reply, a.reply)
+	for _, o := range opts {
+		switch o := o.(type) {
+		case gr.HeaderCallOption:
+			*o.HeaderAddr = a.header
+		case gr.TrailerCallOption:
+			*o.TrailerAddr = a.trailer
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	gr "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/binchencoder/janus-gateway/gateway/runtime"
+	options "github.com/binchencoder/janus-gateway/httpoptions"
+	"github.com/binchencoder/janus-gateway/integrate/concurrency"
+	"github.com/binchencoder/janus-gateway/integrate/retry"
+	skypb "github.com/binchencoder/skylb-api/proto"
+)
+
+func TestHedgeAttemptCopyTo(t *testing.T) {
+	reply := wrapperspb.String("stale")
+	var header, trailer metadata.MD
+	opts := []gr.CallOption{gr.Header(&header), gr.Trailer(&trailer), gr.WaitForReady(true)}
+
+	a := newHedgeAttempt(reply, opts)
+	if len(a.opts) != len(opts) {
+		t.Fatalf("len(opts) = %d; want %d", len(a.opts), len(opts))
+	}
+	// The attempt writes to its own reply and metadata.
+	a.reply.(*wrapperspb.StringValue).Value = "fresh"
+	*a.opts[0].(gr.HeaderCallOption).HeaderAddr = metadata.Pairs("h", "1")
+	*a.opts[1].(gr.TrailerCallOption).TrailerAddr = metadata.Pairs("t", "2")
+	if reply.Value != "stale" || header != nil || trailer != nil {
+		t.Fatalf("attempt wrote to the request: reply = %v, header = %v, trailer = %v", reply, header, trailer)
+	}
+
+	a.copyTo(reply, opts)
+	if want := wrapperspb.String("fresh"); !proto.Equal(reply, want) {
+		t.Errorf("reply = %v; want %v", reply, want)
+	}
+	if got := header.Get("h"); len(got) != 1 || got[0] != "1" {
+		t.Errorf("header[h] = %v; want [1]", got)
+	}
+	if got := trailer.Get("t"); len(got) != 1 || got[0] != "2" {
+		t.Errorf("trailer[t] = %v; want [2]", got)
+	}
+}
+
+// hedgeContext returns the context of a call to a hedged method of a service
+// with the given balancer.
+func hedgeContext(balancer string) context.Context {
+	r := runtime.NewRegistry()
+	svc := &runtime.Service{Spec: &skypb.ServiceSpec{Namespace: "default", ServiceName: "hedge-test"}, Name: "EchoService", Balancer: balancer}
+	r.AddService(svc, nil, nil)
+	m := r.AddMethod(svc.Spec, "EchoService", &runtime.Method{Name: "Echo", Hedge: true, HedgeDelay: "1ms"})
+	return runtime.WithServiceMethod(context.Background(), m)
+}
+
+// slowInvoker answers the first call after 50ms, and the others at once. It
+// counts the calls.
+func slowInvoker(calls *int32) gr.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *gr.ClientConn, opts ...gr.CallOption) error {
+		if atomic.AddInt32(calls, 1) == 1 {
+			select {
+			case <-time.After(50 * time.Millisecond):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		reply.(*wrapperspb.StringValue).Value = "ok"
+		return nil
+	}
+}
+
+func TestHedgeInterceptor(t *testing.T) {
+	for _, spec := range []struct {
+		name      string
+		balancer  string
+		limit     int
+		wantCalls int32
+	}{
+		{name: "round robin", balancer: options.LoadBalancer_ROUND_ROBIN.String(), wantCalls: 2},
+		{name: "consistent", balancer: options.LoadBalancer_CONSISTENT.String(), wantCalls: 1},
+		{name: "free slot", limit: 2, wantCalls: 2},
+		{name: "no free slot", limit: 1, wantCalls: 1},
+	} {
+		t.Run(spec.name, func(t *testing.T) {
+			ctx := hedgeContext(spec.balancer)
+			intercept := hedgeInterceptor("hedge-test", retry.NewBudget(1, 10))
+			if spec.limit > 0 {
+				hi := intercept
+				ci := concurrencyInterceptor("hedge-test", concurrency.NewLimiter(concurrency.Options{Limit: spec.limit}))
+				intercept = func(ctx context.Context, method string, req, reply interface{}, cc *gr.ClientConn, invoker gr.UnaryInvoker, opts ...gr.CallOption) error {
+					return ci(ctx, method, req, reply, cc, func(ctx context.Context, method string, req, reply interface{}, cc *gr.ClientConn, opts ...gr.CallOption) error {
+						return hi(ctx, method, req, reply, cc, invoker, opts...)
+					}, opts...)
+				}
+			}
+
+			var calls int32
+			reply := new(wrapperspb.StringValue)
+			if err := intercept(ctx, "/pkg.EchoService/Echo", wrapperspb.String("hi"), reply, nil, slowInvoker(&calls)); err != nil {
+				t.Fatal(err)
+			}
+			if reply.Value != "ok" {
+				t.Errorf("reply = %q; want ok", reply.Value)
+			}
+			if got := atomic.LoadInt32(&calls); got != spec.wantCalls {
+				t.Errorf("calls = %d; want %d", got, spec.wantCalls)
+			}
+		})
+	}
+}
+
+// writeTestConfig writes the YAML configuration to a temporary file and
+// returns its name.
+func writeTestConfig(t *testing.T, conf string) string {
+	dir, err := ioutil.TempDir("", "upstream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	file := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(file, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestConcurrencyInterceptorSharedLimiter(t *testing.T) {
+	defer func(v string) { *concurrencyConfig = v }(*concurrencyConfig)
+	*concurrencyConfig = writeTestConfig(t, "groups:\n  shared-test:\n    limit: 1\n")
+	f, err := newConcurrencyInterceptor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := &skypb.ServiceSpec{Namespace: "default", ServiceName: "shared-test"}
+	// The interceptors of the service group enabled twice.
+	first, second := f(spec), f(spec)
+	if f(&skypb.ServiceSpec{Namespace: "default", ServiceName: "other"}) != nil {
+		t.Error("interceptor of a service group without limit; want nil")
+	}
+
+	started, done := make(chan struct{}), make(chan struct{})
+	go first(context.Background(), "/pkg.EchoService/Echo", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *gr.ClientConn, opts ...gr.CallOption) error {
+		close(started)
+		<-done
+		return nil
+	})
+	<-started
+	defer close(done)
+
+	var calls int32
+	if err := second(context.Background(), "/pkg.EchoService/Echo", nil, nil, nil, slowInvoker(&calls)); err == nil {
+		t.Error("second interceptor admitted a request over the limit of the first")
+	}
+	if calls != 0 {
+		t.Errorf("calls = %d; want 0", calls)
+	}
+}
+
+func TestConcurrencyInterceptorCanceled(t *testing.T) {
+	l := concurrency.NewLimiter(concurrency.Options{Limit: 1, MaxQueue: 1, QueueTimeout: time.Minute})
+	interceptor := concurrencyInterceptor("canceled-test", l)
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release(0, nil)
+
+	for _, spec := range []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		want codes.Code
+	}{
+		{name: "canceled", ctx: func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx, cancel
+		}, want: codes.Canceled},
+		{name: "deadline", ctx: func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), time.Millisecond)
+		}, want: codes.DeadlineExceeded},
+	} {
+		ctx, cancel := spec.ctx()
+		var calls int32
+		err := interceptor(ctx, "/pkg.EchoService/Echo", nil, nil, nil, slowInvoker(&calls))
+		cancel()
+		if got := status.Code(err); got != spec.want {
+			t.Errorf("%s: interceptor() = %v; want %v", spec.name, err, spec.want)
+		}
+		if calls != 0 {
+			t.Errorf("%s: calls = %d; want 0", spec.name, calls)
+		}
+	}
+}
+
+func TestBreakerInterceptorSharedBreakers(t *testing.T) {
+	defer func(v string) { *breakerConfig = v }(*breakerConfig)
+	*breakerConfig = writeTestConfig(t, "groups:\n  shared-test:\n    consecutive_failures: 1\n    open_timeout: 1m\n")
+	f, err := newBreakerInterceptor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := &skypb.ServiceSpec{Namespace: "default", ServiceName: "shared-test"}
+	first := f(spec)
+
+	failing := func(ctx context.Context, method string, req, reply interface{}, cc *gr.ClientConn, opts ...gr.CallOption) error {
+		return status.Error(codes.Unavailable, "down")
+	}
+	if err := first(context.Background(), "/pkg.EchoService/Echo", nil, nil, nil, failing); status.Code(err) != codes.Unavailable {
+		t.Fatalf("first call = %v; want Unavailable", err)
+	}
+
+	// The service group is disabled and enabled again.
+	second := f(spec)
+	var calls int32
+	if err := second(context.Background(), "/pkg.EchoService/Echo", nil, nil, nil, slowInvoker(&calls)); err == nil {
+		t.Error("second interceptor let a request through the open circuit")
+	}
+	if calls != 0 {
+		t.Errorf("calls = %d; want 0", calls)
+	}
+}
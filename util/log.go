@@ -28,21 +28,41 @@ var (
 	ResponseRestFormat = "[response]%s,%d,%s"
 )
 
+// gateway-access日志
+var (
+	// janus-gateway HTTP访问日志, 由access-log中间件记录.
+	AccessLogger = glog.Context(nil, glog.FileName{Name: "gateway-access"})
+
+	// access log format.
+	// traceId,remoteAddr,httpMethod,uri,status,bytes,ms:response-ms
+	AccessFormat = "%s,%s,%s,%s,%d,%d,ms:%g"
+)
+
 // gateway-config日志
 var (
-	// janus-gateway 配置操作相关日志.
+	// janus-gateway 配置操作相关日志. 支持格式包括:
+	// 1. AdminAuditFormat
 	ConfigLogger = glog.Context(nil, glog.FileName{Name: "gateway-config"})
+
+	// admin audit log format.
+	// [admin]remoteAddr,httpMethod,uri,action,target,result
+	AdminAuditFormat = "[admin]%s,%s,%s,%s,%s,%s"
 )
 
 // gateway-stat日志
 var (
 	// janus-gateway 访问统计日志. 支持格式包括:
 	// 1. StatFormat
+	// 2. RetryStatFormat
 	StatLogger = glog.Context(nil, glog.FileName{Name: "gateway-stat"})
 
 	// stat log format.
 	// traceId,serviceName,httpMethod,path,client,ok:isSuccess,code,ms:response-ms
 	StatFormat = "%s,%s,%s,%s,%s,ok:%s,%d,ms:%g"
+
+	// retry stat log format.
+	// traceId[retry]serviceName,httpMethod,path,attempt:n,code,backoff:duration
+	RetryStatFormat = "%s[retry]%s,%s,%s,attempt:%d,%d,backoff:%v"
 )
 
 // gateway-error日志